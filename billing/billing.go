@@ -0,0 +1,266 @@
+// Package billing - движок расчета стоимости подписок. Функции чистые: принимают условия подписок (Subscription)
+// и период, не обращаются к базе и не зависят от моделей, поэтому сумма, прогноз, симуляция и бюджеты считают одинаково
+package billing
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Charge - начисление за один месяц
+type Charge struct {
+	Month       time.Time `json:"month"`
+	Date        time.Time `json:"date"` //день списания: день привязки, прижатый к концу месяца, или начало неполного периода
+	Amount      int64     `json:"amount_minor"`
+	Explanation string    `json:"explanation,omitempty"` //как посчитан неполный период
+
+	Proration *Proration `json:"-"` //подробности пересчета неполного периода, nil - период оплачен целиком
+}
+
+// DayStart возвращает начало дня, в котором находится дата
+func DayStart(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// MonthStart возвращает первое число месяца, в котором находится дата
+func MonthStart(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthCeil возвращает первое число месяца не раньше даты
+func MonthCeil(date time.Time) time.Time {
+	start := MonthStart(date)
+	if start.Before(date) {
+		return start.AddDate(0, 1, 0)
+	}
+	return start
+}
+
+// MonthEnd возвращает последний день месяца, в котором находится дата
+func MonthEnd(date time.Time) time.Time {
+	return MonthStart(date).AddDate(0, 1, -1)
+}
+
+// ChargeDate возвращает день списания в месяце: день привязки, а если в месяце меньше дней - последний день (31 января -> 28 февраля)
+func ChargeDate(month time.Time, anchorDay int) time.Time {
+	last := MonthEnd(month).Day()
+	if anchorDay > last {
+		anchorDay = last
+	}
+	if anchorDay < 1 {
+		anchorDay = 1
+	}
+	return time.Date(month.Year(), month.Month(), anchorDay, 0, 0, 0, 0, time.UTC)
+}
+
+// AnchorDay возвращает день привязки подписки. У записей без него это день начала
+func AnchorDay(sub *Subscription) int {
+	if sub.AnchorDay > 0 {
+		return sub.AnchorDay
+	}
+	return sub.StartDate.Day()
+}
+
+// FirstPaidMonth возвращает месяц первого списания не раньше даты
+func FirstPaidMonth(sub *Subscription, notBefore time.Time) time.Time {
+	month := MonthStart(notBefore)
+	if ChargeDate(month, AnchorDay(sub)).Before(notBefore) {
+		month = month.AddDate(0, 1, 0)
+	}
+	return month
+}
+
+// monthsBetween - сколько месяцев от from до to
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+}
+
+// StatusAt возвращает статус подписки, действующий в указанном месяце (по последнему переходу не позже него)
+func StatusAt(sub *Subscription, month time.Time) string {
+	status := StatusActive
+	for _, transition := range sub.Transitions {
+		if MonthStart(transition.Date).After(month) {
+			break
+		}
+		status = transition.To
+	}
+	return status
+}
+
+// IsTrial - списание попадает в пробный период: статус trial и, если задана дата окончания пробного периода, она еще не наступила.
+// Подписка, ожидающая одобрения, считается так, как если бы ее одобрили: пробный период определяется только по дате
+func IsTrial(sub *Subscription, date time.Time) bool {
+	switch StatusAt(sub, MonthStart(date)) {
+	case StatusTrial:
+		return sub.TrialEndDate == nil || date.Before(*sub.TrialEndDate)
+	case StatusPendingApproval:
+		return sub.TrialEndDate != nil && date.Before(*sub.TrialEndDate)
+	}
+	return false
+}
+
+// IsBillingMonth - месяц, в котором списывается оплата за период: для месячной оплаты каждый,
+// для годовой - раз в 12 месяцев от первого оплачиваемого месяца
+func IsBillingMonth(sub *Subscription, month time.Time) bool {
+	if sub.BillingPeriod != PeriodAnnual {
+		return true
+	}
+	anchor := FirstPaidMonth(sub, sub.StartDate)
+	if sub.TrialEndDate != nil {
+		anchor = FirstPaidMonth(sub, *sub.TrialEndDate)
+	}
+	if month.Before(anchor) {
+		return false
+	}
+	return monthsBetween(anchor, month)%12 == 0
+}
+
+// PriceAt возвращает цену списания в указанный день: вводную, пока действует акция, иначе обычную по истории цены
+func PriceAt(sub *Subscription, date time.Time) int64 {
+	if sub.PromoPrice != nil && sub.PromoEndDate != nil && date.Before(*sub.PromoEndDate) {
+		return *sub.PromoPrice
+	}
+	price := sub.Price
+	for i, change := range sub.History {
+		if i > 0 && change.From.After(date) {
+			break
+		}
+		price = change.Price //до первого изменения действует самая ранняя известная цена
+	}
+	return price
+}
+
+// Charges возвращает начисления подписки за месяцы периода [from, to]. Списание происходит в день привязки
+// между датой начала и датой окончания включительно и стоит цену на этот день,
+// месяцы на паузе, пробный период и отклоненные подписки не оплачиваются, годовая оплата начисляется только в месяц списания.
+// Если у подписки включен пересчет (proration_mode), неполные периоды в начале и конце и смена цены внутри периода
+// оплачиваются пропорционально дням. Цена берется из истории цены подписки
+func Charges(sub *Subscription, from, to time.Time) []Charge {
+	first := MonthStart(sub.StartDate)
+	if from.After(first) {
+		first = MonthStart(from)
+	}
+	last := MonthStart(to)
+	if sub.EndDate != nil && sub.EndDate.Before(last) {
+		last = MonthStart(*sub.EndDate)
+	}
+
+	anchorDay := AnchorDay(sub)
+	prorate := Prorates(sub)
+	var charges []Charge
+	for month := first; !month.After(last); month = month.AddDate(0, 1, 0) {
+		if !IsBillingMonth(sub, month) {
+			continue
+		}
+		date := ChargeDate(month, anchorDay)
+		next := nextChargeDate(sub, month, anchorDay)
+
+		periodStart := date
+		if date.Before(sub.StartDate) { //подписка началась после дня списания
+			if !prorate || !next.After(sub.StartDate) {
+				continue
+			}
+			periodStart = sub.StartDate //неполный первый период до следующего дня списания
+		}
+		if sub.EndDate != nil && periodStart.After(*sub.EndDate) {
+			continue
+		}
+		if status := StatusAt(sub, month); status == StatusPaused || status == StatusRejected || IsTrial(sub, periodStart) {
+			continue
+		}
+
+		periodEnd := next
+		if prorate && sub.EndDate != nil && sub.EndDate.AddDate(0, 0, 1).Before(next) {
+			periodEnd = sub.EndDate.AddDate(0, 0, 1) //подписка заканчивается внутри периода
+		}
+
+		charge := Charge{Month: month, Date: periodStart, Amount: PriceAt(sub, date)}
+		if prorate {
+			charge.Amount, charge.Proration = prorated(sub, date, next, periodStart, periodEnd)
+			if charge.Proration != nil {
+				charge.Proration.Month = month
+				charge.Explanation = charge.Proration.Explanation
+			}
+		}
+		charges = append(charges, charge)
+	}
+	return charges
+}
+
+// Cost считает полную стоимость подписки за период
+func Cost(sub *Subscription, from, to time.Time) int64 {
+	total := int64(0)
+	for _, charge := range Charges(sub, from, to) {
+		total += charge.Amount
+	}
+	return total
+}
+
+// ShareOf возвращает долю пользователя в начислении: участники платят свой процент или фиксированную сумму,
+// плательщик - остаток. Фиксированные суммы не могут превысить то, что осталось от начисления
+func ShareOf(sub *Subscription, userID string, amount int64) int64 {
+	remaining := amount
+	own := int64(0)
+	for _, member := range sub.Members {
+		share := int64(0)
+		switch {
+		case member.SharePercent != nil:
+			share = int64(math.Round(float64(amount) * *member.SharePercent / 100))
+		case member.ShareAmount != nil:
+			share = *member.ShareAmount
+		}
+		if share > remaining {
+			share = remaining
+		}
+		remaining -= share
+		if member.UserID == userID {
+			own += share
+		}
+	}
+	if sub.UserID == userID {
+		own += remaining
+	}
+	return own
+}
+
+// UserCost считает долю пользователя в стоимости подписки за период
+func UserCost(sub *Subscription, userID string, from, to time.Time) int64 {
+	total := int64(0)
+	for _, charge := range Charges(sub, from, to) {
+		total += ShareOf(sub, userID, charge.Amount)
+	}
+	return total
+}
+
+// MonthTotal - сумма начислений за месяц, ее разбивка по налогу и пояснения к неполным периодам
+type MonthTotal struct {
+	Amount     int64
+	Taxes      TaxTotals
+	Prorations []string
+}
+
+// ByMonth складывает начисления подписок по месяцам периода. Если задан userID, берется только его доля.
+// Налог считается с каждого начисления (или доли) по ставке его подписки
+func ByMonth(subs []Subscription, userID *string, from, to time.Time) map[time.Time]*MonthTotal {
+	totals := map[time.Time]*MonthTotal{}
+	for i := range subs {
+		for _, charge := range Charges(&subs[i], from, to) {
+			total, ok := totals[charge.Month]
+			if !ok {
+				total = &MonthTotal{}
+				totals[charge.Month] = total
+			}
+			if charge.Explanation != "" {
+				total.Prorations = append(total.Prorations, fmt.Sprintf("subscription %d: %s", subs[i].ID, charge.Explanation))
+			}
+			amount := charge.Amount
+			if userID != nil {
+				amount = ShareOf(&subs[i], *userID, amount)
+			}
+			total.Amount += amount
+			total.Taxes.Add(SplitTax(&subs[i], amount))
+		}
+	}
+	return totals
+}
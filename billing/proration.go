@@ -0,0 +1,130 @@
+package billing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Proration - как посчитано начисление за неполный период: за какие дни и по какой дневной ставке
+type Proration struct {
+	SubscriptionID uint
+	Month          time.Time
+	Mode           string
+	PeriodStart    time.Time
+	PeriodEnd      time.Time //последний оплаченный день
+	Days           int       //оплаченных дней
+	CycleDays      int       //на сколько дней делится цена
+	Parts          []ProrationPart
+	Amount         int64
+	Explanation    string
+}
+
+// ProrationPart - отрезок неполного периода с одной ценой
+type ProrationPart struct {
+	Days      int
+	Price     int64
+	DailyRate float64 //цена, деленная на CycleDays, до сотых минимальной единицы
+}
+
+// nextChargeDate возвращает день следующего списания после списания в месяце month
+func nextChargeDate(sub *Subscription, month time.Time, anchorDay int) time.Time {
+	if sub.BillingPeriod == PeriodAnnual {
+		return ChargeDate(month.AddDate(1, 0, 0), anchorDay)
+	}
+	return ChargeDate(month.AddDate(0, 1, 0), anchorDay)
+}
+
+// Prorates - включен ли пересчет неполных периодов
+func Prorates(sub *Subscription) bool {
+	return sub.ProrationMode == ProrationDaily || sub.ProrationMode == ProrationByAnchorDay
+}
+
+// PaidThrough возвращает дату окончания подписки, для которой month - последний оплаченный месяц.
+// Без пересчета это день списания (период оплачен целиком), с пересчетом - последний день периода,
+// чтобы он не считался неполным
+func PaidThrough(sub *Subscription, month time.Time) time.Time {
+	anchorDay := AnchorDay(sub)
+	if !Prorates(sub) {
+		return ChargeDate(month, anchorDay)
+	}
+	return nextChargeDate(sub, MonthStart(month), anchorDay).AddDate(0, 0, -1)
+}
+
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// prorated считает начисление за часть [periodStart, periodEnd) периода оплаты [cycleStart, cycleEnd).
+// Отрезки с разной ценой (смена цены, конец вводной цены) считаются отдельно. Дневная ставка:
+// daily - цена, деленная на число дней календарного месяца (для годовой оплаты - года), в котором начинается период;
+// by-anchor-day - цена, деленная на число дней от дня списания до следующего дня списания.
+// Полный период без смены цены стоит полную цену и не требует пояснения, для него пересчет - nil
+func prorated(sub *Subscription, cycleStart, cycleEnd, periodStart, periodEnd time.Time) (int64, *Proration) {
+	bounds := []time.Time{periodStart, periodEnd}
+	for _, change := range sub.History {
+		if change.From.After(periodStart) && change.From.Before(periodEnd) {
+			bounds = append(bounds, change.From)
+		}
+	}
+	if sub.PromoEndDate != nil && sub.PromoEndDate.After(periodStart) && sub.PromoEndDate.Before(periodEnd) {
+		bounds = append(bounds, *sub.PromoEndDate)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].Before(bounds[j]) })
+
+	var segments []ProrationPart
+	for i := 0; i+1 < len(bounds); i++ {
+		if n := days(bounds[i], bounds[i+1]); n > 0 {
+			price := PriceAt(sub, bounds[i])
+			if last := len(segments) - 1; last >= 0 && segments[last].Price == price {
+				segments[last].Days += n
+				continue
+			}
+			segments = append(segments, ProrationPart{Days: n, Price: price})
+		}
+	}
+
+	if len(segments) == 1 && periodStart.Equal(cycleStart) && periodEnd.Equal(cycleEnd) {
+		return segments[0].Price, nil
+	}
+
+	denominator := days(cycleStart, cycleEnd)
+	if sub.ProrationMode == ProrationDaily {
+		if sub.BillingPeriod == PeriodAnnual {
+			denominator = days(periodStart, periodStart.AddDate(1, 0, 0))
+		} else {
+			denominator = MonthEnd(periodStart).Day()
+		}
+	}
+	total := 0
+	for _, segment := range segments {
+		total += segment.Days
+	}
+	if total > denominator { //неполный период длиннее календарного месяца не может стоить больше цены
+		denominator = total
+	}
+
+	numerator := int64(0)
+	parts := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		numerator += int64(segment.Days) * segment.Price
+		segments[i].DailyRate = math.Round(float64(segment.Price)*100/float64(denominator)) / 100
+		parts = append(parts, fmt.Sprintf("%d/%d days at %d", segment.Days, denominator, segment.Price))
+	}
+	amount := (2*numerator + int64(denominator)) / (2 * int64(denominator)) //округление до ближайшего
+	last := periodEnd.AddDate(0, 0, -1)
+	return amount, &Proration{
+		SubscriptionID: sub.ID,
+		Mode:           sub.ProrationMode,
+		PeriodStart:    periodStart,
+		PeriodEnd:      last,
+		Days:           total,
+		CycleDays:      denominator,
+		Parts:          segments,
+		Amount:         amount,
+		Explanation: fmt.Sprintf("%s: %s, period %s - %s", sub.ProrationMode, strings.Join(parts, " + "),
+			periodStart.Format("2006-01-02"), last.Format("2006-01-02")),
+	}
+}
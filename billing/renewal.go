@@ -0,0 +1,103 @@
+package billing
+
+import "time"
+
+// RenewalTerm возвращает срок продления в месяцах: заданный у подписки, иначе период оплаты (12 для годовой, 1 для месячной)
+func RenewalTerm(sub *Subscription) int {
+	if sub.RenewalTermMonths > 0 {
+		return sub.RenewalTermMonths
+	}
+	if sub.BillingPeriod == PeriodAnnual {
+		return 12
+	}
+	return 1
+}
+
+// Renews - подписка продлевается автоматически: включено автопродление и она не отменена и не отклонена
+func Renews(sub *Subscription) bool {
+	return sub.AutoRenew && sub.Status != StatusCancelled && sub.Status != StatusRejected
+}
+
+// termBoundary возвращает границу сроков договора через months месяцев от начала подписки. День берется из дня
+// привязки и в коротких месяцах прижимается к последнему дню, а месяцы отсчитываются от начала, а не от прошлой границы,
+// поэтому границы не сползают после короткого месяца
+func termBoundary(sub *Subscription, months int) time.Time {
+	return ChargeDate(MonthStart(sub.StartDate).AddDate(0, months, 0), AnchorDay(sub))
+}
+
+// nextBoundary возвращает первую границу сроков позже after: окончание минимального срока, дальше каждые RenewalTerm месяцев
+func nextBoundary(sub *Subscription, after time.Time) time.Time {
+	term := RenewalTerm(sub)
+	months := term
+	if sub.MinimumTermMonths > 0 {
+		months = sub.MinimumTermMonths
+	}
+	for ; ; months += term {
+		if boundary := termBoundary(sub, months); boundary.After(after) {
+			return boundary
+		}
+	}
+}
+
+// NextRenewal возвращает ближайшую дату продления не раньше from, nil - подписка не продлевается.
+// Если срок задан датой окончания, продление наступает на следующий день после нее, иначе - по окончании
+// минимального срока от даты начала. Дальше подписка продлевается на границах сроков от даты начала
+func NextRenewal(sub *Subscription, from time.Time) *time.Time {
+	if !Renews(sub) {
+		return nil
+	}
+	if sub.EndDate == nil {
+		renewal := nextBoundary(sub, from.AddDate(0, 0, -1))
+		return &renewal
+	}
+	renewal := sub.EndDate.AddDate(0, 0, 1)
+	for renewal.Before(from) {
+		renewal = nextBoundary(sub, renewal)
+	}
+	return &renewal
+}
+
+// CancellationDeadline возвращает последний день, когда можно подать отмену, чтобы подписка не продлилась, и продление,
+// от которого он защищает: за NoticeDays дней до продления, без срока уведомления - накануне. Если для ближайшего
+// продления срок уже прошел, берется следующее
+func CancellationDeadline(sub *Subscription, today time.Time) (deadline, renewal *time.Time) {
+	notice := sub.NoticeDays
+	if notice == 0 {
+		notice = 1
+	}
+	from := today
+	for {
+		renewal = NextRenewal(sub, from)
+		if renewal == nil {
+			return nil, nil
+		}
+		last := renewal.AddDate(0, 0, -notice)
+		if !last.Before(today) {
+			return &last, renewal
+		}
+		from = renewal.AddDate(0, 0, 1)
+	}
+}
+
+// RenewedEnd возвращает дату окончания подписки после продления: последний день перед следующей границей сроков.
+// Дата окончания не по границе выравнивается по ней
+func RenewedEnd(sub *Subscription) time.Time {
+	return nextBoundary(sub, sub.EndDate.AddDate(0, 0, 1)).AddDate(0, 0, -1)
+}
+
+// MinimumTermMonth возвращает первый месяц, который может стать последним оплаченным при отмене: месяц последнего
+// списания внутри минимального срока. nil - минимального срока нет
+func MinimumTermMonth(sub *Subscription) *time.Time {
+	if sub.MinimumTermMonths == 0 {
+		return nil
+	}
+	period := 1
+	if sub.BillingPeriod == PeriodAnnual {
+		period = 12
+	}
+	month := MonthStart(sub.StartDate).AddDate(0, sub.MinimumTermMonths-period, 0)
+	if start := MonthStart(sub.StartDate); month.Before(start) {
+		month = start
+	}
+	return &month
+}
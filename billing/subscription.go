@@ -0,0 +1,107 @@
+package billing
+
+import (
+	"sort"
+	"time"
+)
+
+// значения совпадают с константами моделей, чтобы записи из базы переносились без перевода
+const (
+	StatusActive          = "active"
+	StatusTrial           = "trial"
+	StatusPaused          = "paused"
+	StatusCancelled       = "cancelled"
+	StatusPendingApproval = "pending_approval"
+	StatusRejected        = "rejected"
+)
+
+const (
+	PeriodMonthly = "monthly"
+	PeriodAnnual  = "annual"
+)
+
+const (
+	ProrationNone        = "none"
+	ProrationDaily       = "daily"
+	ProrationByAnchorDay = "by-anchor-day"
+)
+
+// Subscription - условия подписки, от которых зависят начисления. Пакет не знает о хранении подписок:
+// вызывающий заполняет условия из своей записи, налог - уже с учетом настроек сервиса
+type Subscription struct {
+	ID        uint
+	UserID    string //плательщик, ему достается остаток после долей участников
+	Price     int64  //обычная цена за период в минимальных единицах
+	StartDate time.Time
+	EndDate   *time.Time
+	Status    string //текущий статус, по нему решается продление
+
+	BillingPeriod string //PeriodMonthly или PeriodAnnual
+	AnchorDay     int    //день списания, 0 - день начала
+	ProrationMode string
+
+	TrialEndDate *time.Time //первый день после пробного периода
+	PromoPrice   *int64
+	PromoEndDate *time.Time
+
+	TaxRate      float64 //в процентах
+	TaxInclusive bool
+
+	AutoRenew         bool
+	MinimumTermMonths int
+	RenewalTermMonths int
+	NoticeDays        int
+
+	Transitions []Transition  //по возрастанию даты
+	Members     []Member      //с кем делится стоимость
+	History     []PriceChange //история обычной цены по возрастанию From, пустая - весь период действует Price
+}
+
+// Transition - смена статуса подписки с указанной даты
+type Transition struct {
+	To   string
+	Date time.Time
+}
+
+// Member - участник совместной подписки: платит процент или фиксированную сумму
+type Member struct {
+	UserID       string
+	SharePercent *float64
+	ShareAmount  *int64
+}
+
+// PriceChange - обычная цена подписки, действующая со дня From
+type PriceChange struct {
+	From  time.Time
+	Price int64
+}
+
+// Version - сохраненное состояние подписки, из которого берется история цены
+type Version struct {
+	Number    int
+	ValidFrom time.Time
+	Price     int64
+}
+
+// PriceHistory превращает версии подписки в историю обычной цены: новая цена действует со дня, в который сохранена версия
+func PriceHistory(versions []Version) []PriceChange {
+	sorted := make([]Version, len(versions))
+	copy(sorted, versions)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+
+	var history []PriceChange
+	for _, version := range sorted {
+		from := DayStart(version.ValidFrom)
+		if n := len(history); n > 0 {
+			if history[n-1].Price == version.Price {
+				continue
+			}
+			if !history[n-1].From.Before(from) { //несколько изменений за день - действует последнее
+				history[n-1].Price = version.Price
+				continue
+			}
+		}
+		history = append(history, PriceChange{From: from, Price: version.Price})
+	}
+	return history
+}
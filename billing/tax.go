@@ -0,0 +1,31 @@
+package billing
+
+import "math"
+
+// TaxTotals - сумма без налога, налог и сумма с налогом в минимальных единицах
+type TaxTotals struct {
+	Net   int64
+	Tax   int64
+	Gross int64
+}
+
+func (t *TaxTotals) Add(other TaxTotals) {
+	t.Net += other.Net
+	t.Tax += other.Tax
+	t.Gross += other.Gross
+}
+
+// SplitTax делит начисление на сумму без налога, налог и сумму с налогом. Если налог включен в цену,
+// начисление - это сумма с налогом, иначе налог добавляется сверху. Налог округляется до ближайшей минимальной единицы
+func SplitTax(sub *Subscription, amount int64) TaxTotals {
+	rate := sub.TaxRate
+	if rate == 0 {
+		return TaxTotals{Net: amount, Gross: amount}
+	}
+	if sub.TaxInclusive {
+		tax := int64(math.Round(float64(amount) * rate / (100 + rate)))
+		return TaxTotals{Net: amount - tax, Tax: tax, Gross: amount}
+	}
+	tax := int64(math.Round(float64(amount) * rate / 100))
+	return TaxTotals{Net: amount, Tax: tax, Gross: amount + tax}
+}
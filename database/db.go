@@ -45,14 +45,110 @@ func ConnectDB(logger *zap.SugaredLogger) *gorm.DB {
 
 		logger.Info("Подключение к базе данных установлено")
 
-		err = DB.AutoMigrate(&models.Service{}, &models.Subscription{})
+		err = DB.AutoMigrate(&models.Service{}, &models.Subscription{}, &models.AuditEntry{}, &models.SubscriptionVersion{}, &models.StatusTransition{}, &models.SubscriptionMember{}, &models.Team{}, &models.TeamMember{}, &models.Budget{}, &models.Charge{}, &models.ServiceAlias{}, &models.StatementImport{}, &models.BankTransaction{}, &models.Attachment{}, &models.Tag{}, &models.CostCenter{}, &models.CostAllocation{})
 		if err != nil {
 			logger.Fatalf("Ошибка миграции базы данных: %v", err)
 		}
 
+		if err = backfillMinorUnits(DB); err != nil {
+			logger.Fatalf("Ошибка перевода сумм в минимальные единицы: %v", err)
+		}
+
+		if err = backfillVersions(DB); err != nil {
+			logger.Fatalf("Ошибка заполнения истории подписок: %v", err)
+		}
+
+		if err = backfillFingerprints(DB); err != nil {
+			logger.Fatalf("Ошибка заполнения ключей банковских операций: %v", err)
+		}
+
+		if err = backfillTransitionMonths(DB); err != nil {
+			logger.Fatalf("Ошибка перевода статусов на начало месяца: %v", err)
+		}
+
 		logger.Info("Миграция базы данных выполнена")
 
 	})
 
 	return DB
 }
+
+// подписки, созданные до появления истории, получают начальную версию (и версию удаления, если уже удалены)
+func backfillVersions(db *gorm.DB) error {
+	return db.Exec(`
+		INSERT INTO subscription_versions (subscription_id, version, service_id, price, price_minor, currency, user_id, start_date, end_date, deleted, valid_from, valid_to)
+		SELECT s.id, 1, s.service_id, s.price, s.price_minor, s.currency, s.user_id, s.start_date, s.end_date, false, s.created_at, s.deleted_at
+		FROM subscriptions s
+		WHERE NOT EXISTS (SELECT 1 FROM subscription_versions v WHERE v.subscription_id = s.id)
+		UNION ALL
+		SELECT s.id, 2, s.service_id, s.price, s.price_minor, s.currency, s.user_id, s.start_date, s.end_date, true, s.deleted_at, NULL
+		FROM subscriptions s
+		WHERE s.deleted_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM subscription_versions v WHERE v.subscription_id = s.id)`).Error
+}
+
+// начальный статус раньше сохранялся с днем начала подписки, а остальные переходы - с начала месяца,
+// из-за чего пауза или отмена в месяце начала считалась более ранней. Все переходы приводятся к началу месяца
+func backfillTransitionMonths(db *gorm.DB) error {
+	return db.Exec(`UPDATE status_transitions SET date = date_trunc('month', date) WHERE date <> date_trunc('month', date)`).Error
+}
+
+// суммы, сохраненные до перехода на минимальные единицы, переносятся из старых колонок в целых единицах.
+// Старые колонки остаются и дальше заполняются для клиентов, которые их читают
+func backfillMinorUnits(db *gorm.DB) error {
+	statements := []string{
+		`UPDATE subscriptions SET price_minor = price * ? WHERE price_minor = 0 AND price <> 0`,
+		`UPDATE subscriptions SET promo_price_minor = promo_price * ? WHERE promo_price_minor IS NULL AND promo_price IS NOT NULL`,
+		`UPDATE subscription_versions SET price_minor = price * ? WHERE price_minor = 0 AND price <> 0`,
+		`UPDATE subscription_versions SET promo_price_minor = promo_price * ? WHERE promo_price_minor IS NULL AND promo_price IS NOT NULL`,
+		`UPDATE subscription_members SET share_amount_minor = share_amount * ? WHERE share_amount_minor IS NULL AND share_amount IS NOT NULL`,
+		`UPDATE budgets SET limit_minor = "limit" * ? WHERE limit_minor = 0 AND "limit" <> 0`,
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement, models.MinorUnits).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// операции, сохраненные до появления ключа, получают его так же, как в repository.SetFingerprints: одинаковые операции
+// одной выписки нумеруются. Повторы из пересекающихся выписок удаляются: остается привязанная к подписке операция или загруженная первой
+func backfillFingerprints(db *gorm.DB) error {
+	var missing int64
+	if err := db.Model(&models.BankTransaction{}).Where("fingerprint IS NULL").Count(&missing).Error; err != nil {
+		return err
+	}
+	if missing == 0 {
+		return nil
+	}
+
+	keyed := `SELECT id, user_id, fingerprint, subscription_id,
+		base || CASE WHEN n > 1 THEN '#' || n ELSE '' END AS computed
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY user_id, import_id, base ORDER BY id) AS n
+			FROM (
+				SELECT id, user_id, import_id, fingerprint, subscription_id,
+				CASE WHEN external_id <> '' THEN 'id:' || external_id
+				ELSE 'tx:' || to_char(date AT TIME ZONE 'UTC', 'YYYY-MM-DD') || '|' || amount_minor || '|' || description END AS base
+				FROM bank_transactions
+			) b
+		) numbered`
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			DELETE FROM bank_transactions WHERE id IN (
+				SELECT id FROM (
+					SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id, computed ORDER BY fingerprint IS NULL, subscription_id IS NULL, id) AS n
+					FROM (` + keyed + `) k
+				) ranked WHERE n > 1
+			)`).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			UPDATE bank_transactions SET fingerprint = k.computed
+			FROM (` + keyed + `) k
+			WHERE k.id = bank_transactions.id AND bank_transactions.fingerprint IS NULL`).Error
+	})
+}
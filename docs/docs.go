@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/ping": {
+        "/approvals": {
             "get": {
-                "description": "do ping",
+                "description": "Возвращает подписки, ожидающие одобрения. Доступно только с TRUST_IDENTITY_HEADERS согласующим из APPROVERS (заголовок X-User-ID) или с ролью согласующего в заголовке X-User-Role",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,22 +25,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Test"
+                    "Approval"
+                ],
+                "summary": "Подписки на согласовании",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Role",
+                        "name": "X-User-Role",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "ping",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Subscription"
+                            }
                         }
                     }
                 }
             }
         },
-        "/services": {
+        "/audit": {
             "get": {
-                "description": "Возвращает список всех сервисов",
+                "description": "Возвращает записи журнала изменений с фильтрацией и пагинацией",
                 "consumes": [
                     "application/json"
                 ],
@@ -48,23 +70,105 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service"
+                    "Audit"
+                ],
+                "summary": "Получить журнал изменений",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 200,
+                        "minimum": 1,
+                        "type": "integer",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AuditPage"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets": {
+            "get": {
+                "description": "Возвращает бюджеты с текущими и прогнозными расходами за текущий месяц",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budget"
+                ],
+                "summary": "Получить бюджеты",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "name": "team_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Получить список сервисов",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Service"
+                                "$ref": "#/definitions/models.BudgetReport"
                             }
                         }
                     }
                 }
             },
             "post": {
-                "description": "Добавляет новый сервис",
+                "description": "Задает месячный лимит расходов пользователя или команды, возможно только для категории сервисов",
                 "consumes": [
                     "application/json"
                 ],
@@ -72,17 +176,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service"
+                    "Budget"
                 ],
-                "summary": "Создать новый сервис",
+                "summary": "Создать бюджет",
                 "parameters": [
                     {
-                        "description": "Service",
-                        "name": "service",
+                        "description": "Budget",
+                        "name": "budget",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateService"
+                            "$ref": "#/definitions/models.CreateBudget"
                         }
                     }
                 ],
@@ -90,15 +194,45 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Service"
+                            "$ref": "#/definitions/models.Budget"
                         }
                     }
                 }
             }
         },
-        "/services/{id}": {
+        "/budgets/{id}": {
+            "get": {
+                "description": "Возвращает бюджет с текущими и прогнозными расходами за текущий месяц",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budget"
+                ],
+                "summary": "Получить бюджет",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BudgetReport"
+                        }
+                    }
+                }
+            },
             "delete": {
-                "description": "Удаляет существующий сервис",
+                "description": "Удаляет бюджет",
                 "consumes": [
                     "application/json"
                 ],
@@ -106,9 +240,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Service"
+                    "Budget"
                 ],
-                "summary": "Удалить сервис",
+                "summary": "Удалить бюджет",
                 "parameters": [
                     {
                         "type": "integer",
@@ -129,9 +263,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/subs": {
+        "/cost-centers": {
             "get": {
-                "description": "Возвращает список всех подписок",
+                "description": "Возвращает все центры затрат по коду",
                 "consumes": [
                     "application/json"
                 ],
@@ -139,23 +273,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscription"
+                    "CostCenter"
                 ],
-                "summary": "Получить список подписок",
+                "summary": "Получить список центров затрат",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Subscription"
+                                "$ref": "#/definitions/models.CostCenter"
                             }
                         }
                     }
                 }
             },
             "post": {
-                "description": "Добавляет новую подписку",
+                "description": "Создает центр затрат, на который можно относить стоимость подписок",
                 "consumes": [
                     "application/json"
                 ],
@@ -163,33 +297,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscription"
+                    "CostCenter"
                 ],
-                "summary": "Добавить новую подписку",
+                "summary": "Создать центр затрат",
                 "parameters": [
                     {
-                        "description": "Subscription",
-                        "name": "subscription",
+                        "description": "Cost center",
+                        "name": "center",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CreateSubscription"
+                            "$ref": "#/definitions/models.CreateCostCenter"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Subscription"
+                            "$ref": "#/definitions/models.CostCenter"
                         }
                     }
                 }
             }
         },
-        "/subs/sum": {
+        "/ping": {
             "get": {
-                "description": "Возвращает сумму подписок по фильтрам",
+                "description": "do ping",
                 "consumes": [
                     "application/json"
                 ],
@@ -197,75 +331,134 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscription"
+                    "Test"
                 ],
-                "summary": "Получить сумму подписок по фильтрам",
+                "summary": "ping",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reconcile": {
+            "post": {
+                "description": "Загружает выписку в CSV или OFX и сверяет операции с начислениями подписок пользователя: оплаченные начисления, начисления без списания и регулярные списания, которых нет среди подписок",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reconcile"
+                ],
+                "summary": "Сверить банковскую выписку",
                 "parameters": [
                     {
-                        "type": "string",
-                        "name": "end_date",
-                        "in": "query"
+                        "type": "file",
+                        "description": "Statement (CSV or OFX)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "name": "service_name",
-                        "in": "query"
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "formData",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "name": "start_date",
-                        "in": "query"
+                        "description": "csv or ofx",
+                        "name": "format",
+                        "in": "formData"
                     },
                     {
                         "type": "string",
-                        "name": "user_id",
-                        "in": "query"
+                        "description": "Currency of CSV rows without currency column",
+                        "name": "currency",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Date tolerance in days, default 3",
+                        "name": "tolerance_days",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Amount tolerance in percent, default 5",
+                        "name": "amount_tolerance",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.ReconcileReport"
                         }
                     }
                 }
             }
         },
-        "/subs/{id}": {
+        "/reports/chargeback": {
             "get": {
-                "description": "Возвращает подписку по ID",
+                "description": "Относит расходы месяца на центры затрат по долям подписок. Подписки без распределения попадают в строку с cost_center_id 0. С format=csv отчет отдается файлом для бухгалтерии",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "Subscription"
+                    "Reports"
                 ],
-                "summary": "Получить подписку по ID",
+                "summary": "Перевыставление затрат",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID",
-                        "name": "id",
-                        "in": "path",
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "по умолчанию json",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "MM-YYYY",
+                        "name": "month",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "только подписки пользователя",
+                        "name": "user_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Subscription"
+                            "$ref": "#/definitions/models.ChargebackReport"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Обновляет существующую подписку",
+            }
+        },
+        "/reports/churn": {
+            "get": {
+                "description": "Считает отмены, действующие с месяцев периода: по сервисам и месяцам, по кодам причин (unspecified - без причины) и средний срок жизни отмененных подписок в месяцах",
                 "consumes": [
                     "application/json"
                 ],
@@ -273,38 +466,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscription"
+                    "Reports"
                 ],
-                "summary": "Обновить подписку",
+                "summary": "Отток подписок",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "MM-YYYY, по умолчанию месяц начала",
+                        "name": "end_date",
+                        "in": "query"
                     },
                     {
-                        "description": "Subscription",
-                        "name": "subscription",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UpdateSubscription"
-                        }
+                        "type": "string",
+                        "description": "MM-YYYY, по умолчанию текущий месяц",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Subscription"
+                            "$ref": "#/definitions/models.ChurnReport"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаляет существующую подписку",
+            }
+        },
+        "/reports/spend": {
+            "get": {
+                "description": "Раскладывает расходы за месяцы периода по меткам (group_by=tag), сервисам или категориям. Подписка с несколькими метками входит в каждую из них, подписки без меток или категории - в группу с пустым ключом",
                 "consumes": [
                     "application/json"
                 ],
@@ -312,16 +508,177 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscription"
+                    "Reports"
                 ],
-                "summary": "Удалить подписку",
+                "summary": "Расходы по группам",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "обязательна, если у подписок разные валюты",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "MM-YYYY, по умолчанию месяц начала",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "tag",
+                            "service",
+                            "category"
+                        ],
+                        "type": "string",
+                        "name": "group_by",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "MM-YYYY, по умолчанию текущий месяц",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SpendReport"
+                        }
+                    }
+                }
+            }
+        },
+        "/services": {
+            "get": {
+                "description": "Возвращает список всех сервисов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "Получить список сервисов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Service"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Добавляет новый сервис",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "Создать новый сервис",
+                "parameters": [
+                    {
+                        "description": "Service",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateService"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Service"
+                        }
+                    }
+                }
+            }
+        },
+        "/services/{id}": {
+            "put": {
+                "description": "Меняет категорию сервиса, по которой считаются бюджеты",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "Обновить сервис",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Service",
+                        "name": "service",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateService"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Service"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет существующий сервис",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "Удалить сервис",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -334,81 +691,2645 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.CreateService": {
+        },
+        "/services/{id}/stats": {
+            "get": {
+                "description": "Возвращает число подписчиков с действующей подпиской, среднюю, минимальную и максимальную цену и выручку за месяц по валютам (годовые цены приведены к месяцу) и число подписчиков по месяцам за последние months месяцев",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Service"
+                ],
+                "summary": "Статистика сервиса",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 60,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "за сколько месяцев тренд подписчиков, по умолчанию 12",
+                        "name": "months",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ServiceStats"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs": {
+            "get": {
+                "description": "Возвращает список всех подписок",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Получить список подписок",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "YYYY-MM-DD, состояние на конец указанного дня",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "только подписки с этой меткой",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "подписки пользователя, в том числе те, где он участник",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Subscription"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Добавляет новую подписку",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Добавить новую подписку",
+                "parameters": [
+                    {
+                        "description": "Subscription",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateSubscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/deadlines": {
+            "get": {
+                "description": "Возвращает подписки с автопродлением, последний день подачи отмены которых наступает в ближайшие within дней, по сроку",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Получить ближайшие сроки отмены перед продлением",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "за сколько дней",
+                        "name": "within",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RenewalDeadline"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/export": {
+            "get": {
+                "description": "Возвращает подписки по тем же фильтрам, что и список, файлом CSV. Метки перечислены через точку с запятой",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Выгрузить подписки в CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "YYYY-MM-DD, состояние на конец указанного дня",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "только подписки с этой меткой",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "подписки пользователя, в том числе те, где он участник",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/forecast": {
+            "get": {
+                "description": "Возвращает помесячный прогноз расходов на months месяцев начиная с текущего и накопленную сумму, если ничего не менять",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Прогноз расходов",
+                "parameters": [
+                    {
+                        "enum": [
+                            "payer",
+                            "share"
+                        ],
+                        "type": "string",
+                        "name": "cost_basis",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "обязательна, если у подписок разные валюты",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 120,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "по умолчанию 12",
+                        "name": "months",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Forecast"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/offers-ending": {
+            "get": {
+                "description": "Возвращает подписки, у которых в ближайшие within дней заканчивается пробный период или вводная цена",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Получить заканчивающиеся пробные периоды и акции",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "за сколько дней",
+                        "name": "within",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OfferEnding"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/simulate": {
+            "post": {
+                "description": "Сравнивает прогноз расходов до и после гипотетических изменений (отмена, смена тарифа, новая подписка). В базу ничего не записывается",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Симуляция изменений",
+                "parameters": [
+                    {
+                        "description": "Changes",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SimulationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SimulationResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/sum": {
+            "get": {
+                "description": "Возвращает сумму подписок по фильтрам: sum_minor в минимальных единицах и currency. net_minor, tax_minor и gross_minor - сумма без налога, налог и сумма с налогом. prorations - неполные периоды подписок: оплаченный период, дни, отрезки цены с дневной ставкой и пояснение расчета. Поле sum в целых единицах устарело",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Получить сумму подписок по фильтрам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "YYYY-MM-DD, считать по данным на конец указанного дня",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "payer",
+                            "share"
+                        ],
+                        "type": "string",
+                        "description": "payer - полная цена плательщику, share - доля каждого участника",
+                        "name": "cost_basis",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "обязательна, если у подписок разные валюты",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "вместе с подписками, ожидающими одобрения",
+                        "name": "include_pending",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "только подписки с этой меткой",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}": {
+            "get": {
+                "description": "Возвращает подписку по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Получить подписку по ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Обновляет существующую подписку",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Обновить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Subscription",
+                        "name": "subscription",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSubscription"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Помечает подписку удаленной (мягкое удаление), ее можно восстановить",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Удалить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/activate": {
+            "post": {
+                "description": "Переводит подписку из пробного периода в активный статус",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Активировать подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transition",
+                        "name": "transition",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/approve": {
+            "post": {
+                "description": "Одобряет подписку, ожидающую согласования: она становится активной (или пробной, если задан пробный период) с даты начала. Доступно только с TRUST_IDENTITY_HEADERS согласующим из APPROVERS (заголовок X-User-ID) или с ролью согласующего в заголовке X-User-Role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Approval"
+                ],
+                "summary": "Одобрить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Role",
+                        "name": "X-User-Role",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Comment",
+                        "name": "transition",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/attachments": {
+            "get": {
+                "description": "Возвращает список файлов, приложенных к подписке",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Получить вложения подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Attachment"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Загружает чек или счет в PDF или изображением (PNG, JPEG, GIF, WebP) размером до 10 МБ",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Приложить файл к подписке",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "PDF or image",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Attachment"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/attachments/{attachment_id}": {
+            "get": {
+                "description": "Возвращает файл с исходным именем и типом содержимого",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Скачать вложение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет файл из хранилища вместе с записью о нем",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Attachment"
+                ],
+                "summary": "Удалить вложение",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Attachment ID",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/cancel": {
+            "post": {
+                "description": "Отменяет подписку: effective_month становится последним оплаченным месяцем, причина и комментарий сохраняются в переходе статуса для отчета об оттоке. Отмена не может закончить подписку раньше минимального срока",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Отменить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cancellation",
+                        "name": "cancel",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CancelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/charges": {
+            "get": {
+                "description": "Возвращает начисления подписки из журнала: прошедшие и будущие списания с налогом и пояснениями к неполным периодам",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Получить начисления подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Charge"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/history": {
+            "get": {
+                "description": "Возвращает все версии подписки с интервалами их действия",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Получить историю подписки",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SubscriptionVersion"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/pause": {
+            "post": {
+                "description": "Ставит активную подписку на паузу, месяцы паузы не оплачиваются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Приостановить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transition",
+                        "name": "transition",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/reject": {
+            "post": {
+                "description": "Отклоняет подписку, ожидающую согласования, с комментарием. Отклоненная подписка не оплачивается. Доступно только с TRUST_IDENTITY_HEADERS согласующим из APPROVERS (заголовок X-User-ID) или с ролью согласующего в заголовке X-User-Role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Approval"
+                ],
+                "summary": "Отклонить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Role",
+                        "name": "X-User-Role",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Comment",
+                        "name": "transition",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/restore": {
+            "post": {
+                "description": "Восстанавливает удаленную подписку",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Восстановить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/subs/{id}/resume": {
+            "post": {
+                "description": "Снимает подписку с паузы",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscription"
+                ],
+                "summary": "Возобновить подписку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Transition",
+                        "name": "transition",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.TransitionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/suggestions": {
+            "get": {
+                "description": "Ищет в загруженных выписках пользователя ежемесячные и ежегодные списания, для которых нет подписки",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Suggestions"
+                ],
+                "summary": "Получить предложения подписок",
+                "parameters": [
+                    {
+                        "maximum": 50,
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "допустимое расхождение сумм в процентах, по умолчанию 5",
+                        "name": "amount_tolerance",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Suggestion"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/suggestions/accept": {
+            "post": {
+                "description": "Создает подписку (и сервис, если его нет) по регулярному платежу из выписок",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Suggestions"
+                ],
+                "summary": "Принять предложение",
+                "parameters": [
+                    {
+                        "description": "Suggestion",
+                        "name": "suggestion",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AcceptSuggestion"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Subscription"
+                        }
+                    }
+                }
+            }
+        },
+        "/teams": {
+            "get": {
+                "description": "Возвращает все команды с участниками",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Team"
+                ],
+                "summary": "Получить список команд",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Team"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создает команду пользователей для общего бюджета",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Team"
+                ],
+                "summary": "Создать команду",
+                "parameters": [
+                    {
+                        "description": "Team",
+                        "name": "team",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateTeam"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Team"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.AcceptSuggestion": {
+            "type": "object",
+            "required": [
+                "currency",
+                "merchant",
+                "user_id"
+            ],
+            "properties": {
+                "amount_tolerance": {
+                    "description": "как при поиске предложений",
+                    "type": "number",
+                    "maximum": 50,
+                    "minimum": 0
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "merchant": {
+                    "type": "string"
+                },
+                "price_minor": {
+                    "description": "по умолчанию сумма последнего списания",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "service_name": {
+                    "description": "по умолчанию из описания платежа",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AllocationInput": {
+            "type": "object",
+            "required": [
+                "cost_center_id",
+                "percent"
+            ],
+            "properties": {
+                "cost_center_id": {
+                    "type": "integer"
+                },
+                "percent": {
+                    "type": "number",
+                    "maximum": 100
+                }
+            }
+        },
+        "models.Attachment": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "определяется по содержимому файла",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "size": {
+                    "description": "в байтах",
+                    "type": "integer"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "diff": {
+                    "description": "только измененные поля: {\"поле\": {\"from\": ..., \"to\": ...}}",
+                    "type": "object"
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "request_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuditPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEntry"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "page_size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BankTransaction": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "как в выписке: списания отрицательные",
+                    "type": "integer"
+                },
+                "currency": {
+                    "description": "код валюты ISO 4217",
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "external_id": {
+                    "description": "FITID из OFX",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "import_id": {
+                    "type": "integer"
+                },
+                "merchant": {
+                    "description": "описание без цифр и знаков, по нему ищутся повторяющиеся платежи",
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "description": "подписка, начисление которой оплачено этой операцией",
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Budget": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "nil - все сервисы",
+                    "type": "string"
+                },
+                "cost_basis": {
+                    "description": "payer или share, как в сумме подписок",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "учитываются только подписки в этой валюте",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "limit": {
+                    "description": "устарело: лимит в целых единицах",
+                    "type": "integer"
+                },
+                "limit_minor": {
+                    "description": "в минимальных единицах валюты",
+                    "type": "integer"
+                },
+                "team": {
+                    "$ref": "#/definitions/models.Team"
+                },
+                "team_id": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BudgetReport": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "$ref": "#/definitions/models.Budget"
+                },
+                "current_minor": {
+                    "description": "начисления месяца, которые уже прошли",
+                    "type": "integer"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "over_budget": {
+                    "type": "boolean"
+                },
+                "projected_minor": {
+                    "description": "все начисления месяца",
+                    "type": "integer"
+                },
+                "remaining_minor": {
+                    "description": "лимит минус прогноз, может быть отрицательным",
+                    "type": "integer"
+                },
+                "taxes": {
+                    "description": "прогноз без налога, налог и с налогом",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.TaxTotals"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.CancelRequest": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "description": "подробности в свободной форме",
+                    "type": "string"
+                },
+                "date": {
+                    "description": "устарело: то же, что effective_month",
+                    "type": "string"
+                },
+                "effective_month": {
+                    "description": "MM-YYYY - последний оплаченный месяц, по умолчанию текущий",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "enum": [
+                        "too_expensive",
+                        "not_used",
+                        "switched",
+                        "missing_features",
+                        "duplicate",
+                        "other"
+                    ]
+                }
+            }
+        },
+        "models.Charge": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "полная стоимость списания в минимальных единицах",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "валюта подписки",
+                    "type": "string"
+                },
+                "date": {
+                    "description": "день списания",
+                    "type": "string"
+                },
+                "explanation": {
+                    "description": "как посчитан неполный период",
+                    "type": "string"
+                },
+                "gross_minor": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "net_minor": {
+                    "type": "integer"
+                },
+                "proration": {
+                    "description": "подробный расчет неполного периода для ответа суммы",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Proration"
+                        }
+                    ]
+                },
+                "subscription_id": {
+                    "type": "integer"
+                },
+                "tax_minor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ChargebackLine": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "integer"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "cost_center_id": {
+                    "description": "0 - подписки без распределения",
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "subscriptions": {
+                    "type": "integer"
+                },
+                "taxes": {
+                    "$ref": "#/definitions/models.TaxTotals"
+                }
+            }
+        },
+        "models.ChargebackReport": {
+            "type": "object",
+            "properties": {
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChargebackLine"
+                    }
+                },
+                "month": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChurnLine": {
+            "type": "object",
+            "properties": {
+                "cancellations": {
+                    "type": "integer"
+                },
+                "month": {
+                    "description": "месяц, с которого действует отмена",
+                    "type": "string"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChurnReason": {
+            "type": "object",
+            "properties": {
+                "cancellations": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChurnReport": {
+            "type": "object",
+            "properties": {
+                "average_lifetime_months": {
+                    "description": "от даты начала до даты окончания",
+                    "type": "number"
+                },
+                "cancellations": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChurnLine"
+                    }
+                },
+                "reasons": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChurnReason"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CostAllocation": {
+            "type": "object",
+            "properties": {
+                "cost_center": {
+                    "$ref": "#/definitions/models.CostCenter"
+                },
+                "cost_center_id": {
+                    "type": "integer"
+                },
+                "percent": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.CostCenter": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "код для бухгалтерии, например \"IT-01\"",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateBudget": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "cost_basis": {
+                    "type": "string",
+                    "enum": [
+                        "payer",
+                        "share"
+                    ]
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "limit": {
+                    "description": "устарело: лимит в целых единицах, если не задан limit_minor",
+                    "type": "integer"
+                },
+                "limit_minor": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "team_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateCostCenter": {
+            "type": "object",
+            "required": [
+                "code",
+                "name"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateService": {
+            "type": "object",
+            "required": [
+                "aliases",
+                "name"
+            ],
+            "properties": {
+                "aliases": {
+                    "description": "названия в банковских выписках",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "category": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "tax_inclusive": {
+                    "description": "цены подписок включают налог",
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "description": "ставка налога в процентах",
+                    "type": "number",
+                    "maximum": 100,
+                    "minimum": 0
+                }
+            }
+        },
+        "models.CreateSubscription": {
+            "type": "object",
+            "required": [
+                "service_name",
+                "start_date",
+                "tags",
+                "user_id"
+            ],
+            "properties": {
+                "allocations": {
+                    "description": "доли центров затрат, в сумме 100%",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AllocationInput"
+                    }
+                },
+                "anchor_day": {
+                    "description": "день списания, по умолчанию день даты начала",
+                    "type": "integer",
+                    "maximum": 31,
+                    "minimum": 1
+                },
+                "auto_renew": {
+                    "type": "boolean"
+                },
+                "billing_period": {
+                    "description": "по умолчанию monthly, price - цена за период",
+                    "type": "string",
+                    "enum": [
+                        "monthly",
+                        "annual"
+                    ]
+                },
+                "currency": {
+                    "description": "по умолчанию RUB",
+                    "type": "string"
+                },
+                "end_date": {
+                    "description": "YYYY-MM-DD или MM-YYYY (месяц целиком)",
+                    "type": "string"
+                },
+                "members": {
+                    "description": "участники совместной подписки",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MemberInput"
+                    }
+                },
+                "minimum_term_months": {
+                    "type": "integer",
+                    "maximum": 120
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "notice_days": {
+                    "type": "integer",
+                    "maximum": 365
+                },
+                "price": {
+                    "description": "устарело: цена в целых единицах, если не задан price_minor",
+                    "type": "integer"
+                },
+                "price_minor": {
+                    "description": "цена в минимальных единицах, указатель чтобы отличать 0 от nil",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "promo_months": {
+                    "description": "сколько оплачиваемых месяцев действует вводная цена, задается вместе с ценой",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "promo_price": {
+                    "description": "устарело: вводная цена в целых единицах",
+                    "type": "integer"
+                },
+                "promo_price_minor": {
+                    "description": "вводная цена в минимальных единицах",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "proration_mode": {
+                    "description": "по умолчанию none",
+                    "type": "string",
+                    "enum": [
+                        "none",
+                        "daily",
+                        "by-anchor-day"
+                    ]
+                },
+                "renewal_term_months": {
+                    "description": "по умолчанию период оплаты",
+                    "type": "integer",
+                    "maximum": 120,
+                    "minimum": 1
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "YYYY-MM-DD или MM-YYYY (первое число месяца)",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "начальный статус, по умолчанию active (trial, если задан пробный период)",
+                    "type": "string",
+                    "enum": [
+                        "trial",
+                        "active"
+                    ]
+                },
+                "tags": {
+                    "description": "метки, регистр не важен",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "description": "по умолчанию ставка сервиса",
+                    "type": "number",
+                    "maximum": 100,
+                    "minimum": 0
+                },
+                "trial_days": {
+                    "description": "длина пробного периода в днях",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateTeam": {
+            "type": "object",
+            "required": [
+                "name",
+                "user_ids"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "user_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.ExpectedCharge": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "с налогом, как списывается с карты",
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Forecast": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "months": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ForecastMonth"
+                    }
+                },
+                "taxes": {
+                    "$ref": "#/definitions/models.TaxTotals"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total_minor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ForecastMonth": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "в минимальных единицах валюты прогноза",
+                    "type": "integer"
+                },
+                "cumulative_minor": {
+                    "description": "сумма с первого месяца прогноза по этот включительно",
+                    "type": "integer"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "prorations": {
+                    "description": "как посчитаны неполные периоды этого месяца",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "taxes": {
+                    "description": "сумма месяца без налога, налог и с налогом",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.TaxTotals"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.MemberInput": {
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "share_amount": {
+                    "description": "устарело: сумма в целых единицах",
+                    "type": "integer"
+                },
+                "share_amount_minor": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "share_percent": {
+                    "type": "number",
+                    "maximum": 100
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OfferEnding": {
+            "type": "object",
+            "properties": {
+                "ends_at": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "trial или promo",
+                    "type": "string"
+                },
+                "next_price_minor": {
+                    "description": "цена, которая начнет действовать, в минимальных единицах",
+                    "type": "integer"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/models.Subscription"
+                }
+            }
+        },
+        "models.PriceStats": {
+            "type": "object",
+            "properties": {
+                "avg_price_minor": {
+                    "type": "integer"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "max_price_minor": {
+                    "type": "integer"
+                },
+                "min_price_minor": {
+                    "type": "integer"
+                },
+                "monthly_revenue_minor": {
+                    "description": "начисления текущего месяца из журнала с налогом",
+                    "type": "integer"
+                },
+                "subscriptions": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Proration": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "integer"
+                },
+                "cycle_days": {
+                    "description": "на сколько дней делится цена",
+                    "type": "integer"
+                },
+                "days": {
+                    "description": "оплаченных дней",
+                    "type": "integer"
+                },
+                "explanation": {
+                    "type": "string"
+                },
+                "mode": {
+                    "description": "daily или by-anchor-day",
+                    "type": "string"
+                },
+                "month": {
+                    "type": "string"
+                },
+                "parts": {
+                    "description": "отрезки периода с разной ценой",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ProrationPart"
+                    }
+                },
+                "period_end": {
+                    "description": "последний оплаченный день",
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ProrationPart": {
+            "type": "object",
+            "properties": {
+                "daily_rate_minor": {
+                    "description": "цена, деленная на cycle_days, до сотых минимальной единицы",
+                    "type": "number"
+                },
+                "days": {
+                    "type": "integer"
+                },
+                "price_minor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ReconcileMatch": {
+            "type": "object",
+            "properties": {
+                "expected": {
+                    "$ref": "#/definitions/models.ExpectedCharge"
+                },
+                "transaction": {
+                    "$ref": "#/definitions/models.BankTransaction"
+                }
+            }
+        },
+        "models.ReconcileReport": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "первая и последняя даты операций в выписке",
+                    "type": "string"
+                },
+                "import_id": {
+                    "type": "integer"
+                },
+                "matched": {
+                    "description": "начисления, за которые нашлось списание",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ReconcileMatch"
+                    }
+                },
+                "missing": {
+                    "description": "начисления за период выписки без списания",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExpectedCharge"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "integer"
+                },
+                "unknown": {
+                    "description": "повторяющиеся списания, которых нет среди подписок",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RecurringPayment"
+                    }
+                }
+            }
+        },
+        "models.RecurringPayment": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "сумма последнего списания",
+                    "type": "integer"
+                },
+                "cadence": {
+                    "description": "monthly или annual",
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "description": "описание последней операции, как в выписке",
+                    "type": "string"
+                },
+                "merchant": {
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BankTransaction"
+                    }
+                }
+            }
+        },
+        "models.RenewalDeadline": {
+            "type": "object",
+            "properties": {
+                "days_left": {
+                    "description": "сколько дней осталось до срока",
+                    "type": "integer"
+                },
+                "deadline": {
+                    "description": "последний день подачи отмены",
+                    "type": "string"
+                },
+                "renews_at": {
+                    "description": "когда подписка продлится",
+                    "type": "string"
+                },
+                "subscription": {
+                    "$ref": "#/definitions/models.Subscription"
+                }
+            }
+        },
+        "models.Service": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "description": "как сервис называется в банковских выписках",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ServiceAlias"
+                    }
+                },
+                "category": {
+                    "description": "категория для бюджетов",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "tax_inclusive": {
+                    "description": "цена уже включает налог",
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "description": "ставка налога в процентах для подписок сервиса, nil - без налога",
+                    "type": "number"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ServiceAlias": {
+            "type": "object",
+            "properties": {
+                "alias": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ServiceStats": {
+            "type": "object",
+            "properties": {
+                "active_subscribers": {
+                    "description": "разные пользователи с действующей подпиской",
+                    "type": "integer"
+                },
+                "prices": {
+                    "description": "по валютам",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PriceStats"
+                    }
+                },
+                "service_id": {
+                    "type": "integer"
+                },
+                "service_name": {
+                    "type": "string"
+                },
+                "trend": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SubscriberPoint"
+                    }
+                }
+            }
+        },
+        "models.SimulationChange": {
+            "type": "object",
+            "required": [
+                "action"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "cancel",
+                        "switch",
+                        "add"
+                    ]
+                },
+                "billing_period": {
+                    "description": "для switch и add",
+                    "type": "string",
+                    "enum": [
+                        "monthly",
+                        "annual"
+                    ]
+                },
+                "month": {
+                    "description": "MM-YYYY, с какого месяца действует изменение (для cancel - первый неоплаченный месяц), по умолчанию первый месяц прогноза",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "устарело: цена в целых единицах",
+                    "type": "integer"
+                },
+                "price_minor": {
+                    "description": "новая цена за период для switch, цена для add, в минимальных единицах",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "service_name": {
+                    "description": "название новой подписки для add",
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "description": "для cancel и switch",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SimulationRequest": {
+            "type": "object",
+            "required": [
+                "changes"
+            ],
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/models.SimulationChange"
+                    }
+                },
+                "cost_basis": {
+                    "type": "string",
+                    "enum": [
+                        "payer",
+                        "share"
+                    ]
+                },
+                "currency": {
+                    "description": "валюта прогноза и новых подписок",
+                    "type": "string"
+                },
+                "months": {
+                    "description": "по умолчанию 12",
+                    "type": "integer",
+                    "maximum": 120,
+                    "minimum": 1
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SimulationResult": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "$ref": "#/definitions/models.Forecast"
+                },
+                "before": {
+                    "$ref": "#/definitions/models.Forecast"
+                },
+                "savings_minor": {
+                    "description": "на сколько меньше потратим, отрицательное значение - перерасход",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SpendGroup": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "метка, сервис или категория, пустая строка - без метки или категории",
+                    "type": "string"
+                },
+                "subscriptions": {
+                    "type": "integer"
+                },
+                "taxes": {
+                    "$ref": "#/definitions/models.TaxTotals"
+                }
+            }
+        },
+        "models.SpendReport": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "group_by": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SpendGroup"
+                    }
+                },
+                "taxes": {
+                    "$ref": "#/definitions/models.TaxTotals"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total_minor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StatusTransition": {
             "type": "object",
-            "required": [
-                "name"
-            ],
             "properties": {
-                "name": {
+                "actor": {
+                    "description": "кто перевел подписку, из заголовка X-User-ID",
+                    "type": "string"
+                },
+                "comment": {
+                    "description": "комментарий к переходу, например причина отказа",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "date": {
+                    "description": "с какого месяца действует новый статус",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "пусто для начального статуса",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "description": "код причины отмены",
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                },
+                "to": {
                     "type": "string"
                 }
             }
         },
-        "models.CreateSubscription": {
+        "models.SubscriberPoint": {
+            "type": "object",
+            "properties": {
+                "month": {
+                    "type": "string"
+                },
+                "subscribers": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Subscription": {
             "type": "object",
-            "required": [
-                "price",
-                "service_name",
-                "start_date",
-                "user_id"
-            ],
             "properties": {
+                "allocations": {
+                    "description": "на какие центры затрат относится стоимость",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CostAllocation"
+                    }
+                },
+                "anchor_day": {
+                    "description": "день месяца списания, в коротких месяцах - последний день",
+                    "type": "integer"
+                },
+                "auto_renew": {
+                    "description": "продлевается автоматически, пока не отменена",
+                    "type": "boolean"
+                },
+                "billing_period": {
+                    "description": "monthly или annual: годовая оплата списывается раз в 12 месяцев",
+                    "type": "string"
+                },
+                "cancellation_deadline": {
+                    "description": "последний день, когда отмена успевает до продления",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "код валюты ISO 4217",
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "мягкое удаление",
+                    "type": "string"
+                },
                 "end_date": {
+                    "description": "используем указатель, чтобы можно было использовать nil",
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer"
+                },
+                "members": {
+                    "description": "с кем делится стоимость",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SubscriptionMember"
+                    }
+                },
+                "minimum_term_months": {
+                    "description": "первый срок договора, 0 - как срок продления",
+                    "type": "integer"
+                },
+                "next_renewal": {
+                    "description": "продление, от которого защищает срок отмены",
+                    "type": "string"
+                },
+                "notes": {
+                    "description": "произвольные заметки пользователя",
+                    "type": "string"
+                },
+                "notice_days": {
+                    "description": "за сколько дней до продления нужно подать отмену",
+                    "type": "integer"
+                },
                 "price": {
-                    "type": "integer",
-                    "minimum": 0
+                    "description": "устарело: цена в целых единицах для старых клиентов",
+                    "type": "integer"
                 },
-                "service_name": {
+                "price_minor": {
+                    "description": "цена за период в минимальных единицах валюты",
+                    "type": "integer"
+                },
+                "promo_end_date": {
+                    "description": "с этого месяца действует обычная цена",
+                    "type": "string"
+                },
+                "promo_price": {
+                    "description": "устарело: вводная цена в целых единицах",
+                    "type": "integer"
+                },
+                "promo_price_minor": {
+                    "description": "вводная цена в минимальных единицах",
+                    "type": "integer"
+                },
+                "proration_mode": {
+                    "description": "пересчет неполных периодов: none, daily или by-anchor-day",
                     "type": "string"
                 },
+                "renewal_term_months": {
+                    "description": "на сколько месяцев продлевается, 0 - на период оплаты",
+                    "type": "integer"
+                },
+                "service": {
+                    "$ref": "#/definitions/models.Service"
+                },
+                "service_id": {
+                    "type": "integer"
+                },
                 "start_date": {
                     "type": "string"
                 },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Tag"
+                    }
+                },
+                "tax_inclusive": {
+                    "description": "цена включает налог, nil - как у сервиса",
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "description": "ставка налога в процентах, nil - как у сервиса",
+                    "type": "number"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatusTransition"
+                    }
+                },
+                "trial_end_date": {
+                    "description": "первый день после пробного периода",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
                 "user_id": {
                     "type": "string"
+                },
+                "warnings": {
+                    "description": "предупреждения после создания или обновления, например о превышении бюджета",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "models.Service": {
+        "models.SubscriptionMember": {
             "type": "object",
             "properties": {
-                "createdAt": {
+                "created_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "name": {
-                    "type": "string"
+                "share_amount": {
+                    "description": "устарело: сумма в целых единицах",
+                    "type": "integer"
                 },
-                "updatedAt": {
+                "share_amount_minor": {
+                    "description": "или фиксированная сумма в месяц в минимальных единицах",
+                    "type": "integer"
+                },
+                "share_percent": {
+                    "description": "доля в процентах от цены месяца",
+                    "type": "number"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                },
+                "user_id": {
                     "type": "string"
                 }
             }
         },
-        "models.Subscription": {
+        "models.SubscriptionVersion": {
             "type": "object",
             "properties": {
-                "createdAt": {
+                "anchor_day": {
+                    "type": "integer"
+                },
+                "billing_period": {
                     "type": "string"
                 },
+                "currency": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "description": "версия, созданная удалением",
+                    "type": "boolean"
+                },
                 "end_date": {
                     "type": "string"
                 },
-                "id": {
+                "price": {
+                    "description": "устарело: цена в целых единицах",
                     "type": "integer"
                 },
-                "price": {
+                "price_minor": {
                     "type": "integer"
                 },
-                "service": {
-                    "$ref": "#/definitions/models.Service"
+                "promo_end_date": {
+                    "type": "string"
+                },
+                "promo_price_minor": {
+                    "type": "integer"
+                },
+                "proration_mode": {
+                    "type": "string"
                 },
                 "service_id": {
                     "type": "integer"
@@ -416,22 +3337,231 @@ const docTemplate = `{
                 "start_date": {
                     "type": "string"
                 },
-                "updatedAt": {
+                "status": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                },
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "type": "number"
+                },
+                "trial_end_date": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "valid_from": {
+                    "type": "string"
+                },
+                "valid_to": {
+                    "description": "nil - текущая версия",
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Suggestion": {
+            "type": "object",
+            "properties": {
+                "amount_minor": {
+                    "description": "сумма последнего списания",
+                    "type": "integer"
+                },
+                "cadence": {
+                    "description": "monthly или annual",
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "description": {
+                    "description": "описание последней операции, как в выписке",
+                    "type": "string"
+                },
+                "merchant": {
+                    "type": "string"
+                },
+                "service_name": {
+                    "description": "название сервиса, с которым будет создана подписка",
+                    "type": "string"
+                },
+                "transactions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BankTransaction"
+                    }
+                }
+            }
+        },
+        "models.Tag": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaxTotals": {
+            "type": "object",
+            "properties": {
+                "gross_minor": {
+                    "type": "integer"
+                },
+                "net_minor": {
+                    "type": "integer"
+                },
+                "tax_minor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Team": {
+            "type": "object",
+            "properties": {
+                "created_at": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TeamMember"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TeamMember": {
+            "type": "object",
+            "properties": {
                 "user_id": {
                     "type": "string"
                 }
             }
         },
+        "models.TransitionRequest": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "description": "для одобрения и отказа",
+                    "type": "string"
+                },
+                "date": {
+                    "description": "MM-YYYY, по умолчанию текущий месяц",
+                    "type": "string"
+                }
+            }
+        },
+        "models.UpdateService": {
+            "type": "object",
+            "required": [
+                "aliases"
+            ],
+            "properties": {
+                "aliases": {
+                    "description": "заменяет список названий, nil - без изменений",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "category": {
+                    "type": "string"
+                },
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "type": "number",
+                    "maximum": 100,
+                    "minimum": 0
+                }
+            }
+        },
         "models.UpdateSubscription": {
             "type": "object",
+            "required": [
+                "tags"
+            ],
             "properties": {
+                "allocations": {
+                    "description": "заменяет распределение по центрам затрат, пустой список убирает его",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AllocationInput"
+                    }
+                },
+                "auto_renew": {
+                    "type": "boolean"
+                },
                 "end_date": {
                     "type": "string"
                 },
+                "members": {
+                    "description": "заменяет список участников, пустой список убирает всех",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MemberInput"
+                    }
+                },
+                "minimum_term_months": {
+                    "type": "integer",
+                    "maximum": 120
+                },
+                "notes": {
+                    "description": "пустая строка удаляет заметки",
+                    "type": "string"
+                },
+                "notice_days": {
+                    "type": "integer",
+                    "maximum": 365
+                },
                 "price": {
+                    "description": "устарело: цена в целых единицах",
                     "type": "integer"
+                },
+                "price_minor": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "proration_mode": {
+                    "type": "string",
+                    "enum": [
+                        "none",
+                        "daily",
+                        "by-anchor-day"
+                    ]
+                },
+                "renewal_term_months": {
+                    "description": "0 - на период оплаты",
+                    "type": "integer",
+                    "maximum": 120
+                },
+                "tags": {
+                    "description": "заменяет метки, пустой список убирает все",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "type": "number",
+                    "maximum": 100,
+                    "minimum": 0
                 }
             }
         }
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	TypeBudgetExceeded = "budget.exceeded"
+
+	TypeSubscriptionSubmitted = "subscription.submitted" //создана и ждет одобрения
+	TypeSubscriptionApproved  = "subscription.approved"
+	TypeSubscriptionRejected  = "subscription.rejected"
+	TypeSubscriptionActivated = "subscription.activated"
+	TypeSubscriptionPaused    = "subscription.paused"
+	TypeSubscriptionResumed   = "subscription.resumed"
+	TypeSubscriptionCancelled = "subscription.cancelled"
+	TypeSubscriptionRenewed   = "subscription.renewed" //автопродление на следующий срок
+)
+
+// событие предметной области
+type Event struct {
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Payload    interface{} `json:"payload"`
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+type LogPublisher struct {
+	logger *zap.SugaredLogger
+}
+
+func NewLogPublisher(logger *zap.SugaredLogger) Publisher { //публикация событий в лог
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, event Event) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		p.logger.Errorf("Marshal event %s failed: %v", event.Type, err)
+		return
+	}
+	p.logger.Warnw("Event", "type", event.Type, "event", string(data))
+}
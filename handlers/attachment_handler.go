@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"subscriptions/services"
+	"subscriptions/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AttachmentHandler struct {
+	service services.AttachmentServiceInterface
+}
+
+func NewAttachmentHandler(service services.AttachmentServiceInterface) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+// @Summary Приложить файл к подписке
+// @Schemes
+// @Description Загружает чек или счет в PDF или изображением (PNG, JPEG, GIF, WebP) размером до 10 МБ
+// @Tags Attachment
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param file formData file true "PDF or image"
+// @Success 201 {object} models.Attachment
+// @Router /subs/{id}/attachments [post]
+func (handler *AttachmentHandler) Upload(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxAttachmentSize+1<<20) //с запасом на заголовки multipart
+	file, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if file.Size > services.MaxAttachmentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+		return
+	}
+	opened, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer opened.Close()
+	data, err := io.ReadAll(opened)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attachment, err := handler.service.Upload(c.Request.Context(), uint(id), file.Filename, data)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+			return
+		}
+		if errors.Is(err, services.ErrAttachmentTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrUnsupportedAttachment) {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// @Summary Получить вложения подписки
+// @Schemes
+// @Description Возвращает список файлов, приложенных к подписке
+// @Tags Attachment
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {array} models.Attachment
+// @Router /subs/{id}/attachments [get]
+func (handler *AttachmentHandler) List(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	attachments, err := handler.service.List(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, attachments)
+}
+
+// @Summary Скачать вложение
+// @Schemes
+// @Description Возвращает файл с исходным именем и типом содержимого
+// @Tags Attachment
+// @Produce octet-stream
+// @Param id path int true "Subscription ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Success 200 {file} file
+// @Router /subs/{id}/attachments/{attachment_id} [get]
+func (handler *AttachmentHandler) Download(c *gin.Context) {
+	id, attachmentID, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+	attachment, content, err := handler.service.Download(c.Request.Context(), id, attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, storage.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName})
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, content, map[string]string{"Content-Disposition": disposition})
+}
+
+// @Summary Удалить вложение
+// @Schemes
+// @Description Удаляет файл из хранилища вместе с записью о нем
+// @Tags Attachment
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param attachment_id path int true "Attachment ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /subs/{id}/attachments/{attachment_id} [delete]
+func (handler *AttachmentHandler) Delete(c *gin.Context) {
+	id, attachmentID, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+	if err := handler.service.Delete(c.Request.Context(), id, attachmentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+func attachmentIDs(c *gin.Context) (uint, uint, bool) { //id подписки и вложения из пути
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+	attachmentID, err := strconv.ParseUint(c.Param("attachment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, 0, false
+	}
+	return uint(id), uint(attachmentID), true
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	service services.AuditServiceInterface
+}
+
+func NewAuditHandler(service services.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// @Summary Получить журнал изменений
+// @Schemes
+// @Description Возвращает записи журнала изменений с фильтрацией и пагинацией
+// @Tags Audit
+// @Accept json
+// @Produce json
+// @Param filters query models.AuditFilter false "Filters"
+// @Success 200 {object} models.AuditPage
+// @Router /audit [get]
+func (handler *AuditHandler) List(c *gin.Context) {
+	var filter models.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := handler.service.List(c.Request.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAuditFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BudgetHandler struct {
+	service services.BudgetServiceInterface
+}
+
+func NewBudgetHandler(service services.BudgetServiceInterface) *BudgetHandler {
+	return &BudgetHandler{service: service}
+}
+
+// @Summary Создать бюджет
+// @Schemes
+// @Description Задает месячный лимит расходов пользователя или команды, возможно только для категории сервисов
+// @Tags Budget
+// @Accept json
+// @Produce json
+// @Param budget body models.CreateBudget true "Budget"
+// @Success 201 {object} models.Budget
+// @Router /budgets [post]
+func (handler *BudgetHandler) Create(c *gin.Context) {
+	var budget models.CreateBudget
+	if err := c.ShouldBindJSON(&budget); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	newBudget, err := handler.service.Create(c.Request.Context(), &budget)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Team not found"})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidPrice) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, newBudget)
+}
+
+// @Summary Получить бюджеты
+// @Schemes
+// @Description Возвращает бюджеты с текущими и прогнозными расходами за текущий месяц
+// @Tags Budget
+// @Accept json
+// @Produce json
+// @Param filters query models.BudgetFilter false "Filters"
+// @Success 200 {array} models.BudgetReport
+// @Router /budgets [get]
+func (handler *BudgetHandler) List(c *gin.Context) {
+	var filter models.BudgetFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reports, err := handler.service.List(c.Request.Context(), &filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+// @Summary Получить бюджет
+// @Schemes
+// @Description Возвращает бюджет с текущими и прогнозными расходами за текущий месяц
+// @Tags Budget
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.BudgetReport
+// @Router /budgets/{id} [get]
+func (handler *BudgetHandler) GetById(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := handler.service.GetReport(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Удалить бюджет
+// @Schemes
+// @Description Удаляет бюджет
+// @Tags Budget
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /budgets/{id} [delete]
+func (handler *BudgetHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err = handler.service.Delete(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+}
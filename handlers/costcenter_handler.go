@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CostCenterHandler struct {
+	service services.CostCenterServiceInterface
+}
+
+func NewCostCenterHandler(service services.CostCenterServiceInterface) *CostCenterHandler {
+	return &CostCenterHandler{service: service}
+}
+
+// @Summary Создать центр затрат
+// @Schemes
+// @Description Создает центр затрат, на который можно относить стоимость подписок
+// @Tags CostCenter
+// @Accept json
+// @Produce json
+// @Param center body models.CreateCostCenter true "Cost center"
+// @Success 201 {object} models.CostCenter
+// @Router /cost-centers [post]
+func (handler *CostCenterHandler) Create(c *gin.Context) {
+	var center models.CreateCostCenter
+	if err := c.ShouldBindJSON(&center); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	newCenter, err := handler.service.Create(c.Request.Context(), &center)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, newCenter)
+}
+
+// @Summary Получить список центров затрат
+// @Schemes
+// @Description Возвращает все центры затрат по коду
+// @Tags CostCenter
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.CostCenter
+// @Router /cost-centers [get]
+func (handler *CostCenterHandler) GetAll(c *gin.Context) {
+	centers, err := handler.service.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, centers)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"subscriptions/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// writeCSV отдает таблицу файлом CSV с заголовком в первой строке
+func writeCSV(c *gin.Context, fileName string, header []string, rows [][]string) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(header); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := w.WriteAll(rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": fileName})
+	c.Header("Content-Disposition", disposition)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// formatMinor записывает сумму в минимальных единицах десятичной дробью: 999 -> "9.99"
+func formatMinor(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/models.MinorUnits, amount%models.MinorUnits)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary Активировать подписку
+// @Schemes
+// @Description Переводит подписку из пробного периода в активный статус
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param transition body models.TransitionRequest false "Transition"
+// @Success 200 {object} models.Subscription
+// @Router /subs/{id}/activate [post]
+func (handler *SubscriptionHandler) Activate(c *gin.Context) {
+	handler.changeStatus(c, services.ActionActivate)
+}
+
+// @Summary Приостановить подписку
+// @Schemes
+// @Description Ставит активную подписку на паузу, месяцы паузы не оплачиваются
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param transition body models.TransitionRequest false "Transition"
+// @Success 200 {object} models.Subscription
+// @Router /subs/{id}/pause [post]
+func (handler *SubscriptionHandler) Pause(c *gin.Context) {
+	handler.changeStatus(c, services.ActionPause)
+}
+
+// @Summary Возобновить подписку
+// @Schemes
+// @Description Снимает подписку с паузы
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param transition body models.TransitionRequest false "Transition"
+// @Success 200 {object} models.Subscription
+// @Router /subs/{id}/resume [post]
+func (handler *SubscriptionHandler) Resume(c *gin.Context) {
+	handler.changeStatus(c, services.ActionResume)
+}
+
+// @Summary Отменить подписку
+// @Schemes
+// @Description Отменяет подписку: effective_month становится последним оплаченным месяцем, причина и комментарий сохраняются в переходе статуса для отчета об оттоке. Отмена не может закончить подписку раньше минимального срока
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param cancel body models.CancelRequest false "Cancellation"
+// @Success 200 {object} models.Subscription
+// @Router /subs/{id}/cancel [post]
+func (handler *SubscriptionHandler) Cancel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var request models.CancelRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) { //тело необязательное
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := handler.service.Cancel(c.Request.Context(), uint(id), &request)
+	statusChanged(c, sub, err)
+}
+
+// @Summary Одобрить подписку
+// @Schemes
+// @Description Одобряет подписку, ожидающую согласования: она становится активной (или пробной, если задан пробный период) с даты начала. Доступно только с TRUST_IDENTITY_HEADERS согласующим из APPROVERS (заголовок X-User-ID) или с ролью согласующего в заголовке X-User-Role
+// @Tags Approval
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param X-User-ID header string false "User ID"
+// @Param X-User-Role header string false "Role"
+// @Param transition body models.TransitionRequest false "Comment"
+// @Success 200 {object} models.Subscription
+// @Router /subs/{id}/approve [post]
+func (handler *SubscriptionHandler) Approve(c *gin.Context) {
+	handler.changeStatus(c, services.ActionApprove)
+}
+
+// @Summary Отклонить подписку
+// @Schemes
+// @Description Отклоняет подписку, ожидающую согласования, с комментарием. Отклоненная подписка не оплачивается. Доступно только с TRUST_IDENTITY_HEADERS согласующим из APPROVERS (заголовок X-User-ID) или с ролью согласующего в заголовке X-User-Role
+// @Tags Approval
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param X-User-ID header string false "User ID"
+// @Param X-User-Role header string false "Role"
+// @Param transition body models.TransitionRequest false "Comment"
+// @Success 200 {object} models.Subscription
+// @Router /subs/{id}/reject [post]
+func (handler *SubscriptionHandler) Reject(c *gin.Context) {
+	handler.changeStatus(c, services.ActionReject)
+}
+
+// @Summary Подписки на согласовании
+// @Schemes
+// @Description Возвращает подписки, ожидающие одобрения. Доступно только с TRUST_IDENTITY_HEADERS согласующим из APPROVERS (заголовок X-User-ID) или с ролью согласующего в заголовке X-User-Role
+// @Tags Approval
+// @Accept json
+// @Produce json
+// @Param X-User-ID header string false "User ID"
+// @Param X-User-Role header string false "Role"
+// @Param filters query models.ApprovalFilter false "Filters"
+// @Success 200 {array} models.Subscription
+// @Router /approvals [get]
+func (handler *SubscriptionHandler) Approvals(c *gin.Context) {
+	var filter models.ApprovalFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subscriptions, err := handler.service.Approvals(c.Request.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrApproverRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (handler *SubscriptionHandler) changeStatus(c *gin.Context, action string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var request models.TransitionRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) { //тело необязательное
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := handler.service.ChangeStatus(c.Request.Context(), uint(id), action, &request)
+	statusChanged(c, sub, err)
+}
+
+func statusChanged(c *gin.Context, sub *models.Subscription, err error) {
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+			return
+		}
+		if errors.Is(err, services.ErrApproverRequired) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTransitionDate) || errors.Is(err, services.ErrMinimumTerm) || errors.Is(err, services.ErrInvalidDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
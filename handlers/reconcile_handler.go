@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxStatementSize = 10 << 20 //10 МБ, выписка за несколько лет
+
+type ReconcileHandler struct {
+	service services.ReconcileServiceInterface
+}
+
+func NewReconcileHandler(service services.ReconcileServiceInterface) *ReconcileHandler {
+	return &ReconcileHandler{service: service}
+}
+
+// @Summary Сверить банковскую выписку
+// @Schemes
+// @Description Загружает выписку в CSV или OFX и сверяет операции с начислениями подписок пользователя: оплаченные начисления, начисления без списания и регулярные списания, которых нет среди подписок
+// @Tags Reconcile
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Statement (CSV or OFX)"
+// @Param user_id formData string true "User ID"
+// @Param format formData string false "csv or ofx"
+// @Param currency formData string false "Currency of CSV rows without currency column"
+// @Param tolerance_days formData int false "Date tolerance in days, default 3"
+// @Param amount_tolerance formData number false "Amount tolerance in percent, default 5"
+// @Success 201 {object} models.ReconcileReport
+// @Router /reconcile [post]
+func (handler *ReconcileHandler) Reconcile(c *gin.Context) {
+	var request models.ReconcileRequest
+	if err := c.ShouldBind(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if file.Size > maxStatementSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "statement is too large"})
+		return
+	}
+	opened, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer opened.Close()
+	data, err := io.ReadAll(opened)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := handler.service.Reconcile(c.Request.Context(), &request, file.Filename, data)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStatement) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler struct {
+	service services.ReportServiceInterface
+}
+
+func NewReportHandler(service services.ReportServiceInterface) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// @Summary Расходы по группам
+// @Schemes
+// @Description Раскладывает расходы за месяцы периода по меткам (group_by=tag), сервисам или категориям. Подписка с несколькими метками входит в каждую из них, подписки без меток или категории - в группу с пустым ключом
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param filters query models.SpendFilter true "Filters"
+// @Success 200 {object} models.SpendReport
+// @Router /reports/spend [get]
+func (handler *ReportHandler) Spend(c *gin.Context) {
+	var filter models.SpendFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := handler.service.Spend(c.Request.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDate) || errors.Is(err, services.ErrInvalidDateFormat) || errors.Is(err, services.ErrMixedCurrencies) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Перевыставление затрат
+// @Schemes
+// @Description Относит расходы месяца на центры затрат по долям подписок. Подписки без распределения попадают в строку с cost_center_id 0. С format=csv отчет отдается файлом для бухгалтерии
+// @Tags Reports
+// @Accept json
+// @Produce json,text/csv
+// @Param filters query models.ChargebackFilter true "Filters"
+// @Success 200 {object} models.ChargebackReport
+// @Router /reports/chargeback [get]
+func (handler *ReportHandler) Chargeback(c *gin.Context) {
+	var filter models.ChargebackFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := handler.service.Chargeback(c.Request.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDateFormat) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.Format == nil || *filter.Format != "csv" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	rows := make([][]string, 0, len(report.Lines))
+	for _, line := range report.Lines {
+		rows = append(rows, []string{report.Month.Format("2006-01"), line.Code, line.Name, line.Currency, formatMinor(line.Amount),
+			formatMinor(line.Taxes.Net), formatMinor(line.Taxes.Tax), formatMinor(line.Taxes.Gross), strconv.Itoa(line.Subscriptions)})
+	}
+	writeCSV(c, "chargeback-"+report.Month.Format("2006-01")+".csv",
+		[]string{"month", "cost_center", "name", "currency", "amount", "net", "tax", "gross", "subscriptions"}, rows)
+}
+
+// @Summary Отток подписок
+// @Schemes
+// @Description Считает отмены, действующие с месяцев периода: по сервисам и месяцам, по кодам причин (unspecified - без причины) и средний срок жизни отмененных подписок в месяцах
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param filters query models.ChurnFilter true "Filters"
+// @Success 200 {object} models.ChurnReport
+// @Router /reports/churn [get]
+func (handler *ReportHandler) Churn(c *gin.Context) {
+	var filter models.ChurnFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report, err := handler.service.Churn(c.Request.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDate) || errors.Is(err, services.ErrInvalidDateFormat) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
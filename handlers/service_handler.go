@@ -59,6 +59,72 @@ func (handler *ServiceHandler) Create(c *gin.Context) {
 	c.JSON(http.StatusCreated, newService)
 }
 
+// @Summary Обновить сервис
+// @Schemes
+// @Description Меняет категорию сервиса, по которой считаются бюджеты
+// @Tags Service
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param service body models.UpdateService true "Service"
+// @Success 200 {object} models.Service
+// @Router /services/{id} [put]
+func (handler *ServiceHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var service models.UpdateService
+	if err := c.ShouldBindJSON(&service); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updated, err := handler.service.Update(c.Request.Context(), uint(id), &service)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// @Summary Статистика сервиса
+// @Schemes
+// @Description Возвращает число подписчиков с действующей подпиской, среднюю, минимальную и максимальную цену и выручку за месяц по валютам (годовые цены приведены к месяцу) и число подписчиков по месяцам за последние months месяцев
+// @Tags Service
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Param filters query models.ServiceStatsFilter false "Filters"
+// @Success 200 {object} models.ServiceStats
+// @Router /services/{id}/stats [get]
+func (handler *ServiceHandler) Stats(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var filter models.ServiceStatsFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	stats, err := handler.service.Stats(c.Request.Context(), uint(id), &filter)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Service not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
 // @Summary Удалить сервис
 // @Schemes
 // @Description Удаляет существующий сервис
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"subscriptions/models"
 	"subscriptions/services"
 
@@ -25,17 +26,70 @@ func NewSubscriptionHandler(service services.SubscriptionServiceInterface) *Subs
 // @Tags Subscription
 // @Accept json
 // @Produce json
+// @Param filters query models.ListFilter false "Filters"
 // @Success 200 {array} models.Subscription
 // @Router /subs [get]
 func (handler *SubscriptionHandler) GetAll(c *gin.Context) {
-	subscriptions, err := handler.service.GetAll(c.Request.Context())
+	var filter models.ListFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subscriptions, err := handler.service.GetAll(c.Request.Context(), &filter)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidAsOf) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, subscriptions)
 }
 
+// @Summary Выгрузить подписки в CSV
+// @Schemes
+// @Description Возвращает подписки по тем же фильтрам, что и список, файлом CSV. Метки перечислены через точку с запятой
+// @Tags Subscription
+// @Produce text/csv
+// @Param filters query models.ListFilter false "Filters"
+// @Success 200 {file} file
+// @Router /subs/export [get]
+func (handler *SubscriptionHandler) Export(c *gin.Context) {
+	var filter models.ListFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subscriptions, err := handler.service.GetAll(c.Request.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAsOf) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	rows := make([][]string, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		tags := make([]string, 0, len(sub.Tags))
+		for _, tag := range sub.Tags {
+			tags = append(tags, tag.Name)
+		}
+		endDate, notes := "", ""
+		if sub.EndDate != nil {
+			endDate = sub.EndDate.Format("2006-01-02")
+		}
+		if sub.Notes != nil {
+			notes = *sub.Notes
+		}
+		rows = append(rows, []string{strconv.FormatUint(uint64(sub.ID), 10), sub.Service.Name, sub.UserID, formatMinor(sub.Price), sub.Currency,
+			sub.BillingPeriod, sub.Status, sub.StartDate.Format("2006-01-02"), endDate, strings.Join(tags, ";"), notes})
+	}
+	writeCSV(c, "subscriptions.csv", []string{"id", "service", "user_id", "price", "currency", "billing_period", "status", "start_date", "end_date", "tags", "notes"}, rows)
+}
+
 // @Summary Добавить новую подписку
 // @Schemes
 // @Description Добавляет новую подписку
@@ -53,7 +107,7 @@ func (handler *SubscriptionHandler) Create(c *gin.Context) {
 	}
 	newSubscription, err := handler.service.Create(c.Request.Context(), &subscription)
 	if err != nil {
-		if err == services.ErrInvalidDate {
+		if errors.Is(err, services.ErrInvalidDate) || errors.Is(err, services.ErrInvalidDateFormat) || errors.Is(err, services.ErrInvalidMembers) || errors.Is(err, services.ErrInvalidAllocations) || errors.Is(err, services.ErrInvalidPrice) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -117,7 +171,7 @@ func (handler *SubscriptionHandler) Update(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
 			return
 		}
-		if err == services.ErrInvalidDate {
+		if errors.Is(err, services.ErrInvalidDate) || errors.Is(err, services.ErrInvalidDateFormat) || errors.Is(err, services.ErrInvalidMembers) || errors.Is(err, services.ErrInvalidAllocations) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -130,7 +184,7 @@ func (handler *SubscriptionHandler) Update(c *gin.Context) {
 
 // @Summary Удалить подписку
 // @Schemes
-// @Description Удаляет существующую подписку
+// @Description Помечает подписку удаленной (мягкое удаление), ее можно восстановить
 // @Tags Subscription
 // @Accept json
 // @Produce json
@@ -155,9 +209,190 @@ func (handler *SubscriptionHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted successfully"})
 }
 
+// @Summary Восстановить подписку
+// @Schemes
+// @Description Восстанавливает удаленную подписку
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {object} models.Subscription
+// @Router /subs/{id}/restore [post]
+func (handler *SubscriptionHandler) Restore(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subscription, err := handler.service.Restore(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deleted subscription not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// @Summary Получить историю подписки
+// @Schemes
+// @Description Возвращает все версии подписки с интервалами их действия
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {array} models.SubscriptionVersion
+// @Router /subs/{id}/history [get]
+func (handler *SubscriptionHandler) History(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	history, err := handler.service.History(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// @Summary Получить начисления подписки
+// @Schemes
+// @Description Возвращает начисления подписки из журнала: прошедшие и будущие списания с налогом и пояснениями к неполным периодам
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path int true "ID"
+// @Success 200 {array} models.Charge
+// @Router /subs/{id}/charges [get]
+func (handler *SubscriptionHandler) Charges(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	charges, err := handler.service.Charges(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, charges)
+}
+
+// @Summary Получить заканчивающиеся пробные периоды и акции
+// @Schemes
+// @Description Возвращает подписки, у которых в ближайшие within дней заканчивается пробный период или вводная цена
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param filters query models.OffersFilter true "Filters"
+// @Success 200 {array} models.OfferEnding
+// @Router /subs/offers-ending [get]
+func (handler *SubscriptionHandler) OffersEnding(c *gin.Context) {
+	var filter models.OffersFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	offers, err := handler.service.OffersEnding(c.Request.Context(), &filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, offers)
+}
+
+// @Summary Получить ближайшие сроки отмены перед продлением
+// @Schemes
+// @Description Возвращает подписки с автопродлением, последний день подачи отмены которых наступает в ближайшие within дней, по сроку
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param filters query models.DeadlinesFilter true "Filters"
+// @Success 200 {array} models.RenewalDeadline
+// @Router /subs/deadlines [get]
+func (handler *SubscriptionHandler) Deadlines(c *gin.Context) {
+	var filter models.DeadlinesFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deadlines, err := handler.service.RenewalDeadlines(c.Request.Context(), &filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, deadlines)
+}
+
+// @Summary Прогноз расходов
+// @Schemes
+// @Description Возвращает помесячный прогноз расходов на months месяцев начиная с текущего и накопленную сумму, если ничего не менять
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param filters query models.ForecastFilter false "Filters"
+// @Success 200 {object} models.Forecast
+// @Router /subs/forecast [get]
+func (handler *SubscriptionHandler) Forecast(c *gin.Context) {
+	var filter models.ForecastFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	forecast, err := handler.service.Forecast(c.Request.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrMixedCurrencies) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
+
+// @Summary Симуляция изменений
+// @Schemes
+// @Description Сравнивает прогноз расходов до и после гипотетических изменений (отмена, смена тарифа, новая подписка). В базу ничего не записывается
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param request body models.SimulationRequest true "Changes"
+// @Success 200 {object} models.SimulationResult
+// @Router /subs/simulate [post]
+func (handler *SubscriptionHandler) Simulate(c *gin.Context) {
+	var request models.SimulationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := handler.service.Simulate(c.Request.Context(), &request)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSimulation) || errors.Is(err, services.ErrMixedCurrencies) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
 // @Summary Получить сумму подписок по фильтрам
 // @Schemes
-// @Description Возвращает сумму подписок по фильтрам
+// @Description Возвращает сумму подписок по фильтрам: sum_minor в минимальных единицах и currency. net_minor, tax_minor и gross_minor - сумма без налога, налог и сумма с налогом. prorations - неполные периоды подписок: оплаченный период, дни, отрезки цены с дневной ставкой и пояснение расчета. Поле sum в целых единицах устарело
 // @Tags Subscription
 // @Accept json
 // @Produce json
@@ -175,12 +410,13 @@ func (handler *SubscriptionHandler) SumByFilters(c *gin.Context) {
 
 	sum, err := handler.service.SumByFilters(c.Request.Context(), &filters)
 	if err != nil {
-		if err == services.ErrInvalidDate {
+		if errors.Is(err, services.ErrInvalidDate) || errors.Is(err, services.ErrInvalidDateFormat) || errors.Is(err, services.ErrInvalidAsOf) || errors.Is(err, services.ErrShareWithoutUser) || errors.Is(err, services.ErrMixedCurrencies) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"sum": sum})
+	c.JSON(http.StatusOK, gin.H{"sum_minor": sum.Amount, "currency": sum.Currency, "net_minor": sum.Net, "tax_minor": sum.Tax, "gross_minor": sum.Gross,
+		"prorations": sum.Prorations, "sum": models.ToMajor(sum.Amount)}) //sum в целых единицах для старых клиентов
 }
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SuggestionHandler struct {
+	service services.SuggestionServiceInterface
+}
+
+func NewSuggestionHandler(service services.SuggestionServiceInterface) *SuggestionHandler {
+	return &SuggestionHandler{service: service}
+}
+
+// @Summary Получить предложения подписок
+// @Schemes
+// @Description Ищет в загруженных выписках пользователя ежемесячные и ежегодные списания, для которых нет подписки
+// @Tags Suggestions
+// @Accept json
+// @Produce json
+// @Param filters query models.SuggestionFilter true "Filters"
+// @Success 200 {array} models.Suggestion
+// @Router /suggestions [get]
+func (handler *SuggestionHandler) List(c *gin.Context) {
+	var filter models.SuggestionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	suggestions, err := handler.service.List(c.Request.Context(), &filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// @Summary Принять предложение
+// @Schemes
+// @Description Создает подписку (и сервис, если его нет) по регулярному платежу из выписок
+// @Tags Suggestions
+// @Accept json
+// @Produce json
+// @Param suggestion body models.AcceptSuggestion true "Suggestion"
+// @Success 201 {object} models.Subscription
+// @Router /suggestions/accept [post]
+func (handler *SuggestionHandler) Accept(c *gin.Context) {
+	var accept models.AcceptSuggestion
+	if err := c.ShouldBindJSON(&accept); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := handler.service.Accept(c.Request.Context(), &accept)
+	if err != nil {
+		if errors.Is(err, services.ErrSuggestionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidPrice) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"subscriptions/models"
+	"subscriptions/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TeamHandler struct {
+	service services.TeamServiceInterface
+}
+
+func NewTeamHandler(service services.TeamServiceInterface) *TeamHandler {
+	return &TeamHandler{service: service}
+}
+
+// @Summary Создать команду
+// @Schemes
+// @Description Создает команду пользователей для общего бюджета
+// @Tags Team
+// @Accept json
+// @Produce json
+// @Param team body models.CreateTeam true "Team"
+// @Success 201 {object} models.Team
+// @Router /teams [post]
+func (handler *TeamHandler) Create(c *gin.Context) {
+	var team models.CreateTeam
+	if err := c.ShouldBindJSON(&team); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	newTeam, err := handler.service.Create(c.Request.Context(), &team)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, newTeam)
+}
+
+// @Summary Получить список команд
+// @Schemes
+// @Description Возвращает все команды с участниками
+// @Tags Team
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Team
+// @Router /teams [get]
+func (handler *TeamHandler) GetAll(c *gin.Context) {
+	teams, err := handler.service.GetAll(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, teams)
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"subscriptions/services"
+
+	"go.uber.org/zap"
+)
+
+const defaultLedgerInterval = 24 * time.Hour
+
+func LedgerIntervalFromEnv(logger *zap.SugaredLogger) time.Duration { //как часто пересобирать журнал начислений
+	interval := defaultLedgerInterval
+	if value := os.Getenv("LEDGER_REBUILD_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			logger.Warnf("Некорректное значение LEDGER_REBUILD_INTERVAL: %s", value)
+		} else {
+			interval = parsed
+		}
+	}
+	return interval
+}
+
+// StartLedgerRebuild пересобирает журнал начислений при запуске и затем периодически, чтобы сдвигать горизонт будущих начислений
+func StartLedgerRebuild(ctx context.Context, service services.SubscriptionServiceInterface, interval time.Duration, logger *zap.SugaredLogger) {
+	go RunPeriodic(ctx, "rebuild charges ledger", interval, logger, func(ctx context.Context) error {
+		_, err := service.RebuildCharges(ctx)
+		return err
+	})
+}
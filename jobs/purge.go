@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"subscriptions/services"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPurgeRetentionDays = 30
+	defaultPurgeInterval      = 24 * time.Hour
+)
+
+type PurgeConfig struct {
+	Enabled   bool
+	Retention time.Duration //сколько хранить удаленные записи
+	Interval  time.Duration //как часто запускать очистку
+}
+
+func PurgeConfigFromEnv(logger *zap.SugaredLogger) PurgeConfig { //настройки очистки из переменных окружения
+	cfg := PurgeConfig{
+		Enabled:   true,
+		Retention: defaultPurgeRetentionDays * 24 * time.Hour,
+		Interval:  defaultPurgeInterval,
+	}
+
+	if value := os.Getenv("PURGE_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			logger.Warnf("Некорректное значение PURGE_ENABLED: %v", err)
+		} else {
+			cfg.Enabled = enabled
+		}
+	}
+
+	if value := os.Getenv("PURGE_RETENTION_DAYS"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			logger.Warnf("Некорректное значение PURGE_RETENTION_DAYS: %s", value)
+		} else {
+			cfg.Retention = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	if value := os.Getenv("PURGE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			logger.Warnf("Некорректное значение PURGE_INTERVAL: %s", value)
+		} else {
+			cfg.Interval = interval
+		}
+	}
+
+	return cfg
+}
+
+// StartPurge запускает фоновую очистку мягко удаленных подписок
+func StartPurge(ctx context.Context, service services.SubscriptionServiceInterface, cfg PurgeConfig, logger *zap.SugaredLogger) {
+	if !cfg.Enabled {
+		logger.Info("Очистка удаленных подписок отключена")
+		return
+	}
+
+	go RunPeriodic(ctx, "purge deleted subscriptions", cfg.Interval, logger, func(ctx context.Context) error {
+		_, err := service.PurgeDeleted(ctx, cfg.Retention)
+		return err
+	})
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"subscriptions/services"
+
+	"go.uber.org/zap"
+)
+
+const defaultRenewalInterval = time.Hour
+
+func RenewalIntervalFromEnv(logger *zap.SugaredLogger) time.Duration { //как часто проверять истекшие сроки подписок с автопродлением
+	interval := defaultRenewalInterval
+	if value := os.Getenv("RENEWAL_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			logger.Warnf("Некорректное значение RENEWAL_INTERVAL: %s", value)
+		} else {
+			interval = parsed
+		}
+	}
+	return interval
+}
+
+// StartRenewals продлевает подписки с автопродлением при запуске и затем периодически. Повторный или параллельный
+// запуск безопасен: подписка, которую уже продлили, не продлевается второй раз
+func StartRenewals(ctx context.Context, service services.SubscriptionServiceInterface, interval time.Duration, logger *zap.SugaredLogger) {
+	go RunPeriodic(ctx, "renew subscriptions", interval, logger, func(ctx context.Context) error {
+		_, err := service.RenewDue(ctx)
+		return err
+	})
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RunPeriodic запускает задачу сразу и затем с заданным интервалом, пока не отменен контекст
+func RunPeriodic(ctx context.Context, name string, interval time.Duration, logger *zap.SugaredLogger, task func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := task(ctx); err != nil {
+			logger.Errorf("Job %s failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
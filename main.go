@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"subscriptions/database"
 	_ "subscriptions/docs"
+	"subscriptions/events"
 	"subscriptions/handlers"
+	"subscriptions/jobs"
 	"subscriptions/repository"
 	"subscriptions/routes"
 	"subscriptions/services"
+	"subscriptions/storage"
 
 	"github.com/joho/godotenv"
 	swaggerFiles "github.com/swaggo/files"
@@ -39,14 +43,67 @@ func main() {
 
 	servicerepo := repository.NewServiceRepo(db) //репозитории
 	subscriptionrepo := repository.NewSubscriptionRepo(db)
+	auditrepo := repository.NewAuditRepo(db)
+	teamrepo := repository.NewTeamRepo(db)
+	budgetrepo := repository.NewBudgetRepo(db)
+	statementrepo := repository.NewStatementRepo(db)
+	attachmentrepo := repository.NewAttachmentRepo(db)
+	costcenterrepo := repository.NewCostCenterRepo(db)
 
-	serviceservice := services.NewServiceService(servicerepo, sugar) //сервисы
-	subscriptionservice := services.NewSubscriptionService(subscriptionrepo, servicerepo, sugar)
+	blobstore, err := storage.FromEnv() //хранилище вложений
+	if err != nil {
+		sugar.Fatalf("Ошибка настройки хранилища вложений: %v", err)
+	}
+
+	publisher := events.NewLogPublisher(sugar) //события
+
+	auditservice := services.NewAuditService(auditrepo, sugar) //сервисы
+	serviceservice := services.NewServiceService(servicerepo, subscriptionrepo, auditservice, sugar)
+	teamservice := services.NewTeamService(teamrepo, sugar)
+	budgetservice := services.NewBudgetService(budgetrepo, teamrepo, subscriptionrepo, publisher, sugar)
+	subscriptionservice := services.NewSubscriptionService(subscriptionrepo, servicerepo, costcenterrepo, blobstore, auditservice, budgetservice, publisher, services.ApprovalConfigFromEnv(sugar), sugar)
+	reconcileservice := services.NewReconcileService(statementrepo, subscriptionrepo, servicerepo, sugar)
+	attachmentservice := services.NewAttachmentService(attachmentrepo, subscriptionrepo, blobstore, auditservice, sugar)
+	suggestionservice := services.NewSuggestionService(statementrepo, subscriptionrepo, servicerepo, subscriptionservice, sugar)
+	reportservice := services.NewReportService(subscriptionrepo, costcenterrepo, sugar)
+	costcenterservice := services.NewCostCenterService(costcenterrepo, sugar)
+
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-charges" { //команда: пересобрать журнал начислений и выйти
+		count, err := subscriptionservice.RebuildCharges(context.Background())
+		if err != nil {
+			sugar.Fatalf("Ошибка пересборки журнала начислений: %v", err)
+		}
+		sugar.Infof("Журнал начислений пересобран, начислений: %d", count)
+		return
+	}
+
+	jobs.StartPurge(context.Background(), subscriptionservice, jobs.PurgeConfigFromEnv(sugar), sugar) //фоновые задачи
+	jobs.StartLedgerRebuild(context.Background(), subscriptionservice, jobs.LedgerIntervalFromEnv(sugar), sugar)
+	jobs.StartRenewals(context.Background(), subscriptionservice, jobs.RenewalIntervalFromEnv(sugar), sugar)
 
 	servicehandler := handlers.NewServiceHandler(serviceservice) //хендлеры
 	subscriptionhandler := handlers.NewSubscriptionHandler(subscriptionservice)
+	audithandler := handlers.NewAuditHandler(auditservice)
+	budgethandler := handlers.NewBudgetHandler(budgetservice)
+	teamhandler := handlers.NewTeamHandler(teamservice)
+	reconcilehandler := handlers.NewReconcileHandler(reconcileservice)
+	suggestionhandler := handlers.NewSuggestionHandler(suggestionservice)
+	reporthandler := handlers.NewReportHandler(reportservice)
+	costcenterhandler := handlers.NewCostCenterHandler(costcenterservice)
+	attachmenthandler := handlers.NewAttachmentHandler(attachmentservice)
 
-	router := routes.SetupRouter(servicehandler, subscriptionhandler)
+	router := routes.SetupRouter(routes.Handlers{
+		Service:      servicehandler,
+		Subscription: subscriptionhandler,
+		Audit:        audithandler,
+		Budget:       budgethandler,
+		Team:         teamhandler,
+		Reconcile:    reconcilehandler,
+		Suggestion:   suggestionhandler,
+		Report:       reporthandler,
+		CostCenter:   costcenterhandler,
+		Attachment:   attachmenthandler,
+	})
 	router.GET("/swagger/*any", swagger.WrapHandler(swaggerFiles.Handler)) //swagger
 	err = router.Run(":" + os.Getenv("APP_PORT"))
 	if err != nil {
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// файл, приложенный к подписке: чек или счет
+type Attachment struct {
+	ID             uint      `json:"id"`
+	SubscriptionID uint      `gorm:"not null; index" json:"subscription_id"`
+	FileName       string    `gorm:"not null" json:"file_name"`
+	ContentType    string    `gorm:"not null" json:"content_type"` //определяется по содержимому файла
+	Size           int64     `gorm:"not null" json:"size"`         //в байтах
+	StorageKey     string    `gorm:"not null; unique" json:"-"`    //ключ файла в хранилище
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	AuditEntitySubscription = "subscription"
+	AuditEntityService      = "service"
+	AuditEntityAttachment   = "attachment"
+
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+	AuditActionRenew   = "renew" //автопродление фоновой задачей
+)
+
+// запись журнала изменений
+type AuditEntry struct {
+	ID        uint      `json:"id"`
+	Actor     string    `gorm:"not null; index" json:"actor"`
+	RequestID string    `gorm:"index" json:"request_id"`
+	Entity    string    `gorm:"not null; index:idx_audit_entity" json:"entity"`
+	EntityID  uint      `gorm:"not null; index:idx_audit_entity" json:"entity_id"`
+	Action    string    `gorm:"not null" json:"action"`
+	Before    JSON      `gorm:"type:jsonb" json:"before" swaggertype:"object"`
+	After     JSON      `gorm:"type:jsonb" json:"after" swaggertype:"object"`
+	Diff      JSON      `gorm:"type:jsonb" json:"diff" swaggertype:"object"` //только измененные поля: {"поле": {"from": ..., "to": ...}}
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// модель для фильтрации журнала
+type AuditFilter struct {
+	Entity   *string `form:"entity"`
+	EntityID *uint   `form:"id"`
+	Actor    *string `form:"actor"`
+	Action   *string `form:"action"`
+	From     *string `form:"from"` //YYYY-MM-DD
+	To       *string `form:"to"`   //YYYY-MM-DD
+	Page     int     `form:"page" binding:"omitempty,gte=1"`
+	PageSize int     `form:"page_size" binding:"omitempty,gte=1,lte=200"`
+}
+
+// страница журнала
+type AuditPage struct {
+	Items    []AuditEntry `json:"items"`
+	Total    int64        `json:"total"`
+	Page     int          `json:"page"`
+	PageSize int          `json:"page_size"`
+}
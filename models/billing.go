@@ -0,0 +1,58 @@
+package models
+
+import "subscriptions/billing"
+
+// Billing переводит подписку в условия для движка расчета: налог подписки важнее налога сервиса,
+// история цены строится из версий, переходы и участники копируются
+func (s *Subscription) Billing() *billing.Subscription {
+	sub := &billing.Subscription{
+		ID:                s.ID,
+		UserID:            s.UserID,
+		Price:             s.Price,
+		StartDate:         s.StartDate,
+		EndDate:           s.EndDate,
+		Status:            s.Status,
+		BillingPeriod:     s.BillingPeriod,
+		AnchorDay:         int(s.AnchorDay),
+		ProrationMode:     s.ProrationMode,
+		TrialEndDate:      s.TrialEndDate,
+		PromoPrice:        s.PromoPrice,
+		PromoEndDate:      s.PromoEndDate,
+		TaxInclusive:      s.Service.TaxInclusive,
+		AutoRenew:         s.AutoRenew,
+		MinimumTermMonths: int(s.MinimumTermMonths),
+		RenewalTermMonths: int(s.RenewalTermMonths),
+		NoticeDays:        int(s.NoticeDays),
+	}
+	if s.Service.TaxRate != nil {
+		sub.TaxRate = *s.Service.TaxRate
+	}
+	if s.TaxRate != nil {
+		sub.TaxRate = *s.TaxRate
+	}
+	if s.TaxInclusive != nil {
+		sub.TaxInclusive = *s.TaxInclusive
+	}
+
+	for _, transition := range s.Transitions {
+		sub.Transitions = append(sub.Transitions, billing.Transition{To: transition.To, Date: transition.Date})
+	}
+	for _, member := range s.Members {
+		sub.Members = append(sub.Members, billing.Member{UserID: member.UserID, SharePercent: member.SharePercent, ShareAmount: member.ShareAmount})
+	}
+	versions := make([]billing.Version, 0, len(s.Versions))
+	for _, version := range s.Versions {
+		versions = append(versions, billing.Version{Number: version.Version, ValidFrom: version.ValidFrom, Price: version.Price})
+	}
+	sub.History = billing.PriceHistory(versions)
+	return sub
+}
+
+// BillingAll переводит подписки в условия для движка расчета в том же порядке
+func BillingAll(subs []Subscription) []billing.Subscription {
+	res := make([]billing.Subscription, len(subs))
+	for i := range subs {
+		res[i] = *subs[i].Billing()
+	}
+	return res
+}
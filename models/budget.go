@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// команда пользователей с общим бюджетом
+type Team struct {
+	ID        uint         `json:"id"`
+	Name      string       `gorm:"not null; unique" json:"name"`
+	Members   []TeamMember `gorm:"foreignKey:TeamID; constraint:OnDelete:CASCADE" json:"members"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type TeamMember struct {
+	ID     uint   `json:"-"`
+	TeamID uint   `gorm:"not null; uniqueIndex:idx_team_member" json:"-"`
+	UserID string `gorm:"type:uuid; not null; uniqueIndex:idx_team_member; index" json:"user_id"`
+}
+
+// месячный лимит расходов пользователя или команды, возможно только по категории сервисов
+type Budget struct {
+	ID        uint      `json:"id"`
+	UserID    *string   `gorm:"type:uuid; index" json:"user_id,omitempty"`
+	TeamID    *uint     `gorm:"index" json:"team_id,omitempty"`
+	Team      *Team     `gorm:"foreignKey:TeamID" json:"team,omitempty"`
+	Category  *string   `json:"category,omitempty"`                                         //nil - все сервисы
+	Limit     int64     `gorm:"column:limit_minor; not null; default:0" json:"limit_minor"` //в минимальных единицах валюты
+	Currency  string    `gorm:"size:3; not null; default:RUB" json:"currency"`              //учитываются только подписки в этой валюте
+	CostBasis string    `gorm:"not null; default:payer" json:"cost_basis"`                  //payer или share, как в сумме подписок
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	LegacyLimit uint `gorm:"column:limit; not null; default:0" json:"limit"` //устарело: лимит в целых единицах
+}
+
+// BeforeSave заполняет устаревшую колонку лимита в целых единицах
+func (b *Budget) BeforeSave(tx *gorm.DB) error {
+	b.LegacyLimit = ToMajor(b.Limit)
+	if b.Currency == "" {
+		b.Currency = DefaultCurrency
+	}
+	return nil
+}
+
+// модель для создания команды
+type CreateTeam struct {
+	Name    string   `json:"name" binding:"required"`
+	UserIDs []string `json:"user_ids" binding:"required,min=1,dive,uuid"`
+}
+
+// модель для создания бюджета
+type CreateBudget struct {
+	UserID     *string `json:"user_id,omitempty" binding:"required_without=TeamID,excluded_with=TeamID,omitempty,uuid"`
+	TeamID     *uint   `json:"team_id,omitempty" binding:"required_without=UserID"`
+	Category   *string `json:"category,omitempty"`
+	LimitMinor *int64  `json:"limit_minor" binding:"required_without=Limit,omitempty,gte=0"`
+	Limit      *uint   `json:"limit,omitempty"` //устарело: лимит в целых единицах, если не задан limit_minor
+	Currency   *string `json:"currency,omitempty" binding:"omitempty,len=3,uppercase"`
+	CostBasis  *string `json:"cost_basis,omitempty" binding:"omitempty,oneof=payer share"`
+}
+
+// модель для фильтрации бюджетов
+type BudgetFilter struct {
+	UserID *string `form:"user_id"`
+	TeamID *uint   `form:"team_id"`
+}
+
+// состояние бюджета в текущем месяце
+type BudgetReport struct {
+	Budget     Budget    `json:"budget"`
+	Month      time.Time `json:"month"`
+	Current    int64     `json:"current_minor"`   //начисления месяца, которые уже прошли
+	Projected  int64     `json:"projected_minor"` //все начисления месяца
+	Remaining  int64     `json:"remaining_minor"` //лимит минус прогноз, может быть отрицательным
+	Taxes      TaxTotals `json:"taxes"`           //прогноз без налога, налог и с налогом
+	OverBudget bool      `json:"over_budget"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// начисление в журнале: одно списание подписки, рассчитанное по ее условиям на момент последнего изменения
+type Charge struct {
+	ID             uint       `json:"id"`
+	SubscriptionID uint       `gorm:"not null; uniqueIndex:idx_charge_month" json:"subscription_id"`
+	Month          time.Time  `gorm:"not null; uniqueIndex:idx_charge_month; index" json:"month"`
+	Date           time.Time  `gorm:"not null" json:"date"`                  //день списания
+	Amount         int64      `gorm:"not null" json:"amount_minor"`          //полная стоимость списания в минимальных единицах
+	Currency       string     `gorm:"size:3; not null" json:"currency"`      //валюта подписки
+	Explanation    string     `json:"explanation,omitempty"`                 //как посчитан неполный период
+	Proration      *Proration `gorm:"type:jsonb" json:"proration,omitempty"` //подробный расчет неполного периода для ответа суммы
+	CreatedAt      time.Time  `json:"created_at"`
+
+	TaxTotals `gorm:"embedded"` //разбивка полной стоимости по налогу
+}
+
+// Proration - как посчитано начисление за неполный период: за какие дни и по какой дневной ставке
+type Proration struct {
+	SubscriptionID uint            `json:"subscription_id"`
+	Month          time.Time       `json:"month"`
+	Mode           string          `json:"mode"` //daily или by-anchor-day
+	PeriodStart    time.Time       `json:"period_start"`
+	PeriodEnd      time.Time       `json:"period_end"` //последний оплаченный день
+	Days           int             `json:"days"`       //оплаченных дней
+	CycleDays      int             `json:"cycle_days"` //на сколько дней делится цена
+	Parts          []ProrationPart `json:"parts"`      //отрезки периода с разной ценой
+	Amount         int64           `json:"amount_minor"`
+	Explanation    string          `json:"explanation"`
+}
+
+// ProrationPart - отрезок неполного периода с одной ценой
+type ProrationPart struct {
+	Days      int     `json:"days"`
+	Price     int64   `json:"price_minor"`
+	DailyRate float64 `json:"daily_rate_minor"` //цена, деленная на cycle_days, до сотых минимальной единицы
+}
+
+// Value хранит расчет неполного периода в колонке jsonb, полный период - NULL
+func (p *Proration) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (p *Proration) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return errors.New("unsupported type for proration column")
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// центр затрат компании, на который относятся расходы на подписки
+type CostCenter struct {
+	ID        uint      `json:"id"`
+	Code      string    `gorm:"not null; unique" json:"code"` //код для бухгалтерии, например "IT-01"
+	Name      string    `gorm:"not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// доля подписки, отнесенная на центр затрат. Доли одной подписки в сумме дают 100%
+type CostAllocation struct {
+	ID             uint        `json:"-"`
+	SubscriptionID uint        `gorm:"not null; index" json:"-"`
+	CostCenterID   uint        `gorm:"not null; index" json:"cost_center_id"`
+	CostCenter     *CostCenter `gorm:"foreignKey:CostCenterID" json:"cost_center,omitempty"`
+	Percent        float64     `gorm:"not null" json:"percent"`
+}
+
+// модель для создания центра затрат
+type CreateCostCenter struct {
+	Code string `json:"code" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+// модель распределения в запросах создания и обновления подписки
+type AllocationInput struct {
+	CostCenterID uint    `json:"cost_center_id" binding:"required"`
+	Percent      float64 `json:"percent" binding:"required,gt=0,lte=100"`
+}
+
+// модель для отчета о перевыставлении затрат
+type ChargebackFilter struct {
+	Month  string  `form:"month" binding:"required"`                  //MM-YYYY
+	Format *string `form:"format" binding:"omitempty,oneof=json csv"` //по умолчанию json
+	UserID *string `form:"user_id" binding:"omitempty,uuid"`          //только подписки пользователя
+}
+
+// расходы центра затрат за месяц в одной валюте
+type ChargebackLine struct {
+	CostCenterID  uint      `json:"cost_center_id"` //0 - подписки без распределения
+	Code          string    `json:"code"`
+	Name          string    `json:"name"`
+	Currency      string    `json:"currency"`
+	Amount        int64     `json:"amount_minor"`
+	Taxes         TaxTotals `json:"taxes"`
+	Subscriptions int       `json:"subscriptions"`
+}
+
+// расходы за месяц по центрам затрат
+type ChargebackReport struct {
+	Month time.Time        `json:"month"`
+	Lines []ChargebackLine `json:"lines"`
+}
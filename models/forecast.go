@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// модель для фильтрации прогноза
+type ForecastFilter struct {
+	UserID    *string `form:"user_id" binding:"omitempty,uuid"`
+	Months    int     `form:"months" binding:"omitempty,gte=1,lte=120"` //по умолчанию 12
+	CostBasis *string `form:"cost_basis" binding:"omitempty,oneof=payer share"`
+	Currency  *string `form:"currency" binding:"omitempty,len=3,uppercase"` //обязательна, если у подписок разные валюты
+}
+
+// прогноз расходов на один месяц
+type ForecastMonth struct {
+	Month      time.Time `json:"month"`
+	Amount     int64     `json:"amount_minor"`         //в минимальных единицах валюты прогноза
+	Cumulative int64     `json:"cumulative_minor"`     //сумма с первого месяца прогноза по этот включительно
+	Taxes      TaxTotals `json:"taxes"`                //сумма месяца без налога, налог и с налогом
+	Prorations []string  `json:"prorations,omitempty"` //как посчитаны неполные периоды этого месяца
+}
+
+// прогноз расходов по месяцам, если ничего не менять
+type Forecast struct {
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Currency string          `json:"currency"`
+	Months   []ForecastMonth `json:"months"`
+	Total    int64           `json:"total_minor"`
+	Taxes    TaxTotals       `json:"taxes"`
+}
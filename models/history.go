@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+)
+
+// версия подписки: состояние записи в интервале [ValidFrom, ValidTo)
+type SubscriptionVersion struct {
+	ID             uint       `json:"-"`
+	SubscriptionID uint       `gorm:"not null; uniqueIndex:idx_subscription_version" json:"subscription_id"`
+	Version        int        `gorm:"not null; uniqueIndex:idx_subscription_version" json:"version"`
+	ServiceID      uint       `gorm:"not null" json:"service_id"`
+	Price          int64      `gorm:"column:price_minor; not null; default:0" json:"price_minor"`
+	Currency       string     `gorm:"size:3; not null; default:RUB" json:"currency"`
+	LegacyPrice    uint       `gorm:"column:price; not null; default:0" json:"price"` //устарело: цена в целых единицах
+	UserID         string     `gorm:"type:uuid; not null" json:"user_id"`
+	StartDate      time.Time  `gorm:"not null" json:"start_date"`
+	EndDate        *time.Time `json:"end_date"`
+	Status         string     `gorm:"not null; default:active" json:"status"`
+	BillingPeriod  string     `gorm:"not null; default:monthly" json:"billing_period"`
+	AnchorDay      uint8      `gorm:"not null; default:1" json:"anchor_day"`
+	ProrationMode  string     `gorm:"not null; default:none" json:"proration_mode"`
+	TrialEndDate   *time.Time `json:"trial_end_date,omitempty"`
+	PromoPrice     *int64     `gorm:"column:promo_price_minor" json:"promo_price_minor,omitempty"`
+	PromoEndDate   *time.Time `json:"promo_end_date,omitempty"`
+	TaxRate        *float64   `json:"tax_rate,omitempty"`
+	TaxInclusive   *bool      `json:"tax_inclusive,omitempty"`
+	Deleted        bool       `gorm:"not null; default:false" json:"deleted"` //версия, созданная удалением
+	ValidFrom      time.Time  `gorm:"not null; index" json:"valid_from"`
+	ValidTo        *time.Time `gorm:"index" json:"valid_to"` //nil - текущая версия
+}
+
+func NewSubscriptionVersion(sub *Subscription, version int, deleted bool, at time.Time) *SubscriptionVersion { //снимок подписки
+	return &SubscriptionVersion{
+		SubscriptionID: sub.ID,
+		Version:        version,
+		ServiceID:      sub.ServiceID,
+		Price:          sub.Price,
+		Currency:       sub.Currency,
+		UserID:         sub.UserID,
+		StartDate:      sub.StartDate,
+		EndDate:        sub.EndDate,
+		Status:         sub.Status,
+		BillingPeriod:  sub.BillingPeriod,
+		AnchorDay:      sub.AnchorDay,
+		ProrationMode:  sub.ProrationMode,
+		TrialEndDate:   sub.TrialEndDate,
+		PromoPrice:     sub.PromoPrice,
+		PromoEndDate:   sub.PromoEndDate,
+		TaxRate:        sub.TaxRate,
+		TaxInclusive:   sub.TaxInclusive,
+		Deleted:        deleted,
+		ValidFrom:      at,
+	}
+}
+
+func (v *SubscriptionVersion) Subscription() Subscription { //подписка в том виде, в котором она была в этой версии
+	sub := Subscription{
+		ID:            v.SubscriptionID,
+		ServiceID:     v.ServiceID,
+		Price:         v.Price,
+		Currency:      v.Currency,
+		UserID:        v.UserID,
+		StartDate:     v.StartDate,
+		EndDate:       v.EndDate,
+		Status:        v.Status,
+		BillingPeriod: v.BillingPeriod,
+		AnchorDay:     v.AnchorDay,
+		ProrationMode: v.ProrationMode,
+		TrialEndDate:  v.TrialEndDate,
+		PromoPrice:    v.PromoPrice,
+		PromoEndDate:  v.PromoEndDate,
+		TaxRate:       v.TaxRate,
+		TaxInclusive:  v.TaxInclusive,
+		UpdatedAt:     v.ValidFrom,
+	}
+	if v.Deleted {
+		sub.DeletedAt.Time = v.ValidFrom
+		sub.DeletedAt.Valid = true
+	}
+	return sub
+}
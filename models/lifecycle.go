@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	StatusTrial     = "trial"
+	StatusActive    = "active"
+	StatusPaused    = "paused"
+	StatusCancelled = "cancelled"
+
+	StatusPendingApproval = "pending_approval" //ждет одобрения, если включен режим согласования
+	StatusRejected        = "rejected"         //не одобрена, не оплачивается
+)
+
+// коды причин отмены для отчета об оттоке
+const (
+	CancelReasonTooExpensive    = "too_expensive"
+	CancelReasonNotUsed         = "not_used"
+	CancelReasonSwitched        = "switched" //перешли на другой сервис
+	CancelReasonMissingFeatures = "missing_features"
+	CancelReasonDuplicate       = "duplicate" //такая подписка уже есть
+	CancelReasonOther           = "other"
+)
+
+// переход подписки между статусами
+type StatusTransition struct {
+	ID             uint      `json:"id"`
+	SubscriptionID uint      `gorm:"not null; index" json:"subscription_id"`
+	From           string    `json:"from"` //пусто для начального статуса
+	To             string    `gorm:"not null" json:"to"`
+	Date           time.Time `gorm:"not null" json:"date"` //с какого месяца действует новый статус
+	Actor          string    `json:"actor,omitempty"`      //кто перевел подписку, из заголовка X-User-ID
+	Comment        *string   `json:"comment,omitempty"`    //комментарий к переходу, например причина отказа
+	Reason         *string   `json:"reason,omitempty"`     //код причины отмены
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// модель для запроса перехода
+type TransitionRequest struct {
+	Date    *string `json:"date,omitempty"`    //MM-YYYY, по умолчанию текущий месяц
+	Comment *string `json:"comment,omitempty"` //для одобрения и отказа
+}
+
+// модель для запроса отмены, reason - код причины для отчета об оттоке
+type CancelRequest struct {
+	EffectiveMonth *string `json:"effective_month,omitempty"` //MM-YYYY - последний оплаченный месяц, по умолчанию текущий
+	Date           *string `json:"date,omitempty"`            //устарело: то же, что effective_month
+	Comment        *string `json:"comment,omitempty"`         //подробности в свободной форме
+	Reason         *string `json:"reason,omitempty" binding:"omitempty,oneof=too_expensive not_used switched missing_features duplicate other"`
+}
+
+// модель для списка подписок, ожидающих одобрения
+type ApprovalFilter struct {
+	UserID *string `form:"user_id" binding:"omitempty,uuid"`
+}
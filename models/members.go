@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	CostBasisPayer = "payer" //плательщик несет полную стоимость
+	CostBasisShare = "share" //каждый участник несет свою долю
+)
+
+// участник совместной подписки и его доля в оплате
+type SubscriptionMember struct {
+	ID             uint           `json:"id"`
+	SubscriptionID uint           `gorm:"not null; index" json:"subscription_id"`
+	UserID         string         `gorm:"type:uuid; not null; index" json:"user_id"`
+	SharePercent   *float64       `json:"share_percent,omitempty"`                                       //доля в процентах от цены месяца
+	ShareAmount    *int64         `gorm:"column:share_amount_minor" json:"share_amount_minor,omitempty"` //или фиксированная сумма в месяц в минимальных единицах
+	CreatedAt      time.Time      `json:"created_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"` //удаленные участники нужны для срезов as_of
+
+	LegacyShareAmount *uint `gorm:"column:share_amount" json:"share_amount,omitempty"` //устарело: сумма в целых единицах
+}
+
+// модель участника в запросах создания и обновления
+type MemberInput struct {
+	UserID           string   `json:"user_id" binding:"required,uuid"`
+	SharePercent     *float64 `json:"share_percent,omitempty" binding:"omitempty,gt=0,lte=100"`
+	ShareAmountMinor *int64   `json:"share_amount_minor,omitempty" binding:"omitempty,gte=0"`
+	ShareAmount      *uint    `json:"share_amount,omitempty"` //устарело: сумма в целых единицах
+}
@@ -2,51 +2,207 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Service struct {
-	ID        uint   `json:"id"`
-	Name      string `gorm:"not null; unique" json:"name"`
+	ID       uint    `json:"id"`
+	Name     string  `gorm:"not null; unique" json:"name"`
+	Category *string `gorm:"index" json:"category,omitempty"` //категория для бюджетов
+
+	TaxRate      *float64 `json:"tax_rate,omitempty"`                           //ставка налога в процентах для подписок сервиса, nil - без налога
+	TaxInclusive bool     `gorm:"not null; default:false" json:"tax_inclusive"` //цена уже включает налог
+
+	Aliases []ServiceAlias `gorm:"foreignKey:ServiceID; constraint:OnDelete:CASCADE" json:"aliases,omitempty"` //как сервис называется в банковских выписках
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// другое название сервиса, под которым он встречается в выписках, например "NFLX.COM"
+type ServiceAlias struct {
+	ID        uint   `json:"-"`
+	ServiceID uint   `gorm:"not null; index" json:"-"`
+	Alias     string `gorm:"not null; unique" json:"alias"`
+}
+
+const (
+	BillingMonthly = "monthly"
+	BillingAnnual  = "annual"
+)
+
+const (
+	ProrationNone        = "none"          //неполные периоды оплачиваются целиком
+	ProrationDaily       = "daily"         //по дням календарного месяца
+	ProrationByAnchorDay = "by-anchor-day" //по дням между днями списания
+)
+
 type Subscription struct {
 	ID        uint       `json:"id"`
 	ServiceID uint       `gorm:"not null; index" json:"service_id"`
 	Service   Service    `gorm:"foreignkey:ServiceID" json:"service"`
-	Price     uint       `gorm:"not null" json:"price"`
+	Price     int64      `gorm:"column:price_minor; not null; default:0" json:"price_minor"` //цена за период в минимальных единицах валюты
+	Currency  string     `gorm:"size:3; not null; default:RUB" json:"currency"`              //код валюты ISO 4217
 	UserID    string     `gorm:"type:uuid; not null; index" json:"user_id"`
 	StartDate time.Time  `gorm:"not null" json:"start_date"`
 	EndDate   *time.Time `json:"end_date"` //используем указатель, чтобы можно было использовать nil
+	Status    string     `gorm:"not null; default:active; index" json:"status"`
+
+	BillingPeriod string `gorm:"not null; default:monthly" json:"billing_period"` //monthly или annual: годовая оплата списывается раз в 12 месяцев
+	AnchorDay     uint8  `gorm:"not null; default:1" json:"anchor_day"`           //день месяца списания, в коротких месяцах - последний день
+	ProrationMode string `gorm:"not null; default:none" json:"proration_mode"`    //пересчет неполных периодов: none, daily или by-anchor-day
+
+	TrialEndDate *time.Time `gorm:"index" json:"trial_end_date,omitempty"`                       //первый день после пробного периода
+	PromoPrice   *int64     `gorm:"column:promo_price_minor" json:"promo_price_minor,omitempty"` //вводная цена в минимальных единицах
+	PromoEndDate *time.Time `gorm:"index" json:"promo_end_date,omitempty"`                       //с этого месяца действует обычная цена
+
+	TaxRate      *float64 `json:"tax_rate,omitempty"`      //ставка налога в процентах, nil - как у сервиса
+	TaxInclusive *bool    `json:"tax_inclusive,omitempty"` //цена включает налог, nil - как у сервиса
+
+	AutoRenew         bool `gorm:"not null; default:false" json:"auto_renew"`                //продлевается автоматически, пока не отменена
+	MinimumTermMonths uint `gorm:"not null; default:0" json:"minimum_term_months,omitempty"` //первый срок договора, 0 - как срок продления
+	RenewalTermMonths uint `gorm:"not null; default:0" json:"renewal_term_months,omitempty"` //на сколько месяцев продлевается, 0 - на период оплаты
+	NoticeDays        uint `gorm:"not null; default:0" json:"notice_days,omitempty"`         //за сколько дней до продления нужно подать отмену
+
+	NextRenewal          *time.Time `gorm:"-" json:"next_renewal,omitempty"`          //продление, от которого защищает срок отмены
+	CancellationDeadline *time.Time `gorm:"-" json:"cancellation_deadline,omitempty"` //последний день, когда отмена успевает до продления
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at" swaggertype:"string"` //мягкое удаление
+
+	Transitions []StatusTransition    `gorm:"foreignKey:SubscriptionID" json:"transitions,omitempty"`
+	Members     []SubscriptionMember  `gorm:"foreignKey:SubscriptionID" json:"members,omitempty"` //с кем делится стоимость
+	Versions    []SubscriptionVersion `gorm:"foreignKey:SubscriptionID; constraint:-" json:"-"`   //история цены для расчета сумм, переживает окончательное удаление
+	Charges     []Charge              `gorm:"foreignKey:SubscriptionID; constraint:-" json:"-"`   //начисления из журнала для сумм и отчетов
+	Tags        []Tag                 `gorm:"many2many:subscription_tags" json:"tags,omitempty"`
+	Allocations []CostAllocation      `gorm:"foreignKey:SubscriptionID" json:"allocations,omitempty"` //на какие центры затрат относится стоимость
+
+	Notes *string `gorm:"type:text" json:"notes,omitempty"` //произвольные заметки пользователя
+
+	Warnings []string `gorm:"-" json:"warnings,omitempty"` //предупреждения после создания или обновления, например о превышении бюджета
+
+	LegacyPrice      uint  `gorm:"column:price; not null; default:0" json:"price"`  //устарело: цена в целых единицах для старых клиентов
+	LegacyPromoPrice *uint `gorm:"column:promo_price" json:"promo_price,omitempty"` //устарело: вводная цена в целых единицах
 }
 
 // модель для создания подписки
 type CreateSubscription struct {
-	ServiceName string  `json:"service_name" binding:"required"`
-	Price       *uint   `json:"price" binding:"required,gte=0"` //указатель чтобы отличать 0 от nil
-	UserID      string  `json:"user_id" binding:"required,uuid"`
-	StartDate   string  `json:"start_date" binding:"required"`
-	EndDate     *string `json:"end_date,omitempty"`
+	ServiceName     string  `json:"service_name" binding:"required"`
+	PriceMinor      *int64  `json:"price_minor" binding:"required_without=Price,omitempty,gte=0"` //цена в минимальных единицах, указатель чтобы отличать 0 от nil
+	Price           *uint   `json:"price,omitempty"`                                              //устарело: цена в целых единицах, если не задан price_minor
+	Currency        *string `json:"currency,omitempty" binding:"omitempty,len=3,uppercase"`       //по умолчанию RUB
+	UserID          string  `json:"user_id" binding:"required,uuid"`
+	StartDate       string  `json:"start_date" binding:"required"`                           //YYYY-MM-DD или MM-YYYY (первое число месяца)
+	EndDate         *string `json:"end_date,omitempty"`                                      //YYYY-MM-DD или MM-YYYY (месяц целиком)
+	Status          *string `json:"status,omitempty" binding:"omitempty,oneof=trial active"` //начальный статус, по умолчанию active (trial, если задан пробный период)
+	TrialDays       *uint   `json:"trial_days,omitempty" binding:"omitempty,gte=1"`          //длина пробного периода в днях
+	PromoPriceMinor *int64  `json:"promo_price_minor,omitempty" binding:"omitempty,gte=0"`   //вводная цена в минимальных единицах
+	PromoPrice      *uint   `json:"promo_price,omitempty"`                                   //устарело: вводная цена в целых единицах
+	PromoMonths     *uint   `json:"promo_months,omitempty" binding:"omitempty,gte=1"`        //сколько оплачиваемых месяцев действует вводная цена, задается вместе с ценой
+
+	BillingPeriod *string `json:"billing_period,omitempty" binding:"omitempty,oneof=monthly annual"`           //по умолчанию monthly, price - цена за период
+	AnchorDay     *uint8  `json:"anchor_day,omitempty" binding:"omitempty,gte=1,lte=31"`                       //день списания, по умолчанию день даты начала
+	ProrationMode *string `json:"proration_mode,omitempty" binding:"omitempty,oneof=none daily by-anchor-day"` //по умолчанию none
+
+	TaxRate      *float64 `json:"tax_rate,omitempty" binding:"omitempty,gte=0,lte=100"` //по умолчанию ставка сервиса
+	TaxInclusive *bool    `json:"tax_inclusive,omitempty"`
+
+	Members []MemberInput `json:"members,omitempty" binding:"omitempty,dive"` //участники совместной подписки
+
+	Notes *string  `json:"notes,omitempty"`
+	Tags  []string `json:"tags,omitempty" binding:"omitempty,dive,required,max=50"` //метки, регистр не важен
+
+	Allocations []AllocationInput `json:"allocations,omitempty" binding:"omitempty,dive"` //доли центров затрат, в сумме 100%
+
+	AutoRenew         bool  `json:"auto_renew"`
+	MinimumTermMonths *uint `json:"minimum_term_months,omitempty" binding:"omitempty,lte=120"`
+	RenewalTermMonths *uint `json:"renewal_term_months,omitempty" binding:"omitempty,gte=1,lte=120"` //по умолчанию период оплаты
+	NoticeDays        *uint `json:"notice_days,omitempty" binding:"omitempty,lte=365"`
 }
 
 // модель для обновления подписки
 type UpdateSubscription struct {
-	Price   *uint   `json:"price,omitempty"`
-	EndDate *string `json:"end_date,omitempty"`
+	PriceMinor    *int64             `json:"price_minor,omitempty" binding:"omitempty,gte=0"`
+	Price         *uint              `json:"price,omitempty"` //устарело: цена в целых единицах
+	EndDate       *string            `json:"end_date,omitempty"`
+	ProrationMode *string            `json:"proration_mode,omitempty" binding:"omitempty,oneof=none daily by-anchor-day"`
+	TaxRate       *float64           `json:"tax_rate,omitempty" binding:"omitempty,gte=0,lte=100"`
+	TaxInclusive  *bool              `json:"tax_inclusive,omitempty"`
+	Members       *[]MemberInput     `json:"members,omitempty" binding:"omitempty,dive"`              //заменяет список участников, пустой список убирает всех
+	Notes         *string            `json:"notes,omitempty"`                                         //пустая строка удаляет заметки
+	Tags          *[]string          `json:"tags,omitempty" binding:"omitempty,dive,required,max=50"` //заменяет метки, пустой список убирает все
+	Allocations   *[]AllocationInput `json:"allocations,omitempty" binding:"omitempty,dive"`          //заменяет распределение по центрам затрат, пустой список убирает его
+
+	AutoRenew         *bool `json:"auto_renew,omitempty"`
+	MinimumTermMonths *uint `json:"minimum_term_months,omitempty" binding:"omitempty,lte=120"`
+	RenewalTermMonths *uint `json:"renewal_term_months,omitempty" binding:"omitempty,lte=120"` //0 - на период оплаты
+	NoticeDays        *uint `json:"notice_days,omitempty" binding:"omitempty,lte=365"`
+}
+
+// модель для фильтрации списка подписок
+type ListFilter struct {
+	UserID         *string `form:"user_id"` //подписки пользователя, в том числе те, где он участник
+	IncludeDeleted bool    `form:"include_deleted"`
+	AsOf           *string `form:"as_of"` //YYYY-MM-DD, состояние на конец указанного дня
+	Tag            *string `form:"tag"`   //только подписки с этой меткой
+	Status         *string `form:"status"`
+}
+
+// модель для поиска заканчивающихся пробных периодов и акций
+type OffersFilter struct {
+	Within int `form:"within" binding:"required,gte=1"` //за сколько дней
+}
+
+// подписка, у которой скоро закончится пробный период или вводная цена
+type OfferEnding struct {
+	Kind         string       `json:"kind"` //trial или promo
+	EndsAt       time.Time    `json:"ends_at"`
+	NextPrice    int64        `json:"next_price_minor"` //цена, которая начнет действовать, в минимальных единицах
+	Subscription Subscription `json:"subscription"`
+}
+
+// модель для поиска ближайших сроков отмены перед продлением
+type DeadlinesFilter struct {
+	Within int     `form:"within" binding:"required,gte=1"` //за сколько дней
+	UserID *string `form:"user_id" binding:"omitempty,uuid"`
+}
+
+// подписка, которую нужно успеть отменить до автоматического продления
+type RenewalDeadline struct {
+	Deadline     time.Time    `json:"deadline"`  //последний день подачи отмены
+	RenewsAt     time.Time    `json:"renews_at"` //когда подписка продлится
+	DaysLeft     int          `json:"days_left"` //сколько дней осталось до срока
+	Subscription Subscription `json:"subscription"`
 }
 
 // модель для фильтрации
 type SumFilter struct {
-	UserID      *string `form:"user_id"`
-	ServiceName *string `form:"service_name"`
-	StartDate   *string `form:"start_date"`
-	EndDate     *string `form:"end_date"`
+	UserID         *string `form:"user_id"`
+	ServiceName    *string `form:"service_name"`
+	StartDate      *string `form:"start_date"`
+	EndDate        *string `form:"end_date"`
+	AsOf           *string `form:"as_of"`                                            //YYYY-MM-DD, считать по данным на конец указанного дня
+	CostBasis      *string `form:"cost_basis" binding:"omitempty,oneof=payer share"` //payer - полная цена плательщику, share - доля каждого участника
+	Currency       *string `form:"currency" binding:"omitempty,len=3,uppercase"`     //обязательна, если у подписок разные валюты
+	Tag            *string `form:"tag"`                                              //только подписки с этой меткой
+	IncludePending bool    `form:"include_pending"`                                  //вместе с подписками, ожидающими одобрения
 }
 
 // модель для создания сервиса
 type CreateService struct {
-	Name string `json:"name" binding:"required"`
+	Name         string   `json:"name" binding:"required"`
+	Category     *string  `json:"category,omitempty"`
+	TaxRate      *float64 `json:"tax_rate,omitempty" binding:"omitempty,gte=0,lte=100"` //ставка налога в процентах
+	TaxInclusive bool     `json:"tax_inclusive"`                                        //цены подписок включают налог
+	Aliases      []string `json:"aliases,omitempty" binding:"omitempty,dive,required"`  //названия в банковских выписках
+}
+
+// модель для обновления сервиса
+type UpdateService struct {
+	Category     *string   `json:"category"`
+	TaxRate      *float64  `json:"tax_rate" binding:"omitempty,gte=0,lte=100"`
+	TaxInclusive bool      `json:"tax_inclusive"`
+	Aliases      *[]string `json:"aliases,omitempty" binding:"omitempty,dive,required"` //заменяет список названий, nil - без изменений
 }
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultCurrency = "RUB"
+	MinorUnits      = 100 //минимальных единиц в основной (копеек в рубле), у всех валют два знака после запятой
+)
+
+// Money - сумма в минимальных единицах валюты: 999 при RUB - это 9.99 рубля
+type Money struct {
+	Amount   int64  `json:"amount_minor"`
+	Currency string `json:"currency"`
+}
+
+// TaxTotals - суммы без налога, налог и суммы с налогом в минимальных единицах
+type TaxTotals struct {
+	Net   int64 `json:"net_minor"`
+	Tax   int64 `json:"tax_minor"`
+	Gross int64 `json:"gross_minor"`
+}
+
+func (t *TaxTotals) Add(other TaxTotals) {
+	t.Net += other.Net
+	t.Tax += other.Tax
+	t.Gross += other.Gross
+}
+
+// SumResult - сумма подписок в валюте и ее разбивка по налогу
+type SumResult struct {
+	Money
+	TaxTotals
+
+	Prorations []Proration `json:"prorations,omitempty"` //неполные периоды подписок в сумме, суммы - полная стоимость подписки
+}
+
+// ToMinor переводит сумму в целых единицах из устаревших полей в минимальные единицы
+func ToMinor(major uint) int64 {
+	return int64(major) * MinorUnits
+}
+
+// ToMajor переводит сумму в целые единицы для устаревших полей, копейки отбрасываются
+func ToMajor(minor int64) uint {
+	if minor < 0 {
+		return 0
+	}
+	return uint(minor / MinorUnits)
+}
+
+// MinorOrLegacy выбирает сумму из запроса: новое поле в минимальных единицах или устаревшее в целых
+func MinorOrLegacy(minor *int64, legacy *uint) *int64 {
+	if minor != nil {
+		return minor
+	}
+	if legacy != nil {
+		amount := ToMinor(*legacy)
+		return &amount
+	}
+	return nil
+}
+
+func legacyAmount(minor *int64) *uint {
+	if minor == nil {
+		return nil
+	}
+	amount := ToMajor(*minor)
+	return &amount
+}
+
+// BeforeSave заполняет устаревшие колонки в целых единицах, их еще читают старые клиенты
+func (s *Subscription) BeforeSave(tx *gorm.DB) error {
+	s.LegacyPrice = ToMajor(s.Price)
+	s.LegacyPromoPrice = legacyAmount(s.PromoPrice)
+	if s.Currency == "" {
+		s.Currency = DefaultCurrency
+	}
+	return nil
+}
+
+func (v *SubscriptionVersion) BeforeSave(tx *gorm.DB) error {
+	v.LegacyPrice = ToMajor(v.Price)
+	if v.Currency == "" {
+		v.Currency = DefaultCurrency
+	}
+	return nil
+}
+
+func (m *SubscriptionMember) BeforeSave(tx *gorm.DB) error {
+	m.LegacyShareAmount = legacyAmount(m.ShareAmount)
+	return nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+const (
+	GroupByTag      = "tag"
+	GroupByService  = "service"
+	GroupByCategory = "category"
+)
+
+// модель для отчета о расходах
+type SpendFilter struct {
+	UserID    *string `form:"user_id" binding:"omitempty,uuid"`
+	StartDate *string `form:"start_date"` //MM-YYYY, по умолчанию текущий месяц
+	EndDate   *string `form:"end_date"`   //MM-YYYY, по умолчанию месяц начала
+	GroupBy   string  `form:"group_by" binding:"required,oneof=tag service category"`
+	Tag       *string `form:"tag"`
+	Currency  *string `form:"currency" binding:"omitempty,len=3,uppercase"` //обязательна, если у подписок разные валюты
+}
+
+// расходы одной группы отчета
+type SpendGroup struct {
+	Key           string    `json:"key"` //метка, сервис или категория, пустая строка - без метки или категории
+	Amount        int64     `json:"amount_minor"`
+	Taxes         TaxTotals `json:"taxes"`
+	Subscriptions int       `json:"subscriptions"`
+}
+
+// расходы за период по группам. Подписка с несколькими метками входит в каждую из них,
+// поэтому при группировке по меткам сумма групп может быть больше итога
+type SpendReport struct {
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	GroupBy  string       `json:"group_by"`
+	Currency string       `json:"currency"`
+	Total    int64        `json:"total_minor"`
+	Taxes    TaxTotals    `json:"taxes"`
+	Groups   []SpendGroup `json:"groups"`
+}
+
+// модель для отчета об оттоке
+type ChurnFilter struct {
+	UserID    *string `form:"user_id" binding:"omitempty,uuid"`
+	StartDate *string `form:"start_date"` //MM-YYYY, по умолчанию текущий месяц
+	EndDate   *string `form:"end_date"`   //MM-YYYY, по умолчанию месяц начала
+}
+
+// отмены подписок одного сервиса за месяц
+type ChurnLine struct {
+	Month         time.Time `json:"month"` //месяц, с которого действует отмена
+	ServiceID     uint      `json:"service_id"`
+	ServiceName   string    `json:"service_name"`
+	Cancellations int       `json:"cancellations"`
+}
+
+// сколько отмен пришлось на причину, без указанной причины - unspecified
+type ChurnReason struct {
+	Reason        string `json:"reason"`
+	Cancellations int    `json:"cancellations"`
+}
+
+// отток за период: отмены по сервисам и месяцам, причины и средний срок жизни отмененных подписок
+type ChurnReport struct {
+	From                  time.Time     `json:"from"`
+	To                    time.Time     `json:"to"`
+	Cancellations         int           `json:"cancellations"`
+	AverageLifetimeMonths float64       `json:"average_lifetime_months"` //от даты начала до даты окончания
+	Lines                 []ChurnLine   `json:"lines"`
+	Reasons               []ChurnReason `json:"reasons"`
+}
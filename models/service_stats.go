@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// модель для статистики сервиса
+type ServiceStatsFilter struct {
+	Months *int `form:"months" binding:"omitempty,gte=1,lte=60"` //за сколько месяцев тренд подписчиков, по умолчанию 12
+}
+
+// цены действующих подписок сервиса в одной валюте, приведенные к месяцу: годовая цена делится на 12
+type PriceStats struct {
+	Currency       string `json:"currency"`
+	Subscriptions  int    `json:"subscriptions"`
+	Average        int64  `json:"avg_price_minor"`
+	Min            int64  `json:"min_price_minor"`
+	Max            int64  `json:"max_price_minor"`
+	MonthlyRevenue int64  `json:"monthly_revenue_minor"` //начисления текущего месяца из журнала с налогом
+}
+
+// число подписчиков сервиса в месяце
+type SubscriberPoint struct {
+	Month       time.Time `json:"month"`
+	Subscribers int       `json:"subscribers"`
+}
+
+// как используется сервис: подписчики, цены и выручка сейчас и подписчики по месяцам
+type ServiceStats struct {
+	ServiceID         uint              `json:"service_id"`
+	ServiceName       string            `json:"service_name"`
+	ActiveSubscribers int               `json:"active_subscribers"` //разные пользователи с действующей подпиской
+	Prices            []PriceStats      `json:"prices"`             //по валютам
+	Trend             []SubscriberPoint `json:"trend"`
+}
@@ -0,0 +1,34 @@
+package models
+
+const (
+	SimulationCancel = "cancel"
+	SimulationSwitch = "switch"
+	SimulationAdd    = "add"
+)
+
+// гипотетическое изменение подписок
+type SimulationChange struct {
+	Action         string  `json:"action" binding:"required,oneof=cancel switch add"`
+	SubscriptionID *uint   `json:"subscription_id,omitempty"`                                         //для cancel и switch
+	Month          *string `json:"month,omitempty"`                                                   //MM-YYYY, с какого месяца действует изменение (для cancel - первый неоплаченный месяц), по умолчанию первый месяц прогноза
+	PriceMinor     *int64  `json:"price_minor,omitempty" binding:"omitempty,gte=0"`                   //новая цена за период для switch, цена для add, в минимальных единицах
+	Price          *uint   `json:"price,omitempty"`                                                   //устарело: цена в целых единицах
+	BillingPeriod  *string `json:"billing_period,omitempty" binding:"omitempty,oneof=monthly annual"` //для switch и add
+	ServiceName    string  `json:"service_name,omitempty"`                                            //название новой подписки для add
+}
+
+// модель для симуляции изменений
+type SimulationRequest struct {
+	UserID    *string            `json:"user_id,omitempty" binding:"omitempty,uuid"`
+	Months    int                `json:"months,omitempty" binding:"omitempty,gte=1,lte=120"` //по умолчанию 12
+	CostBasis *string            `json:"cost_basis,omitempty" binding:"omitempty,oneof=payer share"`
+	Currency  *string            `json:"currency,omitempty" binding:"omitempty,len=3,uppercase"` //валюта прогноза и новых подписок
+	Changes   []SimulationChange `json:"changes" binding:"required,min=1,dive"`
+}
+
+// прогноз до и после изменений
+type SimulationResult struct {
+	Before  Forecast `json:"before"`
+	After   Forecast `json:"after"`
+	Savings int64    `json:"savings_minor"` //на сколько меньше потратим, отрицательное значение - перерасход
+}
@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+const (
+	StatementCSV = "csv"
+	StatementOFX = "ofx"
+)
+
+const (
+	CadenceMonthly = "monthly"
+	CadenceAnnual  = "annual"
+)
+
+// загруженная банковская выписка пользователя
+type StatementImport struct {
+	ID           uint              `json:"id"`
+	UserID       string            `gorm:"type:uuid; not null; index" json:"user_id"`
+	FileName     string            `json:"file_name"`
+	Format       string            `gorm:"not null" json:"format"` //csv или ofx
+	CreatedAt    time.Time         `json:"created_at"`
+	Transactions []BankTransaction `gorm:"foreignKey:ImportID; constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// операция из банковской выписки
+type BankTransaction struct {
+	ID             uint      `json:"id"`
+	ImportID       uint      `gorm:"not null; index" json:"import_id"`
+	UserID         string    `gorm:"type:uuid; not null; index; uniqueIndex:idx_bank_transaction_fingerprint" json:"user_id"`
+	ExternalID     string    `json:"external_id,omitempty"`                                 //FITID из OFX
+	Fingerprint    string    `gorm:"uniqueIndex:idx_bank_transaction_fingerprint" json:"-"` //по нему повторная загрузка операции пропускается: FITID или дата, сумма и описание
+	Date           time.Time `gorm:"not null" json:"date"`
+	Description    string    `gorm:"not null" json:"description"`
+	Merchant       string    `gorm:"not null; index" json:"merchant"`                   //описание без цифр и знаков, по нему ищутся повторяющиеся платежи
+	Amount         int64     `gorm:"column:amount_minor; not null" json:"amount_minor"` //как в выписке: списания отрицательные
+	Currency       string    `gorm:"size:3; not null; default:RUB" json:"currency"`     //код валюты ISO 4217
+	SubscriptionID *uint     `gorm:"index" json:"subscription_id,omitempty"`            //подписка, начисление которой оплачено этой операцией
+}
+
+// Debit - сумма списания в минимальных единицах, 0 для поступлений
+func (t *BankTransaction) Debit() int64 {
+	if t.Amount >= 0 {
+		return 0
+	}
+	return -t.Amount
+}
+
+// параметры сверки выписки, файл передается в поле file
+type ReconcileRequest struct {
+	UserID          string   `form:"user_id" binding:"required,uuid"`
+	Format          *string  `form:"format" binding:"omitempty,oneof=csv ofx"`          //по умолчанию по расширению и содержимому файла
+	Currency        *string  `form:"currency" binding:"omitempty,len=3,uppercase"`      //валюта операций CSV без колонки валюты, по умолчанию RUB
+	ToleranceDays   *int     `form:"tolerance_days" binding:"omitempty,gte=0,lte=15"`   //допустимое расхождение даты списания, по умолчанию 3 дня
+	AmountTolerance *float64 `form:"amount_tolerance" binding:"omitempty,gte=0,lte=50"` //допустимое расхождение суммы в процентах, по умолчанию 5
+}
+
+// результат сверки выписки с подписками
+type ReconcileReport struct {
+	ImportID     uint               `json:"import_id"`
+	From         time.Time          `json:"from"` //первая и последняя даты операций в выписке
+	To           time.Time          `json:"to"`
+	Transactions int                `json:"transactions"`
+	Matched      []ReconcileMatch   `json:"matched"` //начисления, за которые нашлось списание
+	Missing      []ExpectedCharge   `json:"missing"` //начисления за период выписки без списания
+	Unknown      []RecurringPayment `json:"unknown"` //повторяющиеся списания, которых нет среди подписок
+}
+
+// начисление подписки и оплатившая его операция
+type ReconcileMatch struct {
+	Expected    ExpectedCharge  `json:"expected"`
+	Transaction BankTransaction `json:"transaction"`
+}
+
+// начисление подписки, которое должно быть в выписке
+type ExpectedCharge struct {
+	SubscriptionID uint      `json:"subscription_id"`
+	ServiceName    string    `json:"service_name"`
+	Date           time.Time `json:"date"`
+	Amount         int64     `json:"amount_minor"` //с налогом, как списывается с карты
+	Currency       string    `json:"currency"`
+}
+
+// регулярные списания одного получателя, похожие на неучтенную подписку
+type RecurringPayment struct {
+	Merchant     string            `json:"merchant"`
+	Description  string            `json:"description"`  //описание последней операции, как в выписке
+	Cadence      string            `json:"cadence"`      //monthly или annual
+	Amount       int64             `json:"amount_minor"` //сумма последнего списания
+	Currency     string            `json:"currency"`
+	Transactions []BankTransaction `json:"transactions"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// регулярный платеж из загруженных выписок, для которого нет подписки
+type Suggestion struct {
+	RecurringPayment
+	ServiceName string `json:"service_name"` //название сервиса, с которым будет создана подписка
+}
+
+// модель для поиска предложений
+type SuggestionFilter struct {
+	UserID          string   `form:"user_id" binding:"required,uuid"`
+	AmountTolerance *float64 `form:"amount_tolerance" binding:"omitempty,gte=0,lte=50"` //допустимое расхождение сумм в процентах, по умолчанию 5
+}
+
+// модель для принятия предложения: создает подписку и сервис по регулярному платежу
+type AcceptSuggestion struct {
+	UserID          string   `json:"user_id" binding:"required,uuid"`
+	Merchant        string   `json:"merchant" binding:"required"`
+	Currency        string   `json:"currency" binding:"required,len=3,uppercase"`
+	ServiceName     *string  `json:"service_name,omitempty"`                                      //по умолчанию из описания платежа
+	PriceMinor      *int64   `json:"price_minor,omitempty" binding:"omitempty,gte=0"`             //по умолчанию сумма последнего списания
+	AmountTolerance *float64 `json:"amount_tolerance,omitempty" binding:"omitempty,gte=0,lte=50"` //как при поиске предложений
+}
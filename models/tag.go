@@ -0,0 +1,7 @@
+package models
+
+// метка подписки, например "работа" или "семья". Названия хранятся в нижнем регистре
+type Tag struct {
+	ID   uint   `json:"-"`
+	Name string `gorm:"not null; unique" json:"name"`
+}
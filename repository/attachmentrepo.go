@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"gorm.io/gorm"
+)
+
+type AttachmentRepoInterface interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	GetById(ctx context.Context, subscriptionID, id uint) (*models.Attachment, error)
+	List(ctx context.Context, subscriptionID uint) ([]models.Attachment, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type AttachmentRepo struct {
+	db *gorm.DB
+}
+
+func NewAttachmentRepo(db *gorm.DB) AttachmentRepoInterface { //создание репозитория для вложений
+	return &AttachmentRepo{db: db}
+}
+
+func (repo *AttachmentRepo) Create(ctx context.Context, attachment *models.Attachment) error {
+	return repo.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (repo *AttachmentRepo) GetById(ctx context.Context, subscriptionID, id uint) (*models.Attachment, error) { //вложение только своей подписки
+	var attachment models.Attachment
+	if err := repo.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).First(&attachment, id).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (repo *AttachmentRepo) List(ctx context.Context, subscriptionID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	if err := repo.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Order("created_at, id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (repo *AttachmentRepo) Delete(ctx context.Context, id uint) error {
+	res := repo.db.WithContext(ctx).Delete(&models.Attachment{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
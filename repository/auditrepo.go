@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepoInterface interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	Find(ctx context.Context, filter *models.AuditFilter, from, to *time.Time) ([]models.AuditEntry, int64, error)
+}
+
+type AuditRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditRepo(db *gorm.DB) AuditRepoInterface { //создание репозитория для журнала изменений
+	return &AuditRepo{db: db}
+}
+
+func (repo *AuditRepo) Create(ctx context.Context, entry *models.AuditEntry) error {
+	return repo.db.WithContext(ctx).Create(entry).Error
+}
+
+func (repo *AuditRepo) Find(ctx context.Context, filter *models.AuditFilter, from, to *time.Time) ([]models.AuditEntry, int64, error) {
+	query := repo.db.WithContext(ctx).Model(&models.AuditEntry{})
+
+	if filter.Entity != nil {
+		query = query.Where("entity = ?", *filter.Entity)
+	}
+
+	if filter.EntityID != nil {
+		query = query.Where("entity_id = ?", *filter.EntityID)
+	}
+
+	if filter.Actor != nil {
+		query = query.Where("actor = ?", *filter.Actor)
+	}
+
+	if filter.Action != nil {
+		query = query.Where("action = ?", *filter.Action)
+	}
+
+	if from != nil {
+		query = query.Where("created_at >= ?", from)
+	}
+
+	if to != nil {
+		query = query.Where("created_at < ?", to)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditEntry
+	err := query.Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
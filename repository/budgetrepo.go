@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"gorm.io/gorm"
+)
+
+type BudgetRepoInterface interface {
+	Create(ctx context.Context, budget *models.Budget) error
+	GetById(ctx context.Context, id uint) (*models.Budget, error)
+	GetAll(ctx context.Context, filter *models.BudgetFilter) ([]models.Budget, error)
+	FindForOwners(ctx context.Context, userIDs []string, teamIDs []uint) ([]models.Budget, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type BudgetRepo struct {
+	db *gorm.DB
+}
+
+func NewBudgetRepo(db *gorm.DB) BudgetRepoInterface { //создание репозитория для бюджетов
+	return &BudgetRepo{db: db}
+}
+
+func (repo *BudgetRepo) Create(ctx context.Context, budget *models.Budget) error {
+	return repo.db.WithContext(ctx).Create(budget).Error
+}
+
+func (repo *BudgetRepo) GetById(ctx context.Context, id uint) (*models.Budget, error) {
+	var budget models.Budget
+	if err := repo.db.WithContext(ctx).Preload("Team.Members").First(&budget, id).Error; err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (repo *BudgetRepo) GetAll(ctx context.Context, filter *models.BudgetFilter) ([]models.Budget, error) {
+	query := repo.db.WithContext(ctx).Preload("Team.Members")
+	if filter != nil && filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter != nil && filter.TeamID != nil {
+		query = query.Where("team_id = ?", *filter.TeamID)
+	}
+
+	var budgets []models.Budget
+	if err := query.Order("id").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (repo *BudgetRepo) FindForOwners(ctx context.Context, userIDs []string, teamIDs []uint) ([]models.Budget, error) { //бюджеты пользователей и команд
+	if len(userIDs) == 0 && len(teamIDs) == 0 {
+		return nil, nil
+	}
+	if userIDs == nil {
+		userIDs = []string{}
+	}
+	if teamIDs == nil {
+		teamIDs = []uint{}
+	}
+
+	var budgets []models.Budget
+	err := repo.db.WithContext(ctx).Preload("Team.Members").
+		Where("user_id IN ? OR team_id IN ?", userIDs, teamIDs).
+		Find(&budgets).Error
+	if err != nil {
+		return nil, err
+	}
+	return budgets, nil
+}
+
+func (repo *BudgetRepo) Delete(ctx context.Context, id uint) error {
+	res := repo.db.WithContext(ctx).Delete(&models.Budget{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
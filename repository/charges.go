@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"subscriptions/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const chargesBatchSize = 1000
+
+// LedgerFunc строит строки журнала начислений по подписке. Репозиторий вызывает ее внутри транзакции изменения
+// после записи версии, так что журнал меняется вместе с подпиской или не меняется вовсе
+type LedgerFunc func(subscription *models.Subscription) []models.Charge
+
+// replaceCharges заменяет начисления подписки в журнале, пустой список убирает их все
+func replaceCharges(tx *gorm.DB, id uint, charges []models.Charge) error {
+	if err := tx.Where("subscription_id = ?", id).Delete(&models.Charge{}).Error; err != nil {
+		return err
+	}
+	if len(charges) == 0 {
+		return nil
+	}
+	for i := range charges {
+		charges[i].ID = 0
+		charges[i].SubscriptionID = id
+	}
+	return tx.CreateInBatches(&charges, chargesBatchSize).Error
+}
+
+func (repo *SubscriptionRepo) GetCharges(ctx context.Context, id uint) ([]models.Charge, error) {
+	var charges []models.Charge
+	if err := repo.db.WithContext(ctx).Where("subscription_id = ?", id).Order("month").Find(&charges).Error; err != nil {
+		return nil, err
+	}
+	return charges, nil
+}
+
+// SubscriptionIDs возвращает id подписок по возрастанию после afterID, не больше limit. serviceID - только подписки сервиса
+func (repo *SubscriptionRepo) SubscriptionIDs(ctx context.Context, serviceID *uint, afterID uint, limit int) ([]uint, error) {
+	var ids []uint
+	query := repo.db.WithContext(ctx).Model(&models.Subscription{}).Where("id > ?", afterID)
+	if serviceID != nil {
+		query = query.Where("service_id = ?", *serviceID)
+	}
+	if err := query.Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// RebuildCharges заново строит журнал одной подписки и возвращает число строк. Строка подписки блокируется до конца
+// транзакции: параллельное изменение дождется пересборки, а пересборка после него прочитает уже сохраненную подписку.
+// У удаленной подписки начисления просто убираются
+func (repo *SubscriptionRepo) RebuildCharges(ctx context.Context, id uint, ledger LedgerFunc) (int, error) {
+	count := 0
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Subscription{}, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return replaceCharges(tx, id, nil)
+		}
+		if err != nil {
+			return err
+		}
+		var subscription models.Subscription
+		if err = preloadDetails(tx).First(&subscription, id).Error; err != nil {
+			return err
+		}
+		charges := ledger(&subscription)
+		count = len(charges)
+		return replaceCharges(tx, id, charges)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// PruneCharges убирает из журнала начисления подписок, которых больше нет среди действующих
+func (repo *SubscriptionRepo) PruneCharges(ctx context.Context) (int64, error) {
+	alive := repo.db.Model(&models.Subscription{}).Select("id")
+	res := repo.db.WithContext(ctx).Where("subscription_id NOT IN (?)", alive).Delete(&models.Charge{})
+	return res.RowsAffected, res.Error
+}
+
+func chargesBetween(start, end *time.Time) func(db *gorm.DB) *gorm.DB { //начисления за месяцы [start, end] по порядку
+	return func(db *gorm.DB) *gorm.DB {
+		if start != nil {
+			db = db.Where("month >= ?", *start)
+		}
+		if end != nil {
+			db = db.Where("month <= ?", *end)
+		}
+		return db.Order("month")
+	}
+}
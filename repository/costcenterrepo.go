@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"gorm.io/gorm"
+)
+
+type CostCenterRepoInterface interface {
+	Create(ctx context.Context, center *models.CostCenter) error
+	GetAll(ctx context.Context) ([]models.CostCenter, error)
+	GetByIds(ctx context.Context, ids []uint) ([]models.CostCenter, error)
+}
+
+type CostCenterRepo struct {
+	db *gorm.DB
+}
+
+func NewCostCenterRepo(db *gorm.DB) CostCenterRepoInterface { //создание репозитория для центров затрат
+	return &CostCenterRepo{db: db}
+}
+
+func (repo *CostCenterRepo) Create(ctx context.Context, center *models.CostCenter) error {
+	return repo.db.WithContext(ctx).Create(center).Error
+}
+
+func (repo *CostCenterRepo) GetAll(ctx context.Context) ([]models.CostCenter, error) {
+	var centers []models.CostCenter
+	if err := repo.db.WithContext(ctx).Order("code").Find(&centers).Error; err != nil {
+		return nil, err
+	}
+	return centers, nil
+}
+
+func (repo *CostCenterRepo) GetByIds(ctx context.Context, ids []uint) ([]models.CostCenter, error) { //найденные центры, несуществующие id пропускаются
+	var centers []models.CostCenter
+	if err := repo.db.WithContext(ctx).Where("id IN ?", ids).Find(&centers).Error; err != nil {
+		return nil, err
+	}
+	return centers, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CountSubscribers считает разных пользователей с действующей на момент at подпиской на сервис
+func (repo *SubscriptionRepo) CountSubscribers(ctx context.Context, serviceID uint, at time.Time) (int64, error) {
+	var count int64
+	err := activeSubscriptions(repo.db.WithContext(ctx), serviceID, at).
+		Distinct("user_id").
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SubscriberTrend считает разных пользователей, чьи подписки на сервис действовали в каждом из месяцев [from, to], одним запросом.
+// Статус берется текущий: паузы в прошлом не учитываются, отмененные подписки считаются до даты окончания.
+// Месяцы строятся без часового пояса и переводятся в UTC, чтобы переход на летнее время не сдвигал границы
+func (repo *SubscriptionRepo) SubscriberTrend(ctx context.Context, serviceID uint, from, to time.Time) ([]models.SubscriberPoint, error) {
+	var points []models.SubscriberPoint
+	err := repo.db.WithContext(ctx).Raw(`
+		SELECT months.month AT TIME ZONE 'UTC' AS month, COUNT(DISTINCT subscriptions.user_id) AS subscribers
+		FROM generate_series(CAST(? AS timestamp), CAST(? AS timestamp), interval '1 month') AS months(month)
+		LEFT JOIN subscriptions ON subscriptions.service_id = ? AND subscriptions.deleted_at IS NULL
+			AND subscriptions.status NOT IN ?
+			AND subscriptions.start_date < (months.month + interval '1 month') AT TIME ZONE 'UTC'
+			AND (subscriptions.end_date IS NULL OR subscriptions.end_date >= months.month AT TIME ZONE 'UTC')
+		GROUP BY months.month
+		ORDER BY months.month`,
+		from.Format("2006-01-02"), to.Format("2006-01-02"), serviceID, []string{models.StatusPendingApproval, models.StatusRejected}).
+		Scan(&points).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range points {
+		points[i].Month = points[i].Month.UTC()
+	}
+	return points, nil
+}
+
+func activeSubscriptions(db *gorm.DB, serviceID uint, at time.Time) *gorm.DB { //подписки сервиса, действующие на момент at: без паузы и до даты окончания
+	return db.Model(&models.Subscription{}).
+		Where("service_id = ? AND start_date <= ? AND (end_date IS NULL OR end_date >= ?)", serviceID, at, at).
+		Where("status IN ?", []string{models.StatusTrial, models.StatusActive, models.StatusCancelled})
+}
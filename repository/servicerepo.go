@@ -5,6 +5,7 @@ import (
 	"subscriptions/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ServiceRepoInterface interface {
@@ -14,6 +15,7 @@ type ServiceRepoInterface interface {
 	GetByName(ctx context.Context, name string) (*models.Service, error)
 	Update(ctx context.Context, service *models.Service) error
 	Delete(ctx context.Context, id uint) error
+	ReplaceAliases(ctx context.Context, id uint, aliases []models.ServiceAlias) error
 }
 
 type ServiceRepo struct {
@@ -30,7 +32,7 @@ func (repo *ServiceRepo) Create(ctx context.Context, service *models.Service) er
 
 func (repo *ServiceRepo) GetAll(ctx context.Context) ([]models.Service, error) { //получение всех сервисов
 	var services []models.Service
-	if err := repo.db.WithContext(ctx).Find(&services).Error; err != nil {
+	if err := repo.db.WithContext(ctx).Preload("Aliases").Find(&services).Error; err != nil {
 		return nil, err
 	}
 	return services, nil
@@ -38,7 +40,7 @@ func (repo *ServiceRepo) GetAll(ctx context.Context) ([]models.Service, error) {
 
 func (repo *ServiceRepo) GetById(ctx context.Context, id uint) (*models.Service, error) { //получение сервиса по id
 	var service models.Service
-	if err := repo.db.WithContext(ctx).First(&service, id).Error; err != nil {
+	if err := repo.db.WithContext(ctx).Preload("Aliases").First(&service, id).Error; err != nil {
 		return nil, err
 	}
 	return &service, nil
@@ -46,14 +48,14 @@ func (repo *ServiceRepo) GetById(ctx context.Context, id uint) (*models.Service,
 
 func (repo *ServiceRepo) GetByName(ctx context.Context, name string) (*models.Service, error) { //получение сервиса по названию
 	var service models.Service
-	if err := repo.db.WithContext(ctx).Where("name = ?", name).First(&service).Error; err != nil {
+	if err := repo.db.WithContext(ctx).Preload("Aliases").Where("name = ?", name).First(&service).Error; err != nil {
 		return nil, err
 	}
 	return &service, nil
 }
 
 func (repo *ServiceRepo) Update(ctx context.Context, service *models.Service) error { //обновление сервиса
-	return repo.db.WithContext(ctx).Save(service).Error
+	return repo.db.WithContext(ctx).Omit(clause.Associations).Save(service).Error //названия меняются через ReplaceAliases
 }
 
 func (repo *ServiceRepo) Delete(ctx context.Context, id uint) error { //удаление сервиса
@@ -66,3 +68,19 @@ func (repo *ServiceRepo) Delete(ctx context.Context, id uint) error { //удал
 	}
 	return nil
 }
+
+func (repo *ServiceRepo) ReplaceAliases(ctx context.Context, id uint, aliases []models.ServiceAlias) error { //замена названий сервиса в выписках
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("service_id = ?", id).Delete(&models.ServiceAlias{}).Error; err != nil {
+			return err
+		}
+		if len(aliases) == 0 {
+			return nil
+		}
+		for i := range aliases {
+			aliases[i].ID = 0
+			aliases[i].ServiceID = id
+		}
+		return tx.Create(&aliases).Error
+	})
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"subscriptions/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StatementRepoInterface interface {
+	CreateImport(ctx context.Context, statement *models.StatementImport) error
+	FindUnmatched(ctx context.Context, userID string) ([]models.BankTransaction, error)
+	LinkTransactions(ctx context.Context, ids []uint, subscriptionID uint) error
+}
+
+const importBatchSize = 1000 //большие выписки не упираются в лимит параметров запроса
+
+type StatementRepo struct {
+	db *gorm.DB
+}
+
+func NewStatementRepo(db *gorm.DB) StatementRepoInterface { //создание репозитория для банковских выписок
+	return &StatementRepo{db: db}
+}
+
+func (repo *StatementRepo) CreateImport(ctx context.Context, statement *models.StatementImport) error { //выписка вместе с операциями
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Transactions").Create(statement).Error; err != nil {
+			return err
+		}
+		if len(statement.Transactions) == 0 {
+			return nil
+		}
+		for i := range statement.Transactions {
+			statement.Transactions[i].ImportID = statement.ID
+		}
+		SetFingerprints(statement.Transactions)
+		err := tx.Clauses(clause.OnConflict{ //операция из пересекающейся выписки уже сохранена: строка остается прежней, но получает найденную подписку
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "fingerprint"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"subscription_id": gorm.Expr("COALESCE(bank_transactions.subscription_id, excluded.subscription_id)"),
+			}),
+		}).CreateInBatches(statement.Transactions, importBatchSize).Error
+		if err != nil {
+			return err
+		}
+		return readStored(tx, statement.UserID, statement.Transactions)
+	})
+}
+
+// readStored заменяет операции выписки сохраненными строками: у повторов из прошлых выписок свои id, импорт и привязка к подписке
+func readStored(tx *gorm.DB, userID string, transactions []models.BankTransaction) error {
+	stored := make(map[string]models.BankTransaction, len(transactions))
+	for start := 0; start < len(transactions); start += importBatchSize {
+		end := min(start+importBatchSize, len(transactions))
+		fingerprints := make([]string, 0, end-start)
+		for _, transaction := range transactions[start:end] {
+			fingerprints = append(fingerprints, transaction.Fingerprint)
+		}
+		var batch []models.BankTransaction
+		if err := tx.Where("user_id = ? AND fingerprint IN ?", userID, fingerprints).Find(&batch).Error; err != nil {
+			return err
+		}
+		for _, transaction := range batch {
+			stored[transaction.Fingerprint] = transaction
+		}
+	}
+	for i := range transactions {
+		transaction, ok := stored[transactions[i].Fingerprint]
+		if !ok {
+			return fmt.Errorf("bank transaction %s was not saved", transactions[i].Fingerprint)
+		}
+		transactions[i] = transaction
+	}
+	return nil
+}
+
+// TransactionFingerprint - ключ операции среди операций пользователя: FITID, если банк его передал, иначе дата, сумма и описание.
+// Так же его считает миграция для операций, сохраненных раньше
+func TransactionFingerprint(transaction *models.BankTransaction) string {
+	if transaction.ExternalID != "" {
+		return "id:" + transaction.ExternalID
+	}
+	return fmt.Sprintf("tx:%s|%d|%s", transaction.Date.UTC().Format("2006-01-02"), transaction.Amount, transaction.Description)
+}
+
+// SetFingerprints задает ключи операциям одной выписки. Одинаковые операции в одной выписке - разные платежи,
+// поэтому повтор получает номер (#2, #3...), а та же выписка, загруженная снова, дает те же ключи
+func SetFingerprints(transactions []models.BankTransaction) {
+	seen := map[string]int{}
+	for i := range transactions {
+		fingerprint := TransactionFingerprint(&transactions[i])
+		seen[fingerprint]++
+		if n := seen[fingerprint]; n > 1 {
+			fingerprint = fmt.Sprintf("%s#%d", fingerprint, n)
+		}
+		transactions[i].Fingerprint = fingerprint
+	}
+}
+
+func (repo *StatementRepo) FindUnmatched(ctx context.Context, userID string) ([]models.BankTransaction, error) { //списания пользователя, не связанные с подписками
+	var transactions []models.BankTransaction
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ? AND subscription_id IS NULL AND amount_minor < 0", userID).
+		Order("date, id").
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (repo *StatementRepo) LinkTransactions(ctx context.Context, ids []uint, subscriptionID uint) error { //привязка операций к подписке
+	if len(ids) == 0 {
+		return nil
+	}
+	return repo.db.WithContext(ctx).Model(&models.BankTransaction{}).Where("id IN ?", ids).Update("subscription_id", subscriptionID).Error
+}
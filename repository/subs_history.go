@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (repo *SubscriptionRepo) GetHistory(ctx context.Context, id uint) ([]models.SubscriptionVersion, error) { //все версии подписки
+	var versions []models.SubscriptionVersion
+	err := repo.db.WithContext(ctx).Where("subscription_id = ?", id).Order("version").Find(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (repo *SubscriptionRepo) GetAllAsOf(ctx context.Context, filter *models.ListFilter, asOf time.Time) ([]models.Subscription, error) { //подписки в том виде, в котором они были на момент asOf
+	query := versionsAsOf(repo.db.WithContext(ctx), asOf)
+	if filter == nil || !filter.IncludeDeleted {
+		query = query.Where("deleted = ?", false)
+	}
+	if filter != nil && filter.UserID != nil {
+		query = query.Where("user_id = ? OR subscription_id IN (?)", *filter.UserID, membersAsOf(repo.db, asOf).Select("subscription_id").Where("user_id = ?", *filter.UserID))
+	}
+	if filter != nil && filter.Tag != nil {
+		query = query.Where("subscription_id IN (?)", taggedSubscriptionIDs(repo.db, *filter.Tag))
+	}
+	if filter != nil && filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+
+	var versions []models.SubscriptionVersion
+	if err := query.Order("subscription_id").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]models.Subscription, 0, len(versions))
+	for _, version := range versions {
+		subscriptions = append(subscriptions, version.Subscription())
+	}
+	if err := repo.attachServices(ctx, subscriptions); err != nil {
+		return nil, err
+	}
+	if err := repo.attachDetailsAsOf(ctx, subscriptions, asOf); err != nil {
+		return nil, err
+	}
+	if err := repo.attachCurrentDetails(ctx, subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (repo *SubscriptionRepo) findForSumAsOf(ctx context.Context, query *SubscriptionQuery) ([]models.Subscription, error) {
+	asOf := *query.AsOf
+	db := versionsAsOf(repo.db.WithContext(ctx), asOf).Where("deleted = ?", false)
+
+	if query.UserID != nil {
+		if query.WithShared {
+			db = db.Where("subscription_versions.user_id = ? OR subscription_versions.subscription_id IN (?)",
+				*query.UserID, membersAsOf(repo.db, asOf).Select("subscription_id").Where("user_id = ?", *query.UserID))
+		} else {
+			db = db.Where("subscription_versions.user_id = ?", *query.UserID)
+		}
+	}
+
+	if query.ServiceName != nil || query.Category != nil {
+		db = db.Joins("JOIN services ON services.id = subscription_versions.service_id")
+	}
+
+	if query.ServiceName != nil {
+		db = db.Where("services.name = ?", *query.ServiceName)
+	}
+
+	if query.ServiceID != nil {
+		db = db.Where("subscription_versions.service_id = ?", *query.ServiceID)
+	}
+
+	if query.Category != nil {
+		db = db.Where("services.category = ?", *query.Category)
+	}
+
+	if query.Currency != nil {
+		db = db.Where("subscription_versions.currency = ?", *query.Currency)
+	}
+
+	if query.Tag != nil {
+		db = db.Where("subscription_versions.subscription_id IN (?)", taggedSubscriptionIDs(repo.db, *query.Tag))
+	}
+
+	if !query.WithPending {
+		db = db.Where("subscription_versions.status <> ?", models.StatusPendingApproval)
+	}
+
+	if query.Start != nil {
+		db = db.Where("subscription_versions.end_date >= ? OR subscription_versions.end_date IS NULL", *query.Start)
+	}
+
+	if query.End != nil {
+		db = db.Where("subscription_versions.start_date < ?", query.End.AddDate(0, 1, 0))
+	}
+
+	var versions []models.SubscriptionVersion
+	if err := db.Find(&versions).Error; err != nil {
+		return nil, err
+	}
+
+	subscriptions := make([]models.Subscription, 0, len(versions))
+	for _, version := range versions {
+		subscriptions = append(subscriptions, version.Subscription())
+	}
+	if err := repo.attachServices(ctx, subscriptions); err != nil { //сервис нужен для налоговых настроек
+		return nil, err
+	}
+	if err := repo.attachDetailsAsOf(ctx, subscriptions, asOf); err != nil {
+		return nil, err
+	}
+	if err := repo.attachCurrentDetails(ctx, subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (repo *SubscriptionRepo) attachServices(ctx context.Context, subscriptions []models.Subscription) error { //сервисы версий берутся в текущем виде
+	serviceIDs := make([]uint, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		serviceIDs = append(serviceIDs, subscription.ServiceID)
+	}
+	if len(serviceIDs) == 0 {
+		return nil
+	}
+
+	var found []models.Service
+	if err := repo.db.WithContext(ctx).Where("id IN ?", serviceIDs).Find(&found).Error; err != nil {
+		return err
+	}
+	services := map[uint]models.Service{}
+	for _, service := range found {
+		services[service.ID] = service
+	}
+	for i := range subscriptions {
+		subscriptions[i].Service = services[subscriptions[i].ServiceID]
+	}
+	return nil
+}
+
+func (repo *SubscriptionRepo) attachDetailsAsOf(ctx context.Context, subscriptions []models.Subscription, asOf time.Time) error { //переходы, участники и история цены на момент asOf
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		ids = append(ids, subscription.ID)
+	}
+
+	var transitions []models.StatusTransition
+	err := orderTransitions(repo.db.WithContext(ctx).Where("subscription_id IN ? AND created_at < ?", ids, asOf)).
+		Find(&transitions).Error
+	if err != nil {
+		return err
+	}
+
+	var members []models.SubscriptionMember
+	err = membersAsOf(repo.db.WithContext(ctx), asOf).Where("subscription_id IN ?", ids).Find(&members).Error
+	if err != nil {
+		return err
+	}
+
+	var versions []models.SubscriptionVersion
+	err = orderVersions(repo.db.WithContext(ctx).Where("subscription_id IN ? AND valid_from < ?", ids, asOf)).Find(&versions).Error
+	if err != nil {
+		return err
+	}
+
+	transitionsByID := map[uint][]models.StatusTransition{}
+	for _, transition := range transitions {
+		transitionsByID[transition.SubscriptionID] = append(transitionsByID[transition.SubscriptionID], transition)
+	}
+	membersByID := map[uint][]models.SubscriptionMember{}
+	for _, member := range members {
+		membersByID[member.SubscriptionID] = append(membersByID[member.SubscriptionID], member)
+	}
+	versionsByID := map[uint][]models.SubscriptionVersion{}
+	for _, version := range versions {
+		versionsByID[version.SubscriptionID] = append(versionsByID[version.SubscriptionID], version)
+	}
+	for i := range subscriptions {
+		subscriptions[i].Transitions = transitionsByID[subscriptions[i].ID]
+		subscriptions[i].Members = membersByID[subscriptions[i].ID]
+		subscriptions[i].Versions = versionsByID[subscriptions[i].ID]
+	}
+	return nil
+}
+
+func (repo *SubscriptionRepo) attachCurrentDetails(ctx context.Context, subscriptions []models.Subscription) error { //метки и центры затрат не версионируются и берутся в текущем виде
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		ids = append(ids, subscription.ID)
+	}
+
+	var rows []struct {
+		SubscriptionID uint
+		models.Tag
+	}
+	err := repo.db.WithContext(ctx).Table("tags").
+		Select("subscription_tags.subscription_id, tags.id, tags.name").
+		Joins("JOIN subscription_tags ON subscription_tags.tag_id = tags.id").
+		Where("subscription_tags.subscription_id IN ?", ids).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	var allocations []models.CostAllocation
+	if err = repo.db.WithContext(ctx).Preload("CostCenter").Where("subscription_id IN ?", ids).Find(&allocations).Error; err != nil {
+		return err
+	}
+
+	tagsByID := map[uint][]models.Tag{}
+	for _, row := range rows {
+		tagsByID[row.SubscriptionID] = append(tagsByID[row.SubscriptionID], row.Tag)
+	}
+	allocationsByID := map[uint][]models.CostAllocation{}
+	for _, allocation := range allocations {
+		allocationsByID[allocation.SubscriptionID] = append(allocationsByID[allocation.SubscriptionID], allocation)
+	}
+	for i := range subscriptions {
+		subscriptions[i].Tags = tagsByID[subscriptions[i].ID]
+		subscriptions[i].Allocations = allocationsByID[subscriptions[i].ID]
+	}
+	return nil
+}
+
+func versionsAsOf(db *gorm.DB, asOf time.Time) *gorm.DB { //версии, действовавшие непосредственно перед моментом asOf
+	return db.Model(&models.SubscriptionVersion{}).
+		Where("valid_from < ? AND (valid_to IS NULL OR valid_to >= ?)", asOf, asOf)
+}
+
+func membersAsOf(db *gorm.DB, asOf time.Time) *gorm.DB { //участники, которые состояли в подписке на момент asOf
+	return db.Unscoped().Model(&models.SubscriptionMember{}).
+		Where("created_at < ? AND (deleted_at IS NULL OR deleted_at >= ?)", asOf, asOf)
+}
+
+func writeVersion(tx *gorm.DB, subscription *models.Subscription, deleted bool) error { //закрываем текущую версию и сохраняем новую
+	now := time.Now()
+
+	err := tx.Model(&models.SubscriptionVersion{}).
+		Where("subscription_id = ? AND valid_to IS NULL", subscription.ID).
+		Update("valid_to", now).Error
+	if err != nil {
+		return err
+	}
+
+	var last int
+	err = tx.Model(&models.SubscriptionVersion{}).
+		Where("subscription_id = ?", subscription.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&last).Error
+	if err != nil {
+		return err
+	}
+
+	version := models.NewSubscriptionVersion(subscription, last+1, deleted, now)
+	if err = tx.Create(version).Error; err != nil {
+		return err
+	}
+	subscription.Versions = append(subscription.Versions, *version) //история цены в памяти совпадает с базой, по ней строится журнал начислений
+	return nil
+}
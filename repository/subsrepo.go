@@ -6,15 +6,56 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SubscriptionRepoInterface interface {
-	Create(ctx context.Context, subscription *models.Subscription) error
+	Create(ctx context.Context, subscription *models.Subscription, ledger LedgerFunc) error
 	GetById(ctx context.Context, id uint) (*models.Subscription, error)
-	GetAll(ctx context.Context) ([]models.Subscription, error)
-	Update(ctx context.Context, subscription *models.Subscription) error
+	GetAll(ctx context.Context, filter *models.ListFilter) ([]models.Subscription, error)
+	Update(ctx context.Context, subscription *models.Subscription, details *SubscriptionDetails, ledger LedgerFunc) error
 	Delete(ctx context.Context, id uint) error
-	SumByFilters(ctx context.Context, userId, serviceName *string, start, end *time.Time) (int, error)
+	Restore(ctx context.Context, id uint, ledger LedgerFunc) error
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, []string, error) //число подписок и ключи файлов их вложений
+	GetHistory(ctx context.Context, id uint) ([]models.SubscriptionVersion, error)
+	GetAllAsOf(ctx context.Context, filter *models.ListFilter, asOf time.Time) ([]models.Subscription, error)
+	FindForSum(ctx context.Context, query *SubscriptionQuery) ([]models.Subscription, error)
+	AddTransition(ctx context.Context, subscription *models.Subscription, transition *models.StatusTransition, ledger LedgerFunc) error
+	FindOffersEnding(ctx context.Context, from, to time.Time) ([]models.Subscription, error)
+	FindRenewing(ctx context.Context, userID *string) ([]models.Subscription, error)
+	FindDueRenewals(ctx context.Context, today time.Time) ([]models.Subscription, error)
+	Renew(ctx context.Context, subscription *models.Subscription, previousEnd time.Time, ledger LedgerFunc) (bool, error)
+	FindCancelled(ctx context.Context, from, to time.Time, userID *string) ([]models.Subscription, error)
+	CountSubscribers(ctx context.Context, serviceID uint, at time.Time) (int64, error)
+	SubscriberTrend(ctx context.Context, serviceID uint, from, to time.Time) ([]models.SubscriberPoint, error)
+	GetCharges(ctx context.Context, id uint) ([]models.Charge, error)
+	SubscriptionIDs(ctx context.Context, serviceID *uint, afterID uint, limit int) ([]uint, error)
+	RebuildCharges(ctx context.Context, id uint, ledger LedgerFunc) (int, error)
+	PruneCharges(ctx context.Context) (int64, error)
+	FindOrCreateTags(ctx context.Context, names []string) ([]models.Tag, error)
+}
+
+// SubscriptionQuery - условия выборки подписок для расчета сумм
+type SubscriptionQuery struct {
+	UserID      *string
+	ServiceName *string
+	ServiceID   *uint
+	Category    *string    //категория сервиса
+	Currency    *string    //только подписки в этой валюте
+	Tag         *string    //только подписки с этой меткой
+	Start       *time.Time //подписка должна пересекаться с месяцами периода [Start, End]
+	End         *time.Time //первое число последнего месяца, подписки, начавшиеся в этом месяце, тоже попадают
+	AsOf        *time.Time //брать данные в том виде, в котором они были на этот момент
+	WithShared  bool       //вместе с подписками, где UserID - участник
+	WithCharges bool       //вместе с начислениями из журнала за месяцы [Start, End]
+	WithPending bool       //вместе с подписками, ожидающими одобрения
+}
+
+// SubscriptionDetails - связанные записи, которые Update заменяет в одной транзакции с подпиской, nil - без изменений
+type SubscriptionDetails struct {
+	Members     *[]models.SubscriptionMember
+	Tags        *[]models.Tag //уже существующие метки, см. FindOrCreateTags
+	Allocations *[]models.CostAllocation
 }
 
 type SubscriptionRepo struct {
@@ -25,56 +66,380 @@ func NewSubscriptionRepo(db *gorm.DB) SubscriptionRepoInterface {
 	return &SubscriptionRepo{db: db}
 }
 
-func (repo *SubscriptionRepo) Create(ctx context.Context, subscription *models.Subscription) error {
-	return repo.db.WithContext(ctx).Create(subscription).Error
+func (repo *SubscriptionRepo) Create(ctx context.Context, subscription *models.Subscription, ledger LedgerFunc) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Service").Create(subscription).Error; err != nil { //сервис уже существует, он нужен только для налога в журнале
+			return err
+		}
+		if err := writeVersion(tx, subscription, false); err != nil {
+			return err
+		}
+		return replaceCharges(tx, subscription.ID, ledger(subscription))
+	})
 }
 
 func (repo *SubscriptionRepo) GetById(ctx context.Context, id uint) (*models.Subscription, error) {
 	var subscription models.Subscription
-	if err := repo.db.WithContext(ctx).Preload("Service").First(&subscription, id).Error; err != nil {
+	if err := preloadDetails(repo.db.WithContext(ctx)).First(&subscription, id).Error; err != nil {
 		return nil, err
 	}
 	return &subscription, nil
 }
 
-func (repo *SubscriptionRepo) GetAll(ctx context.Context) ([]models.Subscription, error) {
+func (repo *SubscriptionRepo) GetAll(ctx context.Context, filter *models.ListFilter) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	query := preloadDetails(repo.db.WithContext(ctx))
+	if filter != nil && filter.IncludeDeleted {
+		query = query.Unscoped() //вместе с удаленными
+	}
+	if filter != nil && filter.UserID != nil {
+		query = query.Where("user_id = ? OR id IN (?)", *filter.UserID, memberSubscriptionIDs(repo.db, *filter.UserID))
+	}
+	if filter != nil && filter.Tag != nil {
+		query = query.Where("id IN (?)", taggedSubscriptionIDs(repo.db, *filter.Tag))
+	}
+	if filter != nil && filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if err := query.Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (repo *SubscriptionRepo) Update(ctx context.Context, subscription *models.Subscription, details *SubscriptionDetails, ledger LedgerFunc) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := replaceDetails(tx, subscription.ID, details); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(subscription).Error; err != nil {
+			return err
+		}
+		if err := writeVersion(tx, subscription, false); err != nil {
+			return err
+		}
+		return replaceCharges(tx, subscription.ID, ledger(subscription))
+	})
+}
+
+func (repo *SubscriptionRepo) Delete(ctx context.Context, id uint) error { //мягкое удаление, запись остается с deleted_at
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var subscription models.Subscription
+		if err := tx.First(&subscription, id).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&models.Subscription{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := replaceCharges(tx, id, nil); err != nil { //начисления удаленной подписки убираются из журнала
+			return err
+		}
+		return writeVersion(tx, &subscription, true)
+	})
+}
+
+func (repo *SubscriptionRepo) Restore(ctx context.Context, id uint, ledger LedgerFunc) error { //восстановление мягко удаленной подписки
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&models.Subscription{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Update("deleted_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var subscription models.Subscription
+		if err := preloadDetails(tx).First(&subscription, id).Error; err != nil {
+			return err
+		}
+		if err := writeVersion(tx, &subscription, false); err != nil {
+			return err
+		}
+		return replaceCharges(tx, id, ledger(&subscription))
+	})
+}
+
+func (repo *SubscriptionRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, []string, error) { //окончательное удаление старых записей
+	var count int64
+	var keys []string
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		purged := tx.Unscoped().Model(&models.Subscription{}).Select("id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+		if err := tx.Model(&models.Attachment{}).Where("subscription_id IN (?)", purged).Pluck("storage_key", &keys).Error; err != nil { //файлы удалит сервис после коммита
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", purged).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", purged).Delete(&models.StatusTransition{}).Error; err != nil { //переходы и участники ссылаются на подписку
+			return err
+		}
+		if err := tx.Unscoped().Where("subscription_id IN (?)", purged).Delete(&models.SubscriptionMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", purged).Delete(&models.Charge{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM subscription_tags WHERE subscription_id IN (?)", purged).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("subscription_id IN (?)", purged).Delete(&models.CostAllocation{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Delete(&models.Subscription{})
+		count = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, keys, nil
+}
+
+// FindForSum возвращает подписки, которые пересекаются с периодом [Start, End], вместе с сервисом, переходами статусов и участниками.
+// Если задан AsOf, берутся версии подписок, переходы и участники, существовавшие на тот момент
+func (repo *SubscriptionRepo) FindForSum(ctx context.Context, query *SubscriptionQuery) ([]models.Subscription, error) {
+	if query.AsOf != nil {
+		return repo.findForSumAsOf(ctx, query)
+	}
+
+	db := repo.db.WithContext(ctx).Model(&models.Subscription{}).
+		Preload("Service").
+		Preload("Transitions", orderTransitions).
+		Preload("Members").
+		Preload("Versions", orderVersions).
+		Preload("Tags", orderTags).
+		Preload("Allocations")
+
+	if query.WithCharges {
+		db = db.Preload("Charges", chargesBetween(query.Start, query.End))
+	}
+
+	if query.UserID != nil {
+		if query.WithShared {
+			db = db.Where("subscriptions.user_id = ? OR subscriptions.id IN (?)", *query.UserID, memberSubscriptionIDs(repo.db, *query.UserID))
+		} else {
+			db = db.Where("subscriptions.user_id = ?", *query.UserID)
+		}
+	}
+
+	if query.ServiceName != nil || query.Category != nil {
+		db = db.Joins("JOIN services ON services.id = subscriptions.service_id")
+	}
+
+	if query.ServiceName != nil {
+		db = db.Where("services.name = ?", *query.ServiceName)
+	}
+
+	if query.ServiceID != nil {
+		db = db.Where("subscriptions.service_id = ?", *query.ServiceID)
+	}
+
+	if query.Category != nil {
+		db = db.Where("services.category = ?", *query.Category)
+	}
+
+	if query.Currency != nil {
+		db = db.Where("subscriptions.currency = ?", *query.Currency)
+	}
+
+	if query.Tag != nil {
+		db = db.Where("subscriptions.id IN (?)", taggedSubscriptionIDs(repo.db, *query.Tag))
+	}
+
+	if !query.WithPending {
+		db = db.Where("subscriptions.status <> ?", models.StatusPendingApproval)
+	}
+
+	if query.Start != nil {
+		db = db.Where("subscriptions.end_date >= ? OR subscriptions.end_date IS NULL", *query.Start) //нужно учесть записи, у которых нет конца
+	}
+
+	if query.End != nil {
+		db = db.Where("subscriptions.start_date < ?", query.End.AddDate(0, 1, 0))
+	}
+
 	var subscriptions []models.Subscription
-	if err := repo.db.WithContext(ctx).Preload("Service").Find(&subscriptions).Error; err != nil {
+	if err := db.Find(&subscriptions).Error; err != nil {
 		return nil, err
 	}
 	return subscriptions, nil
 }
 
-func (repo *SubscriptionRepo) Update(ctx context.Context, subscription *models.Subscription) error {
-	return repo.db.WithContext(ctx).Save(subscription).Error
+// AddTransition сохраняет новый статус подписки и запись о переходе в одной транзакции
+func (repo *SubscriptionRepo) AddTransition(ctx context.Context, subscription *models.Subscription, transition *models.StatusTransition, ledger LedgerFunc) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transition.SubscriptionID = subscription.ID
+		if err := tx.Create(transition).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(subscription).Error; err != nil {
+			return err
+		}
+		if err := writeVersion(tx, subscription, false); err != nil {
+			return err
+		}
+		subscription.Transitions = append(subscription.Transitions, *transition) //журнал строится уже с новым переходом
+		return replaceCharges(tx, subscription.ID, ledger(subscription))
+	})
 }
 
-func (repo *SubscriptionRepo) Delete(ctx context.Context, id uint) error {
-	return repo.db.WithContext(ctx).Delete(&models.Subscription{}, id).Error
+func (repo *SubscriptionRepo) FindOffersEnding(ctx context.Context, from, to time.Time) ([]models.Subscription, error) { //подписки, у которых в [from, to] заканчивается пробный период или вводная цена
+	var subscriptions []models.Subscription
+	err := repo.db.WithContext(ctx).Preload("Service").
+		Where("status <> ?", models.StatusCancelled).
+		Where("trial_end_date BETWEEN ? AND ? OR promo_end_date BETWEEN ? AND ?", from, to, from, to).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
 }
 
-func (repo *SubscriptionRepo) SumByFilters(ctx context.Context, userId, serviceName *string, start, end *time.Time) (int, error) {
-	query := repo.db.WithContext(ctx).Model(&models.Subscription{}).Select("SUM(Price)")
+func (repo *SubscriptionRepo) FindRenewing(ctx context.Context, userID *string) ([]models.Subscription, error) { //подписки с автопродлением, которые еще могут продлиться
+	var subscriptions []models.Subscription
+	query := repo.db.WithContext(ctx).Preload("Service").
+		Where("auto_renew = ?", true).
+		Where("status NOT IN ?", []string{models.StatusCancelled, models.StatusRejected})
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	if err := query.Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// notRenewedStatuses - подписки в этих статусах не продлеваются: отмененные, отклоненные и еще не одобренные
+var notRenewedStatuses = []string{models.StatusCancelled, models.StatusRejected, models.StatusPendingApproval}
 
-	if userId != nil {
-		query = query.Where("user_id = ?", userId)
+func (repo *SubscriptionRepo) FindDueRenewals(ctx context.Context, today time.Time) ([]models.Subscription, error) { //подписки с автопродлением, срок которых закончился до today
+	var subscriptions []models.Subscription
+	err := preloadDetails(repo.db.WithContext(ctx)).
+		Where("auto_renew = ? AND end_date < ?", true, today).
+		Where("status NOT IN ?", notRenewedStatuses).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
 	}
+	return subscriptions, nil
+}
 
-	if serviceName != nil {
-		query = query.Joins("JOIN services ON services.id = subscriptions.service_id").Where("services.name = ?", serviceName)
+// Renew сохраняет новую дату окончания и версию подписки, только если дата окончания в базе все еще previousEnd
+// и подписка по-прежнему продлевается (см. notRenewedStatuses). Так продление не повторяется, когда задача запущена одновременно в нескольких экземплярах. false - продлевать уже не нужно
+func (repo *SubscriptionRepo) Renew(ctx context.Context, subscription *models.Subscription, previousEnd time.Time, ledger LedgerFunc) (bool, error) {
+	renewed := false
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.Subscription{}).
+			Where("id = ? AND end_date = ? AND auto_renew = ?", subscription.ID, previousEnd, true).
+			Where("status NOT IN ?", notRenewedStatuses).
+			Update("end_date", subscription.EndDate)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		renewed = true
+		if err := writeVersion(tx, subscription, false); err != nil {
+			return err
+		}
+		return replaceCharges(tx, subscription.ID, ledger(subscription))
+	})
+	if err != nil {
+		return false, err
 	}
+	return renewed, nil
+}
 
-	if start != nil {
-		query = query.Where("start_date >= ?", start)
+// FindCancelled возвращает отмененные подписки с переходами статусов, у которых есть переход в месяцах [from, to].
+// Какой из переходов - отмена, проверяет вызывающий
+func (repo *SubscriptionRepo) FindCancelled(ctx context.Context, from, to time.Time, userID *string) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	transitions := repo.db.Model(&models.StatusTransition{}).Select("subscription_id").
+		Where("date >= ? AND date < ?", from, to.AddDate(0, 1, 0))
+	query := repo.db.WithContext(ctx).Preload("Service").Preload("Transitions", orderTransitions).
+		Where("status = ? AND id IN (?)", models.StatusCancelled, transitions)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
 	}
+	if err := query.Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
 
-	if end != nil {
-		query = query.Where("end_date <= ? OR end_date IS NULL", end) //нужно учесть записи, у которых нет конца
+// replaceMembers заменяет участников подписки. Старые записи удаляются мягко, чтобы срезы as_of видели прежний состав
+func replaceMembers(tx *gorm.DB, id uint, members []models.SubscriptionMember) error {
+	if err := tx.Where("subscription_id = ?", id).Delete(&models.SubscriptionMember{}).Error; err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	for i := range members {
+		members[i].ID = 0
+		members[i].SubscriptionID = id
 	}
+	return tx.Create(&members).Error
+}
 
-	var total int
-	if err := query.Scan(&total).Error; err != nil {
-		return 0, err
+// replaceAllocations заменяет распределение подписки по центрам затрат, пустой список убирает его
+func replaceAllocations(tx *gorm.DB, id uint, allocations []models.CostAllocation) error {
+	if err := tx.Where("subscription_id = ?", id).Delete(&models.CostAllocation{}).Error; err != nil {
+		return err
+	}
+	if len(allocations) == 0 {
+		return nil
+	}
+	for i := range allocations {
+		allocations[i].ID = 0
+		allocations[i].SubscriptionID = id
+	}
+	return tx.Omit("CostCenter").Create(&allocations).Error
+}
+
+func replaceDetails(tx *gorm.DB, id uint, details *SubscriptionDetails) error { //участники, метки и центры затрат из details, заданные не nil
+	if details == nil {
+		return nil
+	}
+	if details.Members != nil {
+		if err := replaceMembers(tx, id, *details.Members); err != nil {
+			return err
+		}
+	}
+	if details.Tags != nil {
+		if err := replaceTags(tx, id, *details.Tags); err != nil {
+			return err
+		}
 	}
-	return total, nil
+	if details.Allocations != nil {
+		return replaceAllocations(tx, id, *details.Allocations)
+	}
+	return nil
+}
+
+func preloadDetails(db *gorm.DB) *gorm.DB { //сервис, переходы статусов, участники, история цены, метки и центры затрат подписки
+	return db.Preload("Service").Preload("Transitions", orderTransitions).Preload("Members").Preload("Versions", orderVersions).Preload("Tags", orderTags).
+		Preload("Allocations.CostCenter")
+}
+
+func memberSubscriptionIDs(db *gorm.DB, userID string) *gorm.DB { //подзапрос: подписки, где пользователь участник
+	return db.Model(&models.SubscriptionMember{}).Select("subscription_id").Where("user_id = ?", userID)
+}
+
+func orderTransitions(db *gorm.DB) *gorm.DB {
+	return db.Order("date, id")
+}
+
+func orderVersions(db *gorm.DB) *gorm.DB {
+	return db.Order("version")
+}
+
+func orderTags(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name")
 }
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FindOrCreateTags возвращает метки с указанными названиями, недостающие создаются
+func (repo *SubscriptionRepo) FindOrCreateTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name})
+	}
+	db := repo.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&tags).Error; err != nil {
+		return nil, err
+	}
+	var found []models.Tag
+	if err := db.Where("name IN ?", names).Order("name").Find(&found).Error; err != nil { //при конфликте id существующих меток не возвращаются
+		return nil, err
+	}
+	return found, nil
+}
+
+// replaceTags заменяет метки подписки, пустой список убирает их все. Сами метки остаются для других подписок
+func replaceTags(tx *gorm.DB, id uint, tags []models.Tag) error {
+	association := tx.Model(&models.Subscription{ID: id}).Association("Tags")
+	if len(tags) == 0 {
+		return association.Clear()
+	}
+	return association.Replace(tags)
+}
+
+func taggedSubscriptionIDs(db *gorm.DB, tag string) *gorm.DB { //подзапрос: подписки с меткой
+	return db.Table("subscription_tags").Select("subscription_tags.subscription_id").
+		Joins("JOIN tags ON tags.id = subscription_tags.tag_id").
+		Where("tags.name = ?", tag)
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"gorm.io/gorm"
+)
+
+type TeamRepoInterface interface {
+	Create(ctx context.Context, team *models.Team) error
+	GetAll(ctx context.Context) ([]models.Team, error)
+	GetById(ctx context.Context, id uint) (*models.Team, error)
+	GetByUser(ctx context.Context, userID string) ([]models.Team, error)
+}
+
+type TeamRepo struct {
+	db *gorm.DB
+}
+
+func NewTeamRepo(db *gorm.DB) TeamRepoInterface { //создание репозитория для команд
+	return &TeamRepo{db: db}
+}
+
+func (repo *TeamRepo) Create(ctx context.Context, team *models.Team) error {
+	return repo.db.WithContext(ctx).Create(team).Error
+}
+
+func (repo *TeamRepo) GetAll(ctx context.Context) ([]models.Team, error) {
+	var teams []models.Team
+	if err := repo.db.WithContext(ctx).Preload("Members").Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (repo *TeamRepo) GetById(ctx context.Context, id uint) (*models.Team, error) {
+	var team models.Team
+	if err := repo.db.WithContext(ctx).Preload("Members").First(&team, id).Error; err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (repo *TeamRepo) GetByUser(ctx context.Context, userID string) ([]models.Team, error) { //команды, в которых состоит пользователь
+	var teams []models.Team
+	err := repo.db.WithContext(ctx).Preload("Members").
+		Where("id IN (?)", repo.db.Model(&models.TeamMember{}).Select("team_id").Where("user_id = ?", userID)).
+		Find(&teams).Error
+	if err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
@@ -0,0 +1,48 @@
+package requestctx
+
+import (
+	"context"
+)
+
+const SystemActor = "system" //действия фоновых задач
+
+type contextKey string
+
+const (
+	actorKey     contextKey = "actor"
+	requestIDKey contextKey = "request_id"
+	roleKey      contextKey = "role"
+)
+
+func WithActor(ctx context.Context, actor string) context.Context { //кто выполняет запрос
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
+
+func WithRole(ctx context.Context, role string) context.Context { //роль автора запроса, например approver
+	return context.WithValue(ctx, roleKey, role)
+}
+
+func Role(ctx context.Context) string {
+	if role, ok := ctx.Value(roleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context { //id запроса для сквозного поиска
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+		return requestID
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"subscriptions/requestctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	headerRequestID = "X-Request-ID"
+	headerUserID    = "X-User-ID"
+	headerUserRole  = "X-User-Role"
+)
+
+func RequestContext() gin.HandlerFunc { //кладет в контекст запроса автора изменений, его роль и id запроса. Для прав согласующего заголовкам верят только с TRUST_IDENTITY_HEADERS
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(headerRequestID)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Header(headerRequestID, requestID)
+
+		actor := c.GetHeader(headerUserID)
+		if actor == "" {
+			actor = "anonymous"
+		}
+
+		ctx := requestctx.WithRequestID(c.Request.Context(), requestID)
+		ctx = requestctx.WithActor(ctx, actor)
+		ctx = requestctx.WithRole(ctx, c.GetHeader(headerUserRole))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
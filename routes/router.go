@@ -6,6 +6,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Handlers - хендлеры всех ресурсов API
+type Handlers struct {
+	Service      *handlers.ServiceHandler
+	Subscription *handlers.SubscriptionHandler
+	Audit        *handlers.AuditHandler
+	Budget       *handlers.BudgetHandler
+	Team         *handlers.TeamHandler
+	Reconcile    *handlers.ReconcileHandler
+	Suggestion   *handlers.SuggestionHandler
+	Attachment   *handlers.AttachmentHandler
+	Report       *handlers.ReportHandler
+	CostCenter   *handlers.CostCenterHandler
+}
+
 // @Summary ping
 // @Schemes
 // @Description do ping
@@ -14,8 +28,9 @@ import (
 // @Produce json
 // @Success 200 {string} pong
 // @Router /ping [get]
-func SetupRouter(serviceHandler *handlers.ServiceHandler, subscriptionHandler *handlers.SubscriptionHandler) *gin.Engine {
+func SetupRouter(h Handlers) *gin.Engine {
 	r := gin.Default()
+	r.Use(RequestContext())
 	api := r.Group("/api")
 	{
 		api.GET("/ping", func(c *gin.Context) {
@@ -24,16 +39,59 @@ func SetupRouter(serviceHandler *handlers.ServiceHandler, subscriptionHandler *h
 			})
 		})
 
-		api.POST("/services", serviceHandler.Create)
-		api.GET("/services", serviceHandler.GetAll)
-		api.DELETE("/services/:id", serviceHandler.Delete)
+		api.POST("/services", h.Service.Create)
+		api.GET("/services", h.Service.GetAll)
+		api.PUT("/services/:id", h.Service.Update)
+		api.GET("/services/:id/stats", h.Service.Stats)
+		api.DELETE("/services/:id", h.Service.Delete)
+
+		api.POST("/subs", h.Subscription.Create)
+		api.GET("/subs", h.Subscription.GetAll)
+		api.GET("/subs/export", h.Subscription.Export)
+		api.PUT("/subs/:id", h.Subscription.Update)
+		api.DELETE("/subs/:id", h.Subscription.Delete)
+		api.GET("/subs/:id", h.Subscription.GetById)
+		api.POST("/subs/:id/restore", h.Subscription.Restore)
+		api.GET("/subs/:id/history", h.Subscription.History)
+		api.GET("/subs/:id/charges", h.Subscription.Charges)
+		api.POST("/subs/:id/attachments", h.Attachment.Upload)
+		api.GET("/subs/:id/attachments", h.Attachment.List)
+		api.GET("/subs/:id/attachments/:attachment_id", h.Attachment.Download)
+		api.DELETE("/subs/:id/attachments/:attachment_id", h.Attachment.Delete)
+		api.POST("/subs/:id/activate", h.Subscription.Activate)
+		api.POST("/subs/:id/pause", h.Subscription.Pause)
+		api.POST("/subs/:id/resume", h.Subscription.Resume)
+		api.POST("/subs/:id/cancel", h.Subscription.Cancel)
+		api.POST("/subs/:id/approve", h.Subscription.Approve)
+		api.POST("/subs/:id/reject", h.Subscription.Reject)
+		api.GET("/subs/sum", h.Subscription.SumByFilters)
+		api.GET("/subs/offers-ending", h.Subscription.OffersEnding)
+		api.GET("/subs/deadlines", h.Subscription.Deadlines)
+		api.GET("/subs/forecast", h.Subscription.Forecast)
+		api.POST("/subs/simulate", h.Subscription.Simulate)
+
+		api.GET("/approvals", h.Subscription.Approvals)
+
+		api.GET("/audit", h.Audit.List)
+
+		api.POST("/teams", h.Team.Create)
+		api.GET("/teams", h.Team.GetAll)
+
+		api.POST("/budgets", h.Budget.Create)
+		api.GET("/budgets", h.Budget.List)
+		api.GET("/budgets/:id", h.Budget.GetById)
+		api.DELETE("/budgets/:id", h.Budget.Delete)
+
+		api.POST("/reconcile", h.Reconcile.Reconcile)
+		api.GET("/suggestions", h.Suggestion.List)
+		api.POST("/suggestions/accept", h.Suggestion.Accept)
+
+		api.POST("/cost-centers", h.CostCenter.Create)
+		api.GET("/cost-centers", h.CostCenter.GetAll)
 
-		api.POST("/subs", subscriptionHandler.Create)
-		api.GET("/subs", subscriptionHandler.GetAll)
-		api.PUT("/subs/:id", subscriptionHandler.Update)
-		api.DELETE("/subs/:id", subscriptionHandler.Delete)
-		api.GET("/subs/:id", subscriptionHandler.GetById)
-		api.GET("/subs/sum", subscriptionHandler.SumByFilters)
+		api.GET("/reports/spend", h.Report.Spend)
+		api.GET("/reports/chargeback", h.Report.Chargeback)
+		api.GET("/reports/churn", h.Report.Churn)
 
 	}
 
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"subscriptions/models"
+)
+
+var ErrInvalidAllocations = errors.New("invalid allocations: cost centers must exist and be listed once, percents must add up to 100")
+
+const allocationTolerance = 0.01 //погрешность суммы долей в процентах, например 33.33 + 33.33 + 33.34
+
+// buildAllocations проверяет распределение подписки по центрам затрат: центры существуют, не повторяются,
+// а доли в сумме дают 100%. Пустой список - подписка не распределена
+func (s *SubscriptionService) buildAllocations(ctx context.Context, inputs []models.AllocationInput) ([]models.CostAllocation, error) {
+	allocations := make([]models.CostAllocation, 0, len(inputs))
+	if len(inputs) == 0 {
+		return allocations, nil
+	}
+
+	ids := make([]uint, 0, len(inputs))
+	seen := map[uint]bool{}
+	total := 0.0
+	for _, input := range inputs {
+		if seen[input.CostCenterID] {
+			return nil, ErrInvalidAllocations
+		}
+		seen[input.CostCenterID] = true
+		ids = append(ids, input.CostCenterID)
+		total += input.Percent
+		allocations = append(allocations, models.CostAllocation{CostCenterID: input.CostCenterID, Percent: input.Percent})
+	}
+	if math.Abs(total-100) > allocationTolerance {
+		return nil, ErrInvalidAllocations
+	}
+
+	centers, err := s.costcenters.GetByIds(ctx, ids)
+	if err != nil {
+		s.logger.Errorf("GetByIds cost centers failed: %v", err)
+		return nil, err
+	}
+	if len(centers) != len(ids) {
+		return nil, ErrInvalidAllocations
+	}
+	return allocations, nil
+}
+
+// allocate делит сумму пропорционально долям так, чтобы части в сумме давали ровно amount:
+// копейки, потерянные при округлении вниз, достаются долям с наибольшим остатком
+func allocate(amount int64, percents []float64) []int64 {
+	total := 0.0
+	for _, percent := range percents {
+		total += percent //может отличаться от 100 в пределах погрешности
+	}
+	parts := make([]int64, len(percents))
+	remainders := make([]float64, len(percents))
+	var allocated int64
+	for i, percent := range percents {
+		exact := float64(amount) * percent / total
+		parts[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(parts[i])
+		allocated += parts[i]
+	}
+
+	order := make([]int, len(percents))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; allocated < amount && len(order) > 0; i = (i + 1) % len(order) {
+		parts[order[i]]++
+		allocated++
+	}
+	return parts
+}
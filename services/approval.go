@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"subscriptions/events"
+	"subscriptions/models"
+	"subscriptions/requestctx"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+)
+
+const defaultApproverRole = "approver"
+
+var ErrApproverRequired = errors.New("approver role required")
+
+// ApprovalConfig - режим согласования: новые подписки ждут одобрения согласующего. Согласующие задаются на сервере
+// списком Approvers или ролью ApproverRole, но заголовкам X-User-ID и X-User-Role сервис верит, только если TrustIdentityHeaders:
+// их ставит прокси авторизации перед сервисом и удаляет из запросов клиентов. Без него одобрять подписки не может никто
+type ApprovalConfig struct {
+	Required             bool
+	Approvers            []string //id пользователей из заголовка X-User-ID
+	ApproverRole         string   //роль из заголовка X-User-Role
+	TrustIdentityHeaders bool
+}
+
+func ApprovalConfigFromEnv(logger *zap.SugaredLogger) ApprovalConfig { //настройки согласования из переменных окружения
+	cfg := ApprovalConfig{ApproverRole: defaultApproverRole}
+
+	if value := os.Getenv("APPROVAL_REQUIRED"); value != "" {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			logger.Warnf("Некорректное значение APPROVAL_REQUIRED: %v", err)
+		} else {
+			cfg.Required = required
+		}
+	}
+
+	for _, approver := range strings.Split(os.Getenv("APPROVERS"), ",") {
+		if approver = strings.TrimSpace(approver); approver != "" {
+			cfg.Approvers = append(cfg.Approvers, approver)
+		}
+	}
+
+	if value := os.Getenv("APPROVER_ROLE"); value != "" {
+		cfg.ApproverRole = value
+	}
+
+	if value := os.Getenv("TRUST_IDENTITY_HEADERS"); value != "" {
+		trust, err := strconv.ParseBool(value)
+		if err != nil {
+			logger.Warnf("Некорректное значение TRUST_IDENTITY_HEADERS: %v", err)
+		} else {
+			cfg.TrustIdentityHeaders = trust
+		}
+	}
+
+	if cfg.Required && !cfg.TrustIdentityHeaders {
+		logger.Warn("Согласование включено, но заголовкам X-User-ID и X-User-Role нет доверия: одобрять подписки никто не сможет, задайте TRUST_IDENTITY_HEADERS за прокси авторизации")
+	}
+	return cfg
+}
+
+// Approvals - подписки, ожидающие одобрения, доступны только согласующему
+func (s *SubscriptionService) Approvals(ctx context.Context, filter *models.ApprovalFilter) ([]models.Subscription, error) {
+	if !s.isApprover(ctx) {
+		s.logger.Warnf("Approvals denied for %s", requestctx.Actor(ctx))
+		return nil, ErrApproverRequired
+	}
+	status := models.StatusPendingApproval
+	res, err := s.subsrepo.GetAll(ctx, &models.ListFilter{UserID: filter.UserID, Status: &status})
+	if err != nil {
+		s.logger.Errorf("GetAll subscriptions failed: %v", err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// isApprover - за доверенным прокси автор запроса есть в списке согласующих или у него роль согласующего
+func (s *SubscriptionService) isApprover(ctx context.Context) bool {
+	if !s.approval.TrustIdentityHeaders { //без прокси X-User-ID и X-User-Role ставит сам клиент
+		return false
+	}
+	actor := requestctx.Actor(ctx)
+	for _, approver := range s.approval.Approvers {
+		if actor == approver {
+			return true
+		}
+	}
+	return s.approval.ApproverRole != "" && requestctx.Role(ctx) == s.approval.ApproverRole
+}
+
+// publishTransition сообщает о смене статуса подписки, в том числе о начальном статусе ожидания одобрения
+func (s *SubscriptionService) publishTransition(ctx context.Context, eventType string, sub *models.Subscription, transition *models.StatusTransition) {
+	s.publisher.Publish(ctx, events.Event{
+		Type:    eventType,
+		Payload: map[string]interface{}{"subscription_id": sub.ID, "user_id": sub.UserID, "transition": transition},
+	})
+}
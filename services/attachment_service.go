@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/storage"
+
+	"go.uber.org/zap"
+)
+
+const MaxAttachmentSize = 10 << 20 //10 МБ
+
+var (
+	ErrAttachmentTooLarge    = errors.New("attachment is too large")
+	ErrUnsupportedAttachment = errors.New("unsupported attachment type, expected PDF or image")
+)
+
+// типы файлов, которые можно приложить, и расширение для ключа в хранилище
+var attachmentTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+}
+
+type AttachmentServiceInterface interface {
+	Upload(ctx context.Context, subscriptionID uint, fileName string, data []byte) (*models.Attachment, error)
+	List(ctx context.Context, subscriptionID uint) ([]models.Attachment, error)
+	Download(ctx context.Context, subscriptionID, id uint) (*models.Attachment, io.ReadCloser, error)
+	Delete(ctx context.Context, subscriptionID, id uint) error
+}
+
+type AttachmentService struct {
+	repo     repository.AttachmentRepoInterface
+	subsrepo repository.SubscriptionRepoInterface
+	store    storage.BlobStore
+	audit    AuditServiceInterface
+	logger   *zap.SugaredLogger
+}
+
+func NewAttachmentService(repo repository.AttachmentRepoInterface, subsrepo repository.SubscriptionRepoInterface, store storage.BlobStore, audit AuditServiceInterface, logger *zap.SugaredLogger) AttachmentServiceInterface {
+	return &AttachmentService{repo: repo, subsrepo: subsrepo, store: store, audit: audit, logger: logger}
+}
+
+// Upload сохраняет файл в хранилище и запись о нем в базе. Тип определяется по содержимому, а не по имени файла
+func (s *AttachmentService) Upload(ctx context.Context, subscriptionID uint, fileName string, data []byte) (*models.Attachment, error) {
+	if _, err := s.subsrepo.GetById(ctx, subscriptionID); err != nil {
+		s.logger.Errorf("GetById subscription failed: %v", err)
+		return nil, err
+	}
+	if len(data) > MaxAttachmentSize {
+		s.logger.Warnf("Attachment %s for subscription %d is too large: %d bytes", fileName, subscriptionID, len(data))
+		return nil, ErrAttachmentTooLarge
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	extension, ok := attachmentTypes[contentType]
+	if !ok {
+		s.logger.Warnf("Attachment %s for subscription %d has unsupported type %s", fileName, subscriptionID, contentType)
+		return nil, ErrUnsupportedAttachment
+	}
+
+	key, err := attachmentKey(subscriptionID, extension)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.store.Put(ctx, key, data, contentType); err != nil {
+		s.logger.Errorf("Put attachment failed: %v", err)
+		return nil, err
+	}
+
+	attachment := &models.Attachment{SubscriptionID: subscriptionID, FileName: filepath.Base(fileName), ContentType: contentType, Size: int64(len(data)), StorageKey: key}
+	if err = s.repo.Create(ctx, attachment); err != nil {
+		s.logger.Errorf("Create attachment failed: %v", err)
+		if err := s.store.Delete(ctx, key); err != nil { //файл без записи никто не найдет
+			s.logger.Errorf("Delete orphaned attachment %s failed: %v", key, err)
+		}
+		return nil, err
+	}
+	s.audit.Record(ctx, models.AuditEntityAttachment, attachment.ID, models.AuditActionCreate, nil, attachment)
+	return attachment, nil
+}
+
+func (s *AttachmentService) List(ctx context.Context, subscriptionID uint) ([]models.Attachment, error) {
+	if _, err := s.subsrepo.GetById(ctx, subscriptionID); err != nil {
+		s.logger.Errorf("GetById subscription failed: %v", err)
+		return nil, err
+	}
+	attachments, err := s.repo.List(ctx, subscriptionID)
+	if err != nil {
+		s.logger.Errorf("List attachments failed: %v", err)
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// Download возвращает описание вложения и его содержимое, закрыть его должен вызывающий
+func (s *AttachmentService) Download(ctx context.Context, subscriptionID, id uint) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.repo.GetById(ctx, subscriptionID, id)
+	if err != nil {
+		s.logger.Errorf("GetById attachment failed: %v", err)
+		return nil, nil, err
+	}
+	content, err := s.store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		s.logger.Errorf("Get attachment %s failed: %v", attachment.StorageKey, err)
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+func (s *AttachmentService) Delete(ctx context.Context, subscriptionID, id uint) error {
+	attachment, err := s.repo.GetById(ctx, subscriptionID, id)
+	if err != nil {
+		s.logger.Errorf("GetById attachment failed: %v", err)
+		return err
+	}
+	if err = s.repo.Delete(ctx, id); err != nil {
+		s.logger.Errorf("Delete attachment failed: %v", err)
+		return err
+	}
+	if err = s.store.Delete(ctx, attachment.StorageKey); err != nil { //запись уже удалена, файл останется лишь в хранилище
+		s.logger.Errorf("Delete attachment %s from storage failed: %v", attachment.StorageKey, err)
+	}
+	s.audit.Record(ctx, models.AuditEntityAttachment, id, models.AuditActionDelete, attachment, nil)
+	return nil
+}
+
+func attachmentKey(subscriptionID uint, extension string) (string, error) { //случайное имя, чтобы файлы не перезаписывали друг друга
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("subscriptions/%d/%s%s", subscriptionID, hex.EncodeToString(random), extension), nil
+}
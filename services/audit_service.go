@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/requestctx"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditPageSize = 50
+	auditDateLayout      = "2006-01-02"
+)
+
+var ErrInvalidAuditFilter = errors.New("invalid date in audit filter, expected YYYY-MM-DD")
+
+type AuditServiceInterface interface {
+	Record(ctx context.Context, entity string, entityID uint, action string, before, after interface{})
+	List(ctx context.Context, filter *models.AuditFilter) (*models.AuditPage, error)
+}
+
+type AuditService struct {
+	repo   repository.AuditRepoInterface
+	logger *zap.SugaredLogger
+}
+
+func NewAuditService(repo repository.AuditRepoInterface, logger *zap.SugaredLogger) AuditServiceInterface {
+	return &AuditService{repo: repo, logger: logger}
+}
+
+// Record сохраняет запись об изменении. Ошибка журнала не отменяет уже выполненное изменение, поэтому только логируется
+func (s *AuditService) Record(ctx context.Context, entity string, entityID uint, action string, before, after interface{}) {
+	beforeJSON, err := marshalAudit(before)
+	if err != nil {
+		s.logger.Errorf("Audit marshal failed: %v", err)
+		return
+	}
+	afterJSON, err := marshalAudit(after)
+	if err != nil {
+		s.logger.Errorf("Audit marshal failed: %v", err)
+		return
+	}
+	diff, err := auditDiff(beforeJSON, afterJSON)
+	if err != nil {
+		s.logger.Errorf("Audit diff failed: %v", err)
+		return
+	}
+
+	entry := &models.AuditEntry{
+		Actor:     requestctx.Actor(ctx),
+		RequestID: requestctx.RequestID(ctx),
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		Before:    beforeJSON,
+		After:     afterJSON,
+		Diff:      diff,
+	}
+	if err := s.repo.Create(ctx, entry); err != nil {
+		s.logger.Errorf("Create audit entry failed: %v", err)
+	}
+}
+
+func (s *AuditService) List(ctx context.Context, filter *models.AuditFilter) (*models.AuditPage, error) {
+	if filter == nil {
+		filter = &models.AuditFilter{}
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultAuditPageSize
+	}
+
+	var from, to *time.Time
+	if filter.From != nil {
+		parsed, err := time.Parse(auditDateLayout, *filter.From)
+		if err != nil {
+			s.logger.Errorf("Parsing from date failed: %v", err)
+			return nil, ErrInvalidAuditFilter
+		}
+		from = &parsed
+	}
+	if filter.To != nil {
+		parsed, err := time.Parse(auditDateLayout, *filter.To)
+		if err != nil {
+			s.logger.Errorf("Parsing to date failed: %v", err)
+			return nil, ErrInvalidAuditFilter
+		}
+		parsed = parsed.AddDate(0, 0, 1) //включаем весь день
+		to = &parsed
+	}
+
+	entries, total, err := s.repo.Find(ctx, filter, from, to)
+	if err != nil {
+		s.logger.Errorf("Find audit entries failed: %v", err)
+		return nil, err
+	}
+	return &models.AuditPage{Items: entries, Total: total, Page: filter.Page, PageSize: filter.PageSize}, nil
+}
+
+func marshalAudit(value interface{}) (models.JSON, error) {
+	if value == nil || (reflect.ValueOf(value).Kind() == reflect.Ptr && reflect.ValueOf(value).IsNil()) {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return models.JSON(data), nil
+}
+
+func auditDiff(before, after models.JSON) (models.JSON, error) { //разница по полям верхнего уровня
+	beforeFields := map[string]interface{}{}
+	afterFields := map[string]interface{}{}
+	if len(before) > 0 {
+		if err := json.Unmarshal(before, &beforeFields); err != nil {
+			return nil, err
+		}
+	}
+	if len(after) > 0 {
+		if err := json.Unmarshal(after, &afterFields); err != nil {
+			return nil, err
+		}
+	}
+
+	diff := map[string]map[string]interface{}{}
+	for key, value := range afterFields {
+		if old, ok := beforeFields[key]; !ok || !reflect.DeepEqual(old, value) {
+			diff[key] = map[string]interface{}{"from": beforeFields[key], "to": value}
+		}
+	}
+	for key, old := range beforeFields {
+		if _, ok := afterFields[key]; !ok {
+			diff[key] = map[string]interface{}{"from": old, "to": nil}
+		}
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		return nil, err
+	}
+	return models.JSON(data), nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"subscriptions/billing"
+	"subscriptions/events"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type BudgetServiceInterface interface {
+	Create(ctx context.Context, budget *models.CreateBudget) (*models.Budget, error)
+	List(ctx context.Context, filter *models.BudgetFilter) ([]models.BudgetReport, error)
+	GetReport(ctx context.Context, id uint) (*models.BudgetReport, error)
+	Delete(ctx context.Context, id uint) error
+	CheckSubscription(ctx context.Context, before, sub *models.Subscription) []string
+}
+
+type BudgetService struct {
+	budgetrepo repository.BudgetRepoInterface
+	teamrepo   repository.TeamRepoInterface
+	subsrepo   repository.SubscriptionRepoInterface
+	publisher  events.Publisher
+	logger     *zap.SugaredLogger
+}
+
+func NewBudgetService(budgetrepo repository.BudgetRepoInterface, teamrepo repository.TeamRepoInterface, subsrepo repository.SubscriptionRepoInterface, publisher events.Publisher, logger *zap.SugaredLogger) BudgetServiceInterface {
+	return &BudgetService{budgetrepo: budgetrepo, teamrepo: teamrepo, subsrepo: subsrepo, publisher: publisher, logger: logger}
+}
+
+func (s *BudgetService) Create(ctx context.Context, budget *models.CreateBudget) (*models.Budget, error) {
+	limit := models.MinorOrLegacy(budget.LimitMinor, budget.Limit)
+	if limit == nil {
+		s.logger.Error(ErrInvalidPrice)
+		return nil, ErrInvalidPrice
+	}
+	newBudget := &models.Budget{UserID: budget.UserID, TeamID: budget.TeamID, Category: budget.Category, Limit: *limit, Currency: models.DefaultCurrency, CostBasis: models.CostBasisPayer}
+	if budget.Currency != nil {
+		newBudget.Currency = *budget.Currency
+	}
+	if budget.CostBasis != nil {
+		newBudget.CostBasis = *budget.CostBasis
+	}
+	if budget.TeamID != nil {
+		if _, err := s.teamrepo.GetById(ctx, *budget.TeamID); err != nil { //команда должна существовать
+			s.logger.Errorf("GetById team failed: %v", err)
+			return nil, err
+		}
+	}
+
+	s.logger.Infof("Create budget: %+v", newBudget)
+	if err := s.budgetrepo.Create(ctx, newBudget); err != nil {
+		s.logger.Errorf("Create budget failed: %v", err)
+		return nil, err
+	}
+	return newBudget, nil
+}
+
+func (s *BudgetService) List(ctx context.Context, filter *models.BudgetFilter) ([]models.BudgetReport, error) {
+	budgets, err := s.budgetrepo.GetAll(ctx, filter)
+	if err != nil {
+		s.logger.Errorf("GetAll budgets failed: %v", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	reports := make([]models.BudgetReport, 0, len(budgets))
+	for i := range budgets {
+		report, err := s.report(ctx, &budgets[i], now, nil)
+		if err != nil {
+			s.logger.Errorf("Budget report failed: %v", err)
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+func (s *BudgetService) GetReport(ctx context.Context, id uint) (*models.BudgetReport, error) {
+	budget, err := s.budgetrepo.GetById(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetById budget failed: %v", err)
+		return nil, err
+	}
+	report, err := s.report(ctx, budget, time.Now(), nil)
+	if err != nil {
+		s.logger.Errorf("Budget report failed: %v", err)
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *BudgetService) Delete(ctx context.Context, id uint) error {
+	err := s.budgetrepo.Delete(ctx, id)
+	if err != nil {
+		s.logger.Errorf("Delete budget failed: %v", err)
+		return err
+	}
+	return nil
+}
+
+// CheckSubscription проверяет бюджеты плательщика, участников и их команд после изменения подписки, before - подписка
+// до изменения, nil - новая. Предупреждение и событие появляются только для бюджетов, которые превысило именно это
+// изменение: прогноз месяца без него укладывался в лимит, а с ним - нет. Ошибки проверки не мешают изменению
+func (s *BudgetService) CheckSubscription(ctx context.Context, before, sub *models.Subscription) []string {
+	userIDs := []string{sub.UserID}
+	for _, member := range sub.Members {
+		userIDs = append(userIDs, member.UserID)
+	}
+
+	var teamIDs []uint
+	for _, userID := range userIDs {
+		teams, err := s.teamrepo.GetByUser(ctx, userID)
+		if err != nil {
+			s.logger.Errorf("GetByUser teams failed: %v", err)
+			return nil
+		}
+		for _, team := range teams {
+			teamIDs = append(teamIDs, team.ID)
+		}
+	}
+
+	budgets, err := s.budgetrepo.FindForOwners(ctx, userIDs, teamIDs)
+	if err != nil {
+		s.logger.Errorf("FindForOwners budgets failed: %v", err)
+		return nil
+	}
+
+	now := time.Now()
+	var warnings []string
+	for i := range budgets {
+		budget := &budgets[i]
+		if budget.Category != nil && (sub.Service.Category == nil || *sub.Service.Category != *budget.Category) {
+			continue //подписка не относится к категории бюджета
+		}
+		if budget.Currency != "" && sub.Currency != "" && budget.Currency != sub.Currency {
+			continue //другая валюта в лимит не входит
+		}
+
+		counted := map[uint]int64{}
+		report, err := s.report(ctx, budget, now, counted)
+		if err != nil {
+			s.logger.Errorf("Budget report failed: %v", err)
+			continue
+		}
+		withoutChange := report.Projected - counted[sub.ID] + spendOf(budget, before, report.Month)
+		if !report.OverBudget || withoutChange > budget.Limit { //не превышен или был превышен и до изменения
+			continue
+		}
+
+		warnings = append(warnings, fmt.Sprintf("budget %d exceeded: projected spend %d of limit %d %s (minor units) for %s", budget.ID, report.Projected, budget.Limit, budget.Currency, report.Month.Format("01-2006")))
+		s.publisher.Publish(ctx, events.Event{
+			Type:    events.TypeBudgetExceeded,
+			Payload: map[string]interface{}{"budget": report, "subscription_id": sub.ID},
+		})
+	}
+	return warnings
+}
+
+// report считает расходы владельцев бюджета за текущий месяц по журналу начислений, как и сумма подписок.
+// Если bySubscription не nil, в него складывается вклад каждой подписки в прогноз
+func (s *BudgetService) report(ctx context.Context, budget *models.Budget, now time.Time, bySubscription map[uint]int64) (*models.BudgetReport, error) {
+	month := billing.MonthStart(now)
+	byShare := budget.CostBasis == models.CostBasisShare
+	userIDs := budgetUsers(budget)
+
+	var currency *string
+	if budget.Currency != "" {
+		currency = &budget.Currency
+	}
+
+	report := &models.BudgetReport{Budget: *budget, Month: month}
+	counted := map[uint]bool{} //при оплате плательщиком подписка считается один раз
+	for _, userID := range userIDs {
+		userID := userID
+		subs, err := s.subsrepo.FindForSum(ctx, &repository.SubscriptionQuery{
+			UserID:      &userID,
+			Category:    budget.Category,
+			Currency:    currency,
+			Start:       &month,
+			End:         &month,
+			WithShared:  byShare,
+			WithCharges: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range subs {
+			if !byShare && counted[subs[i].ID] {
+				continue
+			}
+			counted[subs[i].ID] = true
+
+			input := subs[i].Billing()
+			for _, charge := range subs[i].Charges {
+				amount := charge.Amount
+				if byShare {
+					amount = billing.ShareOf(input, userID, amount)
+				}
+				report.Projected += amount
+				if bySubscription != nil {
+					bySubscription[subs[i].ID] += amount
+				}
+				report.Taxes.Add(models.TaxTotals(billing.SplitTax(input, amount)))
+				if !charge.Date.After(now) {
+					report.Current += amount
+				}
+			}
+		}
+	}
+
+	report.Remaining = budget.Limit - report.Projected
+	report.OverBudget = report.Projected > budget.Limit
+	return report, nil
+}
+
+func budgetUsers(budget *models.Budget) []string { //владелец бюджета или участники его команды
+	var userIDs []string
+	if budget.UserID != nil {
+		userIDs = append(userIDs, *budget.UserID)
+	}
+	if budget.Team != nil {
+		for _, member := range budget.Team.Members {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+	return userIDs
+}
+
+// spendOf считает вклад подписки в прогноз бюджета за месяц по тем же правилам, что и report, но по подписке в памяти.
+// Так оценивается прогноз до изменения, когда в журнале уже лежат новые начисления
+func spendOf(budget *models.Budget, sub *models.Subscription, month time.Time) int64 {
+	if sub == nil || sub.Status == models.StatusPendingApproval {
+		return 0
+	}
+	if budget.Category != nil && (sub.Service.Category == nil || *sub.Service.Category != *budget.Category) {
+		return 0
+	}
+	if budget.Currency != "" && sub.Currency != "" && budget.Currency != sub.Currency {
+		return 0
+	}
+
+	var total int64
+	input := sub.Billing()
+	for _, charge := range billing.Charges(input, month, month) {
+		for _, userID := range budgetUsers(budget) {
+			if budget.CostBasis == models.CostBasisShare {
+				total += billing.ShareOf(input, userID, charge.Amount)
+			} else if sub.UserID == userID {
+				total += charge.Amount
+				break //при оплате плательщиком подписка считается один раз
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"subscriptions/models"
+	"subscriptions/repository"
+
+	"go.uber.org/zap"
+)
+
+type CostCenterServiceInterface interface {
+	Create(ctx context.Context, center *models.CreateCostCenter) (*models.CostCenter, error)
+	GetAll(ctx context.Context) ([]models.CostCenter, error)
+}
+
+type CostCenterService struct {
+	repo   repository.CostCenterRepoInterface
+	logger *zap.SugaredLogger
+}
+
+func NewCostCenterService(repo repository.CostCenterRepoInterface, logger *zap.SugaredLogger) CostCenterServiceInterface {
+	return &CostCenterService{repo: repo, logger: logger}
+}
+
+func (s *CostCenterService) Create(ctx context.Context, center *models.CreateCostCenter) (*models.CostCenter, error) {
+	newCenter := &models.CostCenter{Code: strings.TrimSpace(center.Code), Name: strings.TrimSpace(center.Name)}
+	s.logger.Infof("Create cost center: %s", newCenter.Code)
+	if err := s.repo.Create(ctx, newCenter); err != nil {
+		s.logger.Errorf("Create cost center failed: %v", err)
+		return nil, err
+	}
+	return newCenter, nil
+}
+
+func (s *CostCenterService) GetAll(ctx context.Context) ([]models.CostCenter, error) {
+	res, err := s.repo.GetAll(ctx)
+	if err != nil {
+		s.logger.Errorf("GetAll cost centers failed: %v", err)
+		return nil, err
+	}
+	return res, nil
+}
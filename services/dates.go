@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"subscriptions/billing"
+	"subscriptions/models"
+	"time"
+)
+
+var ErrInvalidDateFormat = errors.New("invalid date, expected YYYY-MM-DD or MM-YYYY")
+
+// parseDate разбирает дату в формате YYYY-MM-DD или старом MM-YYYY (тогда берется первое число месяца).
+// dayLevel сообщает, был ли указан день
+func parseDate(value string) (date time.Time, dayLevel bool, err error) {
+	if date, err = time.Parse("2006-01-02", value); err == nil {
+		return date, true, nil
+	}
+	if date, err = time.Parse("01-2006", value); err == nil {
+		return date, false, nil
+	}
+	return time.Time{}, false, ErrInvalidDateFormat
+}
+
+// parseMonth разбирает месяц периода: для даты с днем берется ее месяц
+func parseMonth(value string) (time.Time, error) {
+	date, _, err := parseDate(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return billing.MonthStart(date), nil
+}
+
+// parseEndDate разбирает дату окончания подписки. Для MM-YYYY месяц оплачивается целиком
+func parseEndDate(value string, sub *models.Subscription) (time.Time, error) {
+	date, dayLevel, err := parseDate(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !dayLevel {
+		date = billing.PaidThrough(sub.Billing(), date)
+	}
+	return date, nil
+}
+
+// checkEndDate - общая проверка даты окончания при создании, обновлении и отмене подписки
+func checkEndDate(sub *models.Subscription, end time.Time) error {
+	if end.Before(sub.StartDate) { //конец не должен быть раньше начала
+		return fmt.Errorf("%w: end date must be after start date", ErrInvalidDate)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"subscriptions/billing"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"time"
+)
+
+const defaultForecastMonths = 12
+
+var ErrInvalidSimulation = errors.New("invalid simulation change")
+
+// Forecast прогнозирует расходы на months месяцев начиная с текущего по тем же правилам, что и сумма:
+// учитываются даты окончания, пробные периоды, окончание вводной цены и паузы, которые никто не снимет
+func (s *SubscriptionService) Forecast(ctx context.Context, filter *models.ForecastFilter) (*models.Forecast, error) {
+	from, to := forecastPeriod(filter.Months)
+	subs, err := s.findForForecast(ctx, filter, from, to)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := sumCurrency(subs, filter.Currency)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+	return buildForecast(subs, forecastUser(filter), currency, from, to), nil
+}
+
+// Simulate строит прогноз до и после гипотетических изменений. Изменения применяются к копиям подписок в памяти,
+// в базу ничего не пишется
+func (s *SubscriptionService) Simulate(ctx context.Context, request *models.SimulationRequest) (*models.SimulationResult, error) {
+	filter := &models.ForecastFilter{UserID: request.UserID, Months: request.Months, CostBasis: request.CostBasis, Currency: request.Currency}
+	from, to := forecastPeriod(filter.Months)
+
+	subs, err := s.findForForecast(ctx, filter, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err := sumCurrency(subs, filter.Currency)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	changed, err := applyChanges(subs, request, currency, from, to)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	userID := forecastUser(filter)
+	before := buildForecast(subs, userID, currency, from, to)
+	after := buildForecast(changed, userID, currency, from, to)
+	return &models.SimulationResult{Before: *before, After: *after, Savings: before.Total - after.Total}, nil
+}
+
+func forecastPeriod(months int) (time.Time, time.Time) {
+	if months <= 0 {
+		months = defaultForecastMonths
+	}
+	from := billing.MonthStart(time.Now())
+	return from, from.AddDate(0, months-1, 0)
+}
+
+// forecastUser возвращает пользователя, чью долю нужно считать, или nil, если считаем полную стоимость
+func forecastUser(filter *models.ForecastFilter) *string {
+	if filter.CostBasis != nil && *filter.CostBasis == models.CostBasisShare && filter.UserID != nil {
+		return filter.UserID
+	}
+	return nil
+}
+
+func (s *SubscriptionService) findForForecast(ctx context.Context, filter *models.ForecastFilter, from, to time.Time) ([]models.Subscription, error) {
+	subs, err := s.subsrepo.FindForSum(ctx, &repository.SubscriptionQuery{
+		UserID:      filter.UserID,
+		Start:       &from,
+		End:         &to,
+		Currency:    filter.Currency,
+		WithShared:  forecastUser(filter) != nil,
+		WithCharges: true,
+	})
+	if err != nil {
+		s.logger.Errorf("FindForSum failed: %v", err)
+		return nil, err
+	}
+	return subs, nil
+}
+
+// buildForecast раскладывает начисления подписок из журнала по месяцам периода [from, to] и считает накопленный итог
+func buildForecast(subs []models.Subscription, userID *string, currency string, from, to time.Time) *models.Forecast {
+	totals := map[time.Time]*models.ForecastMonth{}
+	for i := range subs {
+		terms := subs[i].Billing()
+		for j := range subs[i].Charges {
+			charge := &subs[i].Charges[j]
+			total, ok := totals[charge.Month]
+			if !ok {
+				total = &models.ForecastMonth{Month: charge.Month}
+				totals[charge.Month] = total
+			}
+			if charge.Explanation != "" {
+				total.Prorations = append(total.Prorations, fmt.Sprintf("subscription %d: %s", subs[i].ID, charge.Explanation))
+			}
+			amount, taxes := chargeTotals(terms, charge, userID)
+			total.Amount += amount
+			total.Taxes.Add(taxes)
+		}
+	}
+
+	forecast := &models.Forecast{From: from, To: to, Currency: currency, Months: []models.ForecastMonth{}}
+	for month := from; !month.After(to); month = month.AddDate(0, 1, 0) {
+		item := models.ForecastMonth{Month: month}
+		if total, ok := totals[month]; ok {
+			item = *total
+		}
+		forecast.Total += item.Amount
+		forecast.Taxes.Add(item.Taxes)
+		item.Cumulative = forecast.Total
+		forecast.Months = append(forecast.Months, item)
+	}
+	return forecast
+}
+
+// applyChanges возвращает копию списка подписок с примененными изменениями:
+// cancel - месяц становится последним оплаченным, как при отмене подписки;
+// switch - подписка заканчивается в предыдущем месяце, а с указанного продолжается с новой ценой или периодом оплаты;
+// add - новая подписка с указанного месяца.
+// Начисления измененных подписок за [from, to] строятся заново по новым условиям, остальные берутся из журнала
+func applyChanges(subs []models.Subscription, request *models.SimulationRequest, currency string, from, to time.Time) ([]models.Subscription, error) {
+	res := make([]models.Subscription, len(subs))
+	copy(res, subs)
+
+	for i, change := range request.Changes {
+		month := from
+		if change.Month != nil {
+			parsed, err := parseMonth(*change.Month)
+			if err != nil {
+				return nil, fmt.Errorf("%w: change %d: month must be MM-YYYY or YYYY-MM-DD", ErrInvalidSimulation, i)
+			}
+			month = parsed
+		}
+
+		if change.Action == models.SimulationAdd {
+			price := models.MinorOrLegacy(change.PriceMinor, change.Price)
+			if price == nil {
+				return nil, fmt.Errorf("%w: change %d: price_minor is required", ErrInvalidSimulation, i)
+			}
+			sub := models.Subscription{Price: *price, Currency: currency, StartDate: month, Status: models.StatusActive, BillingPeriod: models.BillingMonthly}
+			sub.Service.Name = change.ServiceName
+			if request.UserID != nil {
+				sub.UserID = *request.UserID
+			}
+			if change.BillingPeriod != nil {
+				sub.BillingPeriod = *change.BillingPeriod
+			}
+			sub.Charges = ledgerBetween(&sub, from, to)
+			res = append(res, sub)
+			continue
+		}
+
+		if change.SubscriptionID == nil {
+			return nil, fmt.Errorf("%w: change %d: subscription_id is required", ErrInvalidSimulation, i)
+		}
+		idx := -1
+		for j := range res {
+			if res[j].ID == *change.SubscriptionID {
+				idx = j //после switch берем последнюю часть подписки
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: change %d: subscription %d is not in the forecast", ErrInvalidSimulation, i, *change.SubscriptionID)
+		}
+		sub := res[idx]
+		if sub.EndDate != nil && sub.EndDate.Before(month) { //подписка закончится раньше изменения
+			continue
+		}
+
+		switch change.Action {
+		case models.SimulationCancel: //с month подписка уже не оплачивается, последний оплаченный - предыдущий месяц
+			end := billing.PaidThrough(sub.Billing(), month.AddDate(0, -1, 0))
+			res[idx].EndDate = &end //отмена до начала: конец раньше начала, начислений нет
+			res[idx].Charges = ledgerBetween(&res[idx], from, to)
+		case models.SimulationSwitch:
+			price := models.MinorOrLegacy(change.PriceMinor, change.Price)
+			if price == nil && change.BillingPeriod == nil {
+				return nil, fmt.Errorf("%w: change %d: price_minor or billing_period is required", ErrInvalidSimulation, i)
+			}
+			switched := sub
+			switched.StartDate = month
+			switched.AnchorDay = uint8(billing.AnchorDay(sub.Billing())) //день списания не меняется
+			switched.PromoPrice, switched.PromoEndDate = nil, nil        //новый тариф без вводной цены
+			if price != nil {
+				switched.Price = *price
+				switched.Versions = nil //история старой цены не относится к новому тарифу
+			}
+			if change.BillingPeriod != nil {
+				switched.BillingPeriod = *change.BillingPeriod
+			}
+			switched.Charges = ledgerBetween(&switched, from, to)
+
+			if month.After(billing.MonthStart(sub.StartDate)) {
+				end := billing.PaidThrough(sub.Billing(), month.AddDate(0, -1, 0))
+				res[idx].EndDate = &end
+				res[idx].Charges = ledgerBetween(&res[idx], from, to)
+				res = append(res, switched)
+			} else {
+				res[idx] = switched //тариф меняется с самого начала
+			}
+		}
+	}
+	return res, nil
+}
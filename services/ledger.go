@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"subscriptions/billing"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"time"
+)
+
+const ledgerHorizonMonths = 120 //на сколько месяцев вперед журнал хранит будущие начисления, как самый длинный прогноз
+
+const rebuildBatchSize = 500 //сколько id подписок пересборка журнала читает за раз
+
+// ledgerHorizon - последний месяц, до которого строится журнал начислений
+func ledgerHorizon(now time.Time) time.Time {
+	return billing.MonthStart(now).AddDate(0, ledgerHorizonMonths, 0)
+}
+
+// currentLedger строит журнал начислений подписки до текущего горизонта. Репозиторий вызывает ее внутри транзакции
+// изменения подписки, поэтому ошибка записи журнала отменяет и само изменение
+func currentLedger() repository.LedgerFunc {
+	horizon := ledgerHorizon(time.Now())
+	return func(sub *models.Subscription) []models.Charge {
+		return LedgerCharges(sub, horizon)
+	}
+}
+
+// LedgerCharges превращает начисления подписки с первого месяца по to в строки журнала с валютой и налогом
+func LedgerCharges(sub *models.Subscription, to time.Time) []models.Charge {
+	currency := sub.Currency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+
+	terms := sub.Billing()
+	charges := billing.Charges(terms, time.Time{}, to)
+	rows := make([]models.Charge, 0, len(charges))
+	for _, charge := range charges {
+		rows = append(rows, models.Charge{
+			SubscriptionID: sub.ID,
+			Month:          charge.Month,
+			Date:           charge.Date,
+			Amount:         charge.Amount,
+			Currency:       currency,
+			TaxTotals:      models.TaxTotals(billing.SplitTax(terms, charge.Amount)),
+			Explanation:    charge.Explanation,
+			Proration:      proration(charge.Proration),
+		})
+	}
+	return rows
+}
+
+// ledgerBetween строит строки журнала за месяцы [from, to] в памяти - для условий, которых нет в журнале:
+// срезов as_of и подписок, измененных симуляцией
+func ledgerBetween(sub *models.Subscription, from, to time.Time) []models.Charge {
+	var rows []models.Charge
+	for _, charge := range LedgerCharges(sub, to) {
+		if !charge.Month.Before(from) {
+			rows = append(rows, charge)
+		}
+	}
+	return rows
+}
+
+func proration(p *billing.Proration) *models.Proration { //расчет движка в формат журнала и ответа
+	if p == nil {
+		return nil
+	}
+	parts := make([]models.ProrationPart, 0, len(p.Parts))
+	for _, part := range p.Parts {
+		parts = append(parts, models.ProrationPart(part))
+	}
+	return &models.Proration{SubscriptionID: p.SubscriptionID, Month: p.Month, Mode: p.Mode, PeriodStart: p.PeriodStart, PeriodEnd: p.PeriodEnd,
+		Days: p.Days, CycleDays: p.CycleDays, Parts: parts, Amount: p.Amount, Explanation: p.Explanation}
+}
+
+func (s *SubscriptionService) Charges(ctx context.Context, id uint) ([]models.Charge, error) {
+	if _, err := s.subsrepo.GetById(ctx, id); err != nil { //удаленная или несуществующая подписка
+		s.logger.Errorf("GetById subscription failed: %v", err)
+		return nil, err
+	}
+	charges, err := s.subsrepo.GetCharges(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetCharges failed: %v", err)
+		return nil, err
+	}
+	return charges, nil
+}
+
+// RebuildCharges заново строит весь журнал начислений по текущим подпискам и сдвигает горизонт будущих начислений
+func (s *SubscriptionService) RebuildCharges(ctx context.Context) (int, error) {
+	subs, charges, err := rebuildLedger(ctx, s.subsrepo, nil)
+	if err != nil {
+		s.logger.Errorf("RebuildCharges failed: %v", err)
+		return 0, err
+	}
+	pruned, err := s.subsrepo.PruneCharges(ctx)
+	if err != nil {
+		s.logger.Errorf("PruneCharges failed: %v", err)
+		return 0, err
+	}
+	s.logger.Infof("Rebuilt charges ledger: %d subscriptions, %d charges, %d stale charges removed", subs, charges, pruned)
+	return charges, nil
+}
+
+// rebuildLedger пересобирает журнал подписок пачками по rebuildBatchSize id, каждую подписку - в своей транзакции,
+// так что пересборка не держит в памяти все подписки и не затирает изменения, сделанные во время нее.
+// serviceID - только подписки сервиса. Возвращает число подписок и строк журнала
+func rebuildLedger(ctx context.Context, subsrepo repository.SubscriptionRepoInterface, serviceID *uint) (int, int, error) {
+	ledger := currentLedger()
+	subs, charges := 0, 0
+	var after uint
+	for {
+		ids, err := subsrepo.SubscriptionIDs(ctx, serviceID, after, rebuildBatchSize)
+		if err != nil {
+			return subs, charges, err
+		}
+		for _, id := range ids {
+			count, err := subsrepo.RebuildCharges(ctx, id, ledger)
+			if err != nil {
+				return subs, charges, fmt.Errorf("subscription %d: %w", id, err)
+			}
+			subs++
+			charges += count
+		}
+		if len(ids) < rebuildBatchSize {
+			return subs, charges, nil
+		}
+		after = ids[len(ids)-1]
+	}
+}
+
+// ledgerTotals складывает начисления подписок из журнала. Если задан userID, берется только его доля,
+// налог с доли считается по ставке подписки
+func ledgerTotals(subs []models.Subscription, userID *string) (int64, models.TaxTotals) {
+	var amount int64
+	var taxes models.TaxTotals
+	for i := range subs {
+		terms := subs[i].Billing()
+		for j := range subs[i].Charges {
+			chargeAmount, chargeTaxes := chargeTotals(terms, &subs[i].Charges[j], userID)
+			amount += chargeAmount
+			taxes.Add(chargeTaxes)
+		}
+	}
+	return amount, taxes
+}
+
+// chargeTotals - сумма строки журнала с налогом, а если задан userID - его доля и налог с доли
+func chargeTotals(terms *billing.Subscription, charge *models.Charge, userID *string) (int64, models.TaxTotals) {
+	if userID == nil {
+		return charge.Amount, charge.TaxTotals
+	}
+	share := billing.ShareOf(terms, *userID, charge.Amount)
+	return share, models.TaxTotals(billing.SplitTax(terms, share))
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"subscriptions/billing"
+	"subscriptions/events"
+	"subscriptions/models"
+	"subscriptions/requestctx"
+	"time"
+)
+
+const (
+	ActionActivate = "activate"
+	ActionPause    = "pause"
+	ActionResume   = "resume"
+	ActionCancel   = "cancel"
+)
+
+var (
+	ErrUnknownAction         = errors.New("unknown lifecycle action")
+	ErrInvalidTransition     = errors.New("transition is not allowed from current status")
+	ErrInvalidTransitionDate = errors.New("invalid transition date: expected MM-YYYY or YYYY-MM-DD not earlier than start date and previous transition")
+	ErrMinimumTerm           = errors.New("cancellation must not end the subscription before its minimum term")
+)
+
+type lifecycleAction struct {
+	from     []string //из каких статусов разрешен переход
+	to       string
+	event    string //событие о переходе
+	approval bool   //решение согласующего, действует с начала подписки
+}
+
+// конечный автомат: pending_approval -> trial -> active -> paused -> active -> cancelled, pending_approval -> rejected
+var lifecycleActions = map[string]lifecycleAction{
+	ActionApprove:  {from: []string{models.StatusPendingApproval}, to: models.StatusActive, event: events.TypeSubscriptionApproved, approval: true},
+	ActionReject:   {from: []string{models.StatusPendingApproval}, to: models.StatusRejected, event: events.TypeSubscriptionRejected, approval: true},
+	ActionActivate: {from: []string{models.StatusTrial}, to: models.StatusActive, event: events.TypeSubscriptionActivated},
+	ActionPause:    {from: []string{models.StatusActive}, to: models.StatusPaused, event: events.TypeSubscriptionPaused},
+	ActionResume:   {from: []string{models.StatusPaused}, to: models.StatusActive, event: events.TypeSubscriptionResumed},
+	ActionCancel:   {from: []string{models.StatusTrial, models.StatusActive, models.StatusPaused}, to: models.StatusCancelled, event: events.TypeSubscriptionCancelled},
+}
+
+func (a lifecycleAction) allowedFrom(status string) bool {
+	for _, from := range a.from {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SubscriptionService) ChangeStatus(ctx context.Context, id uint, action string, request *models.TransitionRequest) (*models.Subscription, error) {
+	if request == nil {
+		request = &models.TransitionRequest{}
+	}
+	return s.changeStatus(ctx, id, action, request.Date, request.Comment, nil)
+}
+
+// Cancel отменяет подписку с причиной: effective_month становится последним оплаченным месяцем, дата окончания
+// проверяется так же, как при создании и обновлении, и не может оказаться внутри минимального срока
+func (s *SubscriptionService) Cancel(ctx context.Context, id uint, request *models.CancelRequest) (*models.Subscription, error) {
+	month := request.EffectiveMonth
+	if month == nil {
+		month = request.Date
+	}
+	return s.changeStatus(ctx, id, ActionCancel, month, request.Comment, request.Reason)
+}
+
+func (s *SubscriptionService) changeStatus(ctx context.Context, id uint, action string, requestDate, comment, reason *string) (*models.Subscription, error) {
+	transition, ok := lifecycleActions[action]
+	if !ok {
+		s.logger.Errorf("ChangeStatus failed: unknown action %s", action)
+		return nil, ErrUnknownAction
+	}
+	if transition.approval && !s.isApprover(ctx) {
+		s.logger.Warnf("ChangeStatus %s denied for %s", action, requestctx.Actor(ctx))
+		return nil, ErrApproverRequired
+	}
+
+	sub, err := s.subsrepo.GetById(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetById subscription failed: %v", err)
+		return nil, err
+	}
+	before := *sub
+
+	if !transition.allowedFrom(sub.Status) {
+		s.logger.Errorf("ChangeStatus failed: %s is not allowed from %s", action, sub.Status)
+		return nil, ErrInvalidTransition
+	}
+
+	to := transition.to
+	terms := sub.Billing()
+	date := billing.MonthStart(time.Now())
+	end := billing.PaidThrough(terms, date)
+	if transition.approval { //одобренная подписка действует с самого начала, отклоненная не оплачивается ни за один месяц
+		date = billing.MonthStart(sub.StartDate) //как у начального статуса
+		if action == ActionApprove && sub.TrialEndDate != nil {
+			to = models.StatusTrial
+		}
+	} else if requestDate != nil {
+		day, dayLevel, err := parseDate(*requestDate)
+		if err != nil {
+			s.logger.Errorf("Parsing transition date failed: %v", err)
+			return nil, ErrInvalidTransitionDate
+		}
+		date = billing.MonthStart(day) //статус меняется помесячно
+		end = billing.PaidThrough(terms, date)
+		if dayLevel && billing.Prorates(terms) {
+			end = day //с пересчетом отмена действует с точностью до дня
+		}
+	}
+	if date.Before(billing.MonthStart(sub.StartDate)) {
+		s.logger.Error(ErrInvalidTransitionDate)
+		return nil, ErrInvalidTransitionDate
+	}
+	if last := len(sub.Transitions); last > 0 && date.Before(sub.Transitions[last-1].Date) { //переходы идут по порядку
+		s.logger.Error(ErrInvalidTransitionDate)
+		return nil, ErrInvalidTransitionDate
+	}
+
+	record := &models.StatusTransition{From: sub.Status, To: to, Date: date, Actor: requestctx.Actor(ctx), Comment: comment}
+	if to == models.StatusCancelled {
+		if first := billing.MinimumTermMonth(terms); first != nil && date.Before(*first) {
+			s.logger.Errorf("Cancel subscription %d failed: minimum term lasts until %s", sub.ID, first.Format("01-2006"))
+			return nil, ErrMinimumTerm
+		}
+		if sub.EndDate == nil || sub.EndDate.After(end) {
+			if err = checkEndDate(sub, end); err != nil {
+				s.logger.Error(err)
+				return nil, err
+			}
+			sub.EndDate = &end //месяц отмены - последний оплаченный месяц
+		}
+		record.Reason = reason
+	}
+	sub.Status = to
+
+	s.logger.Infof("Changing subscription %d status: %s -> %s from %s", sub.ID, record.From, record.To, date.Format("01-2006"))
+	if err = s.subsrepo.AddTransition(ctx, sub, record, currentLedger()); err != nil { //переход попадает в sub.Transitions
+		s.logger.Errorf("AddTransition failed: %v", err)
+		return nil, err
+	}
+
+	s.audit.Record(ctx, models.AuditEntitySubscription, sub.ID, action, &before, sub)
+	s.publishTransition(ctx, transition.event, sub, record)
+	sub.Warnings = s.budgets.CheckSubscription(ctx, &before, sub) //возобновление или одобрение может превысить бюджет
+	return sub, nil
+}
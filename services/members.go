@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"subscriptions/models"
+)
+
+var ErrInvalidMembers = errors.New("invalid members: each member needs exactly one of share_percent or share_amount, must differ from the payer and be listed once, and shares must not exceed the price")
+
+// buildMembers проверяет участников совместной подписки: у каждого ровно один вид доли, плательщик не участник,
+// без повторов, а сумма долей не превышает цену
+func buildMembers(payerID string, price int64, inputs []models.MemberInput) ([]models.SubscriptionMember, error) {
+	members := make([]models.SubscriptionMember, 0, len(inputs))
+	seen := map[string]bool{payerID: true}
+	shares := 0.0
+
+	for _, input := range inputs {
+		amount := models.MinorOrLegacy(input.ShareAmountMinor, input.ShareAmount)
+		if (input.SharePercent == nil) == (amount == nil) || seen[input.UserID] {
+			return nil, ErrInvalidMembers
+		}
+		seen[input.UserID] = true
+
+		if input.SharePercent != nil {
+			shares += float64(price) * *input.SharePercent / 100
+		} else {
+			shares += float64(*amount)
+		}
+
+		members = append(members, models.SubscriptionMember{
+			UserID:       input.UserID,
+			SharePercent: input.SharePercent,
+			ShareAmount:  amount,
+		})
+	}
+
+	if shares > float64(price) {
+		return nil, ErrInvalidMembers
+	}
+	return members, nil
+}
+
+func memberInputs(members []models.SubscriptionMember) []models.MemberInput { //текущие участники для повторной проверки
+	inputs := make([]models.MemberInput, 0, len(members))
+	for _, member := range members {
+		inputs = append(inputs, models.MemberInput{UserID: member.UserID, SharePercent: member.SharePercent, ShareAmountMinor: member.ShareAmount})
+	}
+	return inputs
+}
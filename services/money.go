@@ -0,0 +1,32 @@
+package services
+
+import (
+	"errors"
+	"subscriptions/models"
+)
+
+var ErrInvalidPrice = errors.New("invalid price: price_minor (or legacy price) is required, promo price and promo_months go together")
+
+var ErrMixedCurrencies = errors.New("subscriptions have different currencies, specify currency")
+
+// sumCurrency возвращает валюту суммы: заданную в фильтре или общую для всех подписок. Складывать разные валюты нельзя
+func sumCurrency(subs []models.Subscription, filter *string) (string, error) {
+	if filter != nil {
+		return *filter, nil
+	}
+	currency := ""
+	for _, sub := range subs {
+		subCurrency := sub.Currency
+		if subCurrency == "" {
+			subCurrency = models.DefaultCurrency
+		}
+		if currency != "" && currency != subCurrency {
+			return "", ErrMixedCurrencies
+		}
+		currency = subCurrency
+	}
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	return currency, nil
+}
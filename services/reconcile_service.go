@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"subscriptions/billing"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/statement"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidStatement = errors.New("invalid statement")
+
+const (
+	defaultToleranceDays   = 3   //списание может прийти на несколько дней позже или раньше дня списания
+	defaultAmountTolerance = 5.0 //и немного отличаться по сумме, например из-за курса
+)
+
+type ReconcileServiceInterface interface {
+	Reconcile(ctx context.Context, request *models.ReconcileRequest, fileName string, data []byte) (*models.ReconcileReport, error)
+}
+
+type ReconcileService struct {
+	repo        repository.StatementRepoInterface
+	subsrepo    repository.SubscriptionRepoInterface
+	servicerepo repository.ServiceRepoInterface
+	logger      *zap.SugaredLogger
+}
+
+func NewReconcileService(repo repository.StatementRepoInterface, subsrepo repository.SubscriptionRepoInterface, servicerepo repository.ServiceRepoInterface, logger *zap.SugaredLogger) ReconcileServiceInterface {
+	return &ReconcileService{repo: repo, subsrepo: subsrepo, servicerepo: servicerepo, logger: logger}
+}
+
+// Reconcile разбирает выписку, сохраняет ее операции и сверяет их с начислениями подписок пользователя из журнала:
+// какие начисления оплачены, какие нет и какие регулярные списания не относятся ни к одной подписке
+func (s *ReconcileService) Reconcile(ctx context.Context, request *models.ReconcileRequest, fileName string, data []byte) (*models.ReconcileReport, error) {
+	format := statement.DetectFormat(fileName, data)
+	if request.Format != nil {
+		format = *request.Format
+	}
+	currency := models.DefaultCurrency
+	if request.Currency != nil {
+		currency = *request.Currency
+	}
+	toleranceDays := defaultToleranceDays
+	if request.ToleranceDays != nil {
+		toleranceDays = *request.ToleranceDays
+	}
+	amountTolerance := defaultAmountTolerance
+	if request.AmountTolerance != nil {
+		amountTolerance = *request.AmountTolerance
+	}
+
+	transactions, err := statement.Parse(format, data, currency)
+	if err != nil {
+		s.logger.Warnf("Parse statement %s failed: %v", fileName, err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStatement, err)
+	}
+	from, to := statementPeriod(transactions)
+
+	windowStart := billing.MonthStart(from.AddDate(0, 0, -toleranceDays)) //начисления, которые могли списаться в период выписки
+	windowEnd := billing.MonthStart(to.AddDate(0, 0, toleranceDays))
+	subs, err := s.subsrepo.FindForSum(ctx, &repository.SubscriptionQuery{UserID: &request.UserID, Start: &windowStart, End: &windowEnd, WithCharges: true})
+	if err != nil {
+		s.logger.Errorf("FindForSum failed: %v", err)
+		return nil, err
+	}
+	services, err := s.servicerepo.GetAll(ctx)
+	if err != nil {
+		s.logger.Errorf("GetAll services failed: %v", err)
+		return nil, err
+	}
+
+	names := serviceNames(services)
+	subscriptionNames := map[uint][]string{}
+	for _, sub := range subs {
+		subscriptionNames[sub.ID] = names[sub.ServiceID]
+	}
+	expected := expectedCharges(subs, from.AddDate(0, 0, -toleranceDays), to.AddDate(0, 0, toleranceDays))
+	paidBy := matchCharges(expected, transactions, subscriptionNames, toleranceDays, amountTolerance)
+	for e, t := range paidBy {
+		transactions[t].SubscriptionID = &expected[e].SubscriptionID
+	}
+
+	imported := &models.StatementImport{UserID: request.UserID, FileName: fileName, Format: format, Transactions: transactions}
+	for i := range imported.Transactions {
+		imported.Transactions[i].UserID = request.UserID
+	}
+	if err = s.repo.CreateImport(ctx, imported); err != nil {
+		s.logger.Errorf("CreateImport failed: %v", err)
+		return nil, err
+	}
+
+	report := &models.ReconcileReport{ImportID: imported.ID, From: from, To: to, Transactions: len(imported.Transactions),
+		Matched: []models.ReconcileMatch{}, Missing: []models.ExpectedCharge{}}
+	for e, charge := range expected {
+		if t, paid := paidBy[e]; paid {
+			report.Matched = append(report.Matched, models.ReconcileMatch{Expected: charge, Transaction: imported.Transactions[t]})
+		} else if !charge.Date.Before(from) && !charge.Date.After(to) { //за пределами выписки списания может просто не быть
+			report.Missing = append(report.Missing, charge)
+		}
+	}
+	report.Unknown = untracked(imported.Transactions, subscriptionNames, amountTolerance)
+
+	s.logger.Infof("Reconciled statement %d: %d transactions, %d matched, %d missing, %d unknown recurring",
+		imported.ID, len(imported.Transactions), len(report.Matched), len(report.Missing), len(report.Unknown))
+	return report, nil
+}
+
+// serviceNames - названия и псевдонимы каждого сервиса, под которыми он встречается в выписках
+func serviceNames(services []models.Service) map[uint][]string {
+	names := map[uint][]string{}
+	for _, service := range services {
+		names[service.ID] = append(names[service.ID], service.Name)
+		for _, alias := range service.Aliases {
+			names[service.ID] = append(names[service.ID], alias.Alias)
+		}
+	}
+	return names
+}
+
+func statementPeriod(transactions []models.BankTransaction) (from, to time.Time) {
+	from, to = transactions[0].Date, transactions[0].Date
+	for _, transaction := range transactions[1:] {
+		if transaction.Date.Before(from) {
+			from = transaction.Date
+		}
+		if transaction.Date.After(to) {
+			to = transaction.Date
+		}
+	}
+	return from, to
+}
+
+// expectedCharges - начисления подписок из журнала с днем списания в [from, to], по дате
+func expectedCharges(subs []models.Subscription, from, to time.Time) []models.ExpectedCharge {
+	var expected []models.ExpectedCharge
+	for _, sub := range subs {
+		for _, charge := range sub.Charges {
+			if charge.Date.Before(from) || charge.Date.After(to) || charge.Gross == 0 {
+				continue
+			}
+			expected = append(expected, models.ExpectedCharge{
+				SubscriptionID: sub.ID,
+				ServiceName:    sub.Service.Name,
+				Date:           charge.Date,
+				Amount:         charge.Gross,
+				Currency:       charge.Currency,
+			})
+		}
+	}
+	sort.SliceStable(expected, func(i, j int) bool { return expected[i].Date.Before(expected[j].Date) })
+	return expected
+}
+
+// matchCharges для каждого начисления ищет еще не занятое списание: получатель совпадает с названием сервиса
+// подписки или его псевдонимом, а дата и сумма в пределах допуска. Из подходящих берется ближайшее по дате.
+// Возвращает номер списания для каждого оплаченного начисления
+func matchCharges(expected []models.ExpectedCharge, transactions []models.BankTransaction, names map[uint][]string, toleranceDays int, amountTolerance float64) map[int]int {
+	used := map[int]bool{}
+	paidBy := map[int]int{}
+	for e, charge := range expected {
+		best, bestDays := -1, 0
+		for t := range transactions {
+			transaction := &transactions[t]
+			if used[t] || transaction.Currency != charge.Currency || !statement.AmountWithin(transaction.Debit(), charge.Amount, amountTolerance) {
+				continue
+			}
+			days := absDays(transaction.Date, charge.Date)
+			if days > toleranceDays || (best >= 0 && days >= bestDays) {
+				continue
+			}
+			if matchesAny(transaction.Merchant, names[charge.SubscriptionID]) {
+				best, bestDays = t, days
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			paidBy[e] = best
+		}
+	}
+	return paidBy
+}
+
+// untracked - регулярные списания среди неопознанных операций, получатели которых не похожи ни на одну подписку пользователя
+func untracked(transactions []models.BankTransaction, names map[uint][]string, amountTolerance float64) []models.RecurringPayment {
+	var unmatched []models.BankTransaction
+	for _, transaction := range transactions {
+		if transaction.SubscriptionID == nil {
+			unmatched = append(unmatched, transaction)
+		}
+	}
+	var tracked []string
+	for _, subscriptionNames := range names {
+		tracked = append(tracked, subscriptionNames...)
+	}
+
+	payments := []models.RecurringPayment{}
+	for _, payment := range statement.DetectRecurring(unmatched, amountTolerance) {
+		if !matchesAny(payment.Merchant, tracked) {
+			payments = append(payments, payment)
+		}
+	}
+	return payments
+}
+
+func matchesAny(merchant string, names []string) bool {
+	for _, name := range names {
+		if statement.MatchesName(merchant, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func absDays(a, b time.Time) int {
+	days := int(a.Sub(b).Hours() / 24)
+	if days < 0 {
+		return -days
+	}
+	return days
+}
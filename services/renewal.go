@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"subscriptions/billing"
+	"subscriptions/events"
+	"subscriptions/models"
+	"time"
+)
+
+// withDeadline заполняет ближайшее продление и последний день подачи отмены перед ним
+func withDeadline(sub *models.Subscription, today time.Time) {
+	sub.CancellationDeadline, sub.NextRenewal = billing.CancellationDeadline(sub.Billing(), today)
+}
+
+// applyTerms переносит условия продления из запроса в подписку
+func applyTerms(sub *models.Subscription, autoRenew *bool, minimumTerm, renewalTerm, noticeDays *uint) {
+	if autoRenew != nil {
+		sub.AutoRenew = *autoRenew
+	}
+	if minimumTerm != nil {
+		sub.MinimumTermMonths = *minimumTerm
+	}
+	if renewalTerm != nil {
+		sub.RenewalTermMonths = *renewalTerm
+	}
+	if noticeDays != nil {
+		sub.NoticeDays = *noticeDays
+	}
+}
+
+// RenewalDeadlines возвращает подписки с автопродлением, последний день отмены которых наступает в ближайшие within дней
+func (s *SubscriptionService) RenewalDeadlines(ctx context.Context, filter *models.DeadlinesFilter) ([]models.RenewalDeadline, error) {
+	today := billing.DayStart(time.Now())
+	to := today.AddDate(0, 0, filter.Within)
+
+	subs, err := s.subsrepo.FindRenewing(ctx, filter.UserID)
+	if err != nil {
+		s.logger.Errorf("FindRenewing failed: %v", err)
+		return nil, err
+	}
+
+	res := []models.RenewalDeadline{}
+	for _, sub := range subs {
+		withDeadline(&sub, today)
+		if sub.CancellationDeadline == nil || sub.CancellationDeadline.After(to) {
+			continue
+		}
+		res = append(res, models.RenewalDeadline{
+			Deadline:     *sub.CancellationDeadline,
+			RenewsAt:     *sub.NextRenewal,
+			DaysLeft:     int(sub.CancellationDeadline.Sub(today).Hours() / 24),
+			Subscription: sub,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Deadline.Before(res[j].Deadline) })
+	return res, nil
+}
+
+// RenewDue продлевает подписки с автопродлением, срок которых закончился: дата окончания сдвигается на срок продления,
+// пока не станет не раньше сегодняшнего дня. Каждое продление сохраняется версией в истории, попадает в журнал
+// изменений и публикуется событием. Возвращает число продлений
+func (s *SubscriptionService) RenewDue(ctx context.Context) (int, error) {
+	today := billing.DayStart(time.Now())
+	subs, err := s.subsrepo.FindDueRenewals(ctx, today)
+	if err != nil {
+		s.logger.Errorf("FindDueRenewals failed: %v", err)
+		return 0, err
+	}
+
+	ledger := currentLedger()
+	count := 0
+	for i := range subs {
+		sub := &subs[i]
+		for sub.EndDate.Before(today) {
+			before := *sub
+			previousEnd := *sub.EndDate
+			end := billing.RenewedEnd(sub.Billing())
+			sub.EndDate = &end
+			renewed, err := s.subsrepo.Renew(ctx, sub, previousEnd, ledger)
+			if err != nil {
+				s.logger.Errorf("Renew subscription %d failed: %v", sub.ID, err)
+				return count, err
+			}
+			if !renewed { //продлена параллельным запуском или отменена после выборки
+				s.logger.Infof("Subscription %d is already renewed or cancelled", sub.ID)
+				*sub = before
+				break
+			}
+			count++
+			s.logger.Infof("Renewed subscription %d: %s -> %s", sub.ID, previousEnd.Format("2006-01-02"), end.Format("2006-01-02"))
+			s.audit.Record(ctx, models.AuditEntitySubscription, sub.ID, models.AuditActionRenew, &before, sub)
+			s.publisher.Publish(ctx, events.Event{
+				Type:    events.TypeSubscriptionRenewed,
+				Payload: map[string]interface{}{"subscription_id": sub.ID, "user_id": sub.UserID, "previous_end_date": previousEnd, "end_date": end},
+			})
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"subscriptions/billing"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type ReportServiceInterface interface {
+	Spend(ctx context.Context, filter *models.SpendFilter) (*models.SpendReport, error)
+	Chargeback(ctx context.Context, filter *models.ChargebackFilter) (*models.ChargebackReport, error)
+	Churn(ctx context.Context, filter *models.ChurnFilter) (*models.ChurnReport, error)
+}
+
+type ReportService struct {
+	subsrepo    repository.SubscriptionRepoInterface
+	costcenters repository.CostCenterRepoInterface
+	logger      *zap.SugaredLogger
+}
+
+func NewReportService(subsrepo repository.SubscriptionRepoInterface, costcenters repository.CostCenterRepoInterface, logger *zap.SugaredLogger) ReportServiceInterface {
+	return &ReportService{subsrepo: subsrepo, costcenters: costcenters, logger: logger}
+}
+
+// Spend раскладывает расходы за месяцы периода по меткам, сервисам или категориям. Суммы берутся из журнала начислений,
+// как и в сумме подписок: полная цена подписки без деления на доли участников
+func (s *ReportService) Spend(ctx context.Context, filter *models.SpendFilter) (*models.SpendReport, error) {
+	from, to, err := s.reportPeriod(filter.StartDate, filter.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.subsrepo.FindForSum(ctx, &repository.SubscriptionQuery{
+		UserID:      filter.UserID,
+		Currency:    filter.Currency,
+		Tag:         normalizeTag(filter.Tag),
+		Start:       &from,
+		End:         &to,
+		WithCharges: true,
+	})
+	if err != nil {
+		s.logger.Errorf("FindForSum failed: %v", err)
+		return nil, err
+	}
+	currency, err := sumCurrency(subs, filter.Currency)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	report := &models.SpendReport{From: from, To: to, GroupBy: filter.GroupBy, Currency: currency, Groups: []models.SpendGroup{}}
+	groups := map[string]*models.SpendGroup{}
+	for i := range subs {
+		amount, taxes := ledgerTotals(subs[i:i+1], nil)
+		report.Total += amount
+		report.Taxes.Add(taxes)
+
+		for _, key := range spendKeys(&subs[i], filter.GroupBy) {
+			group, ok := groups[key]
+			if !ok {
+				group = &models.SpendGroup{Key: key}
+				groups[key] = group
+			}
+			group.Amount += amount
+			group.Taxes.Add(taxes)
+			group.Subscriptions++
+		}
+	}
+
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool { //сначала самые дорогие
+		if report.Groups[i].Amount != report.Groups[j].Amount {
+			return report.Groups[i].Amount > report.Groups[j].Amount
+		}
+		return report.Groups[i].Key < report.Groups[j].Key
+	})
+	return report, nil
+}
+
+// Chargeback относит расходы месяца из журнала начислений на центры затрат по долям подписок. Подписки без распределения
+// попадают в строку с cost_center_id 0, суммы в разных валютах - в разные строки
+func (s *ReportService) Chargeback(ctx context.Context, filter *models.ChargebackFilter) (*models.ChargebackReport, error) {
+	month, err := parseMonth(filter.Month)
+	if err != nil {
+		s.logger.Errorf("Parsing month failed: %v", err)
+		return nil, err
+	}
+	subs, err := s.subsrepo.FindForSum(ctx, &repository.SubscriptionQuery{UserID: filter.UserID, Start: &month, End: &month, WithCharges: true})
+	if err != nil {
+		s.logger.Errorf("FindForSum failed: %v", err)
+		return nil, err
+	}
+	centers, err := s.costcenters.GetAll(ctx)
+	if err != nil {
+		s.logger.Errorf("GetAll cost centers failed: %v", err)
+		return nil, err
+	}
+	centersByID := map[uint]models.CostCenter{}
+	for _, center := range centers {
+		centersByID[center.ID] = center
+	}
+
+	type key struct {
+		center   uint
+		currency string
+	}
+	lines := map[key]*models.ChargebackLine{}
+	for i := range subs {
+		sub := &subs[i]
+		amount, _ := ledgerTotals(subs[i:i+1], nil)
+		if amount == 0 { //пробный период, пауза или подписка уже закончилась
+			continue
+		}
+		currency := sub.Currency
+		if currency == "" {
+			currency = models.DefaultCurrency
+		}
+
+		terms := sub.Billing()
+		ids, percents := []uint{0}, []float64{100}
+		if len(sub.Allocations) > 0 {
+			ids, percents = nil, nil
+			for _, allocation := range sub.Allocations {
+				ids = append(ids, allocation.CostCenterID)
+				percents = append(percents, allocation.Percent)
+			}
+		}
+		for j, part := range allocate(amount, percents) {
+			k := key{ids[j], currency}
+			line, ok := lines[k]
+			if !ok {
+				center := centersByID[ids[j]]
+				line = &models.ChargebackLine{CostCenterID: ids[j], Code: center.Code, Name: center.Name, Currency: currency}
+				lines[k] = line
+			}
+			line.Amount += part
+			line.Taxes.Add(models.TaxTotals(billing.SplitTax(terms, part)))
+			line.Subscriptions++
+		}
+	}
+
+	report := &models.ChargebackReport{Month: month, Lines: []models.ChargebackLine{}}
+	for _, line := range lines {
+		report.Lines = append(report.Lines, *line)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool { //по коду центра, нераспределенные в конце
+		a, b := report.Lines[i], report.Lines[j]
+		if (a.CostCenterID == 0) != (b.CostCenterID == 0) {
+			return b.CostCenterID == 0
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Currency < b.Currency
+	})
+	return report, nil
+}
+
+// Churn считает отмены, которые действуют с месяцев периода: по сервисам и месяцам, по причинам и средний срок жизни
+// отмененных подписок от даты начала до даты окончания
+func (s *ReportService) Churn(ctx context.Context, filter *models.ChurnFilter) (*models.ChurnReport, error) {
+	from, to, err := s.reportPeriod(filter.StartDate, filter.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := s.subsrepo.FindCancelled(ctx, from, to, filter.UserID)
+	if err != nil {
+		s.logger.Errorf("FindCancelled failed: %v", err)
+		return nil, err
+	}
+
+	type key struct {
+		month   time.Time
+		service uint
+	}
+	lines := map[key]*models.ChurnLine{}
+	reasons := map[string]int{}
+	report := &models.ChurnReport{From: from, To: to, Lines: []models.ChurnLine{}, Reasons: []models.ChurnReason{}}
+	var lifetime float64
+	for i := range subs {
+		sub := &subs[i]
+		cancellation := cancellationOf(sub)
+		if cancellation == nil || cancellation.Date.Before(from) || !cancellation.Date.Before(to.AddDate(0, 1, 0)) {
+			continue
+		}
+		k := key{billing.MonthStart(cancellation.Date), sub.ServiceID}
+		line, ok := lines[k]
+		if !ok {
+			line = &models.ChurnLine{Month: k.month, ServiceID: sub.ServiceID, ServiceName: sub.Service.Name}
+			lines[k] = line
+		}
+		line.Cancellations++
+
+		reason := cancelReasonUnspecified
+		if cancellation.Reason != nil {
+			reason = *cancellation.Reason
+		}
+		reasons[reason]++
+
+		end := cancellation.Date
+		if sub.EndDate != nil {
+			end = *sub.EndDate
+		}
+		lifetime += lifetimeMonths(sub.StartDate, end)
+		report.Cancellations++
+	}
+
+	for _, line := range lines {
+		report.Lines = append(report.Lines, *line)
+	}
+	sort.Slice(report.Lines, func(i, j int) bool { //по месяцам, внутри месяца - больше отмен выше
+		a, b := report.Lines[i], report.Lines[j]
+		if !a.Month.Equal(b.Month) {
+			return a.Month.Before(b.Month)
+		}
+		if a.Cancellations != b.Cancellations {
+			return a.Cancellations > b.Cancellations
+		}
+		return a.ServiceName < b.ServiceName
+	})
+	for reason, count := range reasons {
+		report.Reasons = append(report.Reasons, models.ChurnReason{Reason: reason, Cancellations: count})
+	}
+	sort.Slice(report.Reasons, func(i, j int) bool {
+		if report.Reasons[i].Cancellations != report.Reasons[j].Cancellations {
+			return report.Reasons[i].Cancellations > report.Reasons[j].Cancellations
+		}
+		return report.Reasons[i].Reason < report.Reasons[j].Reason
+	})
+	if report.Cancellations > 0 {
+		report.AverageLifetimeMonths = math.Round(lifetime/float64(report.Cancellations)*10) / 10
+	}
+	return report, nil
+}
+
+// reportPeriod разбирает месяцы отчета: без начала берется текущий месяц, без конца - месяц начала
+func (s *ReportService) reportPeriod(start, end *string) (time.Time, time.Time, error) {
+	from := billing.MonthStart(time.Now())
+	if start != nil {
+		parsed, err := parseMonth(*start)
+		if err != nil {
+			s.logger.Errorf("Parsing start date failed: %v", err)
+			return time.Time{}, time.Time{}, err
+		}
+		from = parsed
+	}
+	to := from
+	if end != nil {
+		parsed, err := parseMonth(*end)
+		if err != nil {
+			s.logger.Errorf("Parsing end date failed: %v", err)
+			return time.Time{}, time.Time{}, err
+		}
+		to = parsed
+	}
+	if from.After(to) { //конец не должен быть раньше начала
+		err := fmt.Errorf("%w: end date must be after start date", ErrInvalidDate)
+		s.logger.Error(err)
+		return time.Time{}, time.Time{}, err
+	}
+	return from, to, nil
+}
+
+const (
+	cancelReasonUnspecified = "unspecified" //отмена без указанной причины
+	averageMonthDays        = 365.25 / 12
+)
+
+// cancellationOf возвращает последний переход подписки в статус cancelled
+func cancellationOf(sub *models.Subscription) *models.StatusTransition {
+	for i := len(sub.Transitions) - 1; i >= 0; i-- {
+		if sub.Transitions[i].To == models.StatusCancelled {
+			return &sub.Transitions[i]
+		}
+	}
+	return nil
+}
+
+// lifetimeMonths - сколько месяцев подписка действовала, день окончания включительно
+func lifetimeMonths(start, end time.Time) float64 {
+	return end.AddDate(0, 0, 1).Sub(start).Hours() / 24 / averageMonthDays
+}
+
+// spendKeys - группы, в которые попадает подписка. Подписка без меток или без категории попадает в группу с пустым ключом
+func spendKeys(sub *models.Subscription, groupBy string) []string {
+	switch groupBy {
+	case models.GroupByTag:
+		if len(sub.Tags) == 0 {
+			return []string{""}
+		}
+		keys := make([]string, 0, len(sub.Tags))
+		for _, tag := range sub.Tags {
+			keys = append(keys, tag.Name)
+		}
+		return keys
+	case models.GroupByCategory:
+		if sub.Service.Category == nil {
+			return []string{""}
+		}
+		return []string{*sub.Service.Category}
+	}
+	return []string{sub.Service.Name}
+}
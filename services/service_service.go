@@ -2,8 +2,13 @@ package services
 
 import (
 	"context"
+	"math"
+	"sort"
+	"strings"
+	"subscriptions/billing"
 	"subscriptions/models"
 	"subscriptions/repository"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -11,16 +16,22 @@ import (
 type ServiceServiceInterface interface {
 	GetAll(ctx context.Context) ([]models.Service, error)
 	Create(ctx context.Context, service *models.CreateService) (*models.Service, error)
+	Update(ctx context.Context, id uint, service *models.UpdateService) (*models.Service, error)
 	Delete(ctx context.Context, id uint) error
+	Stats(ctx context.Context, id uint, filter *models.ServiceStatsFilter) (*models.ServiceStats, error)
 }
 
+const defaultTrendMonths = 12
+
 type ServiceService struct {
-	repo   repository.ServiceRepoInterface
-	logger *zap.SugaredLogger
+	repo     repository.ServiceRepoInterface
+	subsrepo repository.SubscriptionRepoInterface
+	audit    AuditServiceInterface
+	logger   *zap.SugaredLogger
 }
 
-func NewServiceService(repo repository.ServiceRepoInterface, logger *zap.SugaredLogger) ServiceServiceInterface {
-	return &ServiceService{repo: repo, logger: logger}
+func NewServiceService(repo repository.ServiceRepoInterface, subsrepo repository.SubscriptionRepoInterface, audit AuditServiceInterface, logger *zap.SugaredLogger) ServiceServiceInterface {
+	return &ServiceService{repo: repo, subsrepo: subsrepo, audit: audit, logger: logger}
 }
 
 func (s *ServiceService) GetAll(ctx context.Context) ([]models.Service, error) {
@@ -33,21 +44,176 @@ func (s *ServiceService) GetAll(ctx context.Context) ([]models.Service, error) {
 }
 
 func (s *ServiceService) Create(ctx context.Context, service *models.CreateService) (*models.Service, error) {
-	newService := &models.Service{Name: service.Name}
+	newService := &models.Service{Name: service.Name, Category: service.Category, TaxRate: service.TaxRate, TaxInclusive: service.TaxInclusive,
+		Aliases: buildAliases(service.Aliases)}
 	s.logger.Infof("Create service: %v", newService)
 	err := s.repo.Create(ctx, newService)
 	if err != nil {
 		s.logger.Errorf("Create service failed: %v", err)
 		return nil, err
 	}
+	s.audit.Record(ctx, models.AuditEntityService, newService.ID, models.AuditActionCreate, nil, newService)
 	return newService, nil
 }
 
+func (s *ServiceService) Update(ctx context.Context, id uint, update *models.UpdateService) (*models.Service, error) {
+	service, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetById service failed: %v", err)
+		return nil, err
+	}
+	before := *service
+
+	service.Category = update.Category //пустое значение снимает категорию
+	service.TaxRate, service.TaxInclusive = update.TaxRate, update.TaxInclusive
+	s.logger.Infof("Update service: %v", service)
+	if err = s.repo.Update(ctx, service); err != nil {
+		s.logger.Errorf("Update service failed: %v", err)
+		return nil, err
+	}
+	if update.Aliases != nil {
+		service.Aliases = buildAliases(*update.Aliases)
+		if err = s.repo.ReplaceAliases(ctx, id, service.Aliases); err != nil {
+			s.logger.Errorf("ReplaceAliases failed: %v", err)
+			return nil, err
+		}
+	}
+	if taxChanged(&before, service) { //строки журнала подписок сервиса хранят налог по старой ставке
+		subs, charges, err := rebuildLedger(ctx, s.subsrepo, &id)
+		if err != nil {
+			s.logger.Errorf("Rebuild charges for service %d failed: %v", id, err)
+			return nil, err
+		}
+		s.logger.Infof("Rebuilt charges of service %d: %d subscriptions, %d charges", id, subs, charges)
+	}
+	s.audit.Record(ctx, models.AuditEntityService, id, models.AuditActionUpdate, &before, service)
+	return service, nil
+}
+
+func taxChanged(before, after *models.Service) bool {
+	if before.TaxInclusive != after.TaxInclusive || (before.TaxRate == nil) != (after.TaxRate == nil) {
+		return true
+	}
+	return before.TaxRate != nil && *before.TaxRate != *after.TaxRate
+}
+
 func (s *ServiceService) Delete(ctx context.Context, id uint) error {
-	err := s.repo.Delete(ctx, id)
+	before, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetById service failed: %v", err)
+		return err
+	}
+
+	err = s.repo.Delete(ctx, id)
 	if err != nil {
 		s.logger.Errorf("Delete service failed: %v", err)
 		return err
 	}
+	s.audit.Record(ctx, models.AuditEntityService, id, models.AuditActionDelete, before, nil)
 	return nil
 }
+
+// Stats показывает, как используется сервис: сколько у него подписчиков сейчас, какие цены и выручка за месяц
+// по валютам и как менялось число подписчиков за последние месяцы
+func (s *ServiceService) Stats(ctx context.Context, id uint, filter *models.ServiceStatsFilter) (*models.ServiceStats, error) {
+	service, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetById service failed: %v", err)
+		return nil, err
+	}
+	months := defaultTrendMonths
+	if filter != nil && filter.Months != nil {
+		months = *filter.Months
+	}
+
+	now := time.Now()
+	subscribers, err := s.subsrepo.CountSubscribers(ctx, id, now)
+	if err != nil {
+		s.logger.Errorf("CountSubscribers failed: %v", err)
+		return nil, err
+	}
+	to := billing.MonthStart(now)
+	subs, err := s.subsrepo.FindForSum(ctx, &repository.SubscriptionQuery{ServiceID: &id, Start: &to, End: &to, WithCharges: true})
+	if err != nil {
+		s.logger.Errorf("FindForSum failed: %v", err)
+		return nil, err
+	}
+	prices := priceStats(subs, now)
+	trend, err := s.subsrepo.SubscriberTrend(ctx, id, to.AddDate(0, 1-months, 0), to)
+	if err != nil {
+		s.logger.Errorf("SubscriberTrend failed: %v", err)
+		return nil, err
+	}
+
+	return &models.ServiceStats{ServiceID: service.ID, ServiceName: service.Name, ActiveSubscribers: int(subscribers), Prices: prices, Trend: trend}, nil
+}
+
+// priceStats собирает по валютам цены подписок, действующих на момент at, годовая цена делится на 12.
+// Выручка - начисления текущего месяца из журнала с налогом всех подписок сервиса, поэтому в ней учтены пробный
+// период, вводная цена, пересчет неполных периодов и отмена посреди месяца
+func priceStats(subs []models.Subscription, at time.Time) []models.PriceStats {
+	byCurrency := map[string]*models.PriceStats{}
+	totals := map[string]float64{}
+	statsOf := func(currency string) *models.PriceStats {
+		if byCurrency[currency] == nil {
+			byCurrency[currency] = &models.PriceStats{Currency: currency}
+		}
+		return byCurrency[currency]
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		for _, charge := range sub.Charges {
+			statsOf(charge.Currency).MonthlyRevenue += charge.Gross
+		}
+		if !activeAt(sub, at) {
+			continue
+		}
+		price := float64(sub.Price)
+		if sub.BillingPeriod == models.BillingAnnual {
+			price /= 12
+		}
+		monthly := int64(math.Round(price))
+		stats := statsOf(sub.Currency)
+		if stats.Subscriptions == 0 || monthly < stats.Min {
+			stats.Min = monthly
+		}
+		if monthly > stats.Max {
+			stats.Max = monthly
+		}
+		stats.Subscriptions++
+		totals[sub.Currency] += price
+	}
+
+	res := make([]models.PriceStats, 0, len(byCurrency))
+	for currency, stats := range byCurrency {
+		if stats.Subscriptions > 0 {
+			stats.Average = int64(math.Round(totals[currency] / float64(stats.Subscriptions)))
+		}
+		res = append(res, *stats)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Currency < res[j].Currency })
+	return res
+}
+
+// activeAt - подписка действует на момент at: начата, не закончилась и не на паузе, как в CountSubscribers
+func activeAt(sub *models.Subscription, at time.Time) bool {
+	if sub.Status != models.StatusTrial && sub.Status != models.StatusActive && sub.Status != models.StatusCancelled {
+		return false
+	}
+	return !sub.StartDate.After(at) && (sub.EndDate == nil || !sub.EndDate.Before(at))
+}
+
+func buildAliases(names []string) []models.ServiceAlias { //названия без повторов и пробелов по краям
+	aliases := make([]models.ServiceAlias, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		aliases = append(aliases, models.ServiceAlias{Alias: name})
+	}
+	return aliases
+}
@@ -3,33 +3,61 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"subscriptions/billing"
+	"subscriptions/events"
 	"subscriptions/models"
 	"subscriptions/repository"
+	"subscriptions/storage"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-var ErrInvalidDate error
+var ErrInvalidDate = errors.New("invalid date")
+
+var ErrInvalidAsOf = errors.New("invalid as_of date, expected YYYY-MM-DD")
+
+var ErrShareWithoutUser = errors.New("cost_basis=share requires user_id")
 
 type SubscriptionServiceInterface interface {
 	Create(ctx context.Context, subscription *models.CreateSubscription) (*models.Subscription, error)
 	GetById(ctx context.Context, id uint) (*models.Subscription, error)
-	GetAll(ctx context.Context) ([]models.Subscription, error)
+	GetAll(ctx context.Context, filter *models.ListFilter) ([]models.Subscription, error)
 	Update(ctx context.Context, id uint, subscription *models.UpdateSubscription) (*models.Subscription, error)
 	Delete(ctx context.Context, id uint) error
-	SumByFilters(ctx context.Context, filters *models.SumFilter) (int, error)
+	Restore(ctx context.Context, id uint) (*models.Subscription, error)
+	PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error)
+	History(ctx context.Context, id uint) ([]models.SubscriptionVersion, error)
+	ChangeStatus(ctx context.Context, id uint, action string, request *models.TransitionRequest) (*models.Subscription, error)
+	Cancel(ctx context.Context, id uint, request *models.CancelRequest) (*models.Subscription, error)
+	Approvals(ctx context.Context, filter *models.ApprovalFilter) ([]models.Subscription, error)
+	OffersEnding(ctx context.Context, filter *models.OffersFilter) ([]models.OfferEnding, error)
+	RenewalDeadlines(ctx context.Context, filter *models.DeadlinesFilter) ([]models.RenewalDeadline, error)
+	RenewDue(ctx context.Context) (int, error)
+	SumByFilters(ctx context.Context, filters *models.SumFilter) (*models.SumResult, error)
+	Forecast(ctx context.Context, filter *models.ForecastFilter) (*models.Forecast, error)
+	Simulate(ctx context.Context, request *models.SimulationRequest) (*models.SimulationResult, error)
+	Charges(ctx context.Context, id uint) ([]models.Charge, error)
+	RebuildCharges(ctx context.Context) (int, error)
 }
 
 type SubscriptionService struct {
 	subsrepo    repository.SubscriptionRepoInterface
 	servicerepo repository.ServiceRepoInterface
+	costcenters repository.CostCenterRepoInterface
+	blobs       storage.BlobStore
+	audit       AuditServiceInterface
+	budgets     BudgetServiceInterface
+	publisher   events.Publisher
+	approval    ApprovalConfig
 	logger      *zap.SugaredLogger
 }
 
-func NewSubscriptionService(subsrepo repository.SubscriptionRepoInterface, servicerepo repository.ServiceRepoInterface, logger *zap.SugaredLogger) SubscriptionServiceInterface {
-	return &SubscriptionService{subsrepo: subsrepo, servicerepo: servicerepo, logger: logger}
+func NewSubscriptionService(subsrepo repository.SubscriptionRepoInterface, servicerepo repository.ServiceRepoInterface, costcenters repository.CostCenterRepoInterface, blobs storage.BlobStore, audit AuditServiceInterface, budgets BudgetServiceInterface, publisher events.Publisher, approval ApprovalConfig, logger *zap.SugaredLogger) SubscriptionServiceInterface {
+	return &SubscriptionService{subsrepo: subsrepo, servicerepo: servicerepo, costcenters: costcenters, blobs: blobs, audit: audit, budgets: budgets, publisher: publisher, approval: approval, logger: logger}
 }
 
 func (s *SubscriptionService) Create(ctx context.Context, subscription *models.CreateSubscription) (*models.Subscription, error) {
@@ -41,41 +69,124 @@ func (s *SubscriptionService) Create(ctx context.Context, subscription *models.C
 				s.logger.Errorf("Create service failed: %v", err)
 				return nil, err
 			}
+			s.audit.Record(ctx, models.AuditEntityService, service.ID, models.AuditActionCreate, nil, service)
 		} else {
 			s.logger.Errorf("GetByName service failed: %v", err)
 			return nil, err
 		}
 	}
 
-	startDate, err := time.Parse("01-2006", subscription.StartDate)
+	startDate, _, err := parseDate(subscription.StartDate)
 	if err != nil {
 		s.logger.Errorf("Parsing start date failed: %v", err)
 		return nil, err
 	}
 
-	var endDate *time.Time
+	anchorDay := startDate.Day() //списания в день начала, для MM-YYYY - первого числа
+	if subscription.AnchorDay != nil {
+		anchorDay = int(*subscription.AnchorDay)
+	}
+
+	price := models.MinorOrLegacy(subscription.PriceMinor, subscription.Price)
+	if price == nil {
+		s.logger.Error(ErrInvalidPrice)
+		return nil, ErrInvalidPrice
+	}
+	promoPrice := models.MinorOrLegacy(subscription.PromoPriceMinor, subscription.PromoPrice)
+	if (promoPrice == nil) != (subscription.PromoMonths == nil) { //вводная цена задается вместе с длительностью
+		s.logger.Error(ErrInvalidPrice)
+		return nil, ErrInvalidPrice
+	}
+
+	sub := &models.Subscription{ServiceID: service.ID, UserID: subscription.UserID, StartDate: startDate, Price: *price, Currency: models.DefaultCurrency, AnchorDay: uint8(anchorDay),
+		BillingPeriod: models.BillingMonthly, ProrationMode: models.ProrationNone}
+	if subscription.Currency != nil {
+		sub.Currency = *subscription.Currency
+	}
+	sub.TaxRate, sub.TaxInclusive = subscription.TaxRate, subscription.TaxInclusive
+	if subscription.BillingPeriod != nil {
+		sub.BillingPeriod = *subscription.BillingPeriod
+	}
+	if subscription.ProrationMode != nil {
+		sub.ProrationMode = *subscription.ProrationMode
+	}
+	applyTerms(sub, &subscription.AutoRenew, subscription.MinimumTermMonths, subscription.RenewalTermMonths, subscription.NoticeDays)
+
 	if subscription.EndDate != nil {
-		endDateParse, err := time.Parse("01-2006", *subscription.EndDate)
+		endDate, err := parseEndDate(*subscription.EndDate, sub)
 		if err != nil {
 			s.logger.Errorf("Parsing end date failed: %v", err)
 			return nil, err
 		}
-		endDate = &endDateParse
 
-		if endDate.Before(startDate) { //конец не должен быть раньше начала
-			ErrInvalidDate = errors.New("end date must be after start date")
-			s.logger.Error(ErrInvalidDate)
-			return nil, ErrInvalidDate
+		if err = checkEndDate(sub, endDate); err != nil {
+			s.logger.Error(err)
+			return nil, err
+		}
+		sub.EndDate = &endDate
+	}
+
+	status := models.StatusActive
+	if subscription.Status != nil {
+		status = *subscription.Status
+	}
+
+	firstPaidMonth := billing.FirstPaidMonth(sub.Billing(), startDate)
+	if subscription.TrialDays != nil { //с пробным периодом подписка всегда начинается в статусе trial
+		trialEnd := startDate.AddDate(0, 0, int(*subscription.TrialDays))
+		sub.TrialEndDate = &trialEnd
+		status = models.StatusTrial
+		firstPaidMonth = billing.FirstPaidMonth(sub.Billing(), trialEnd)
+	}
+	if promoPrice != nil { //вводная цена действует первые оплачиваемые списания
+		promoEnd := billing.ChargeDate(firstPaidMonth.AddDate(0, int(*subscription.PromoMonths), 0), anchorDay)
+		sub.PromoPrice = promoPrice
+		sub.PromoEndDate = &promoEnd
+	}
+	if s.approval.Required { //пробный период и вводная цена сохраняются до одобрения
+		status = models.StatusPendingApproval
+	}
+	sub.Status = status
+
+	if len(subscription.Members) > 0 {
+		members, err := buildMembers(subscription.UserID, sub.Price, subscription.Members)
+		if err != nil {
+			s.logger.Error(err)
+			return nil, err
+		}
+		sub.Members = members
+	}
+	if len(subscription.Allocations) > 0 {
+		allocations, err := s.buildAllocations(ctx, subscription.Allocations)
+		if err != nil {
+			s.logger.Error(err)
+			return nil, err
+		}
+		sub.Allocations = allocations
+	}
+	if len(subscription.Tags) > 0 { //метки создаются после всех проверок
+		tags, err := s.findTags(ctx, subscription.Tags)
+		if err != nil {
+			return nil, err
 		}
+		sub.Tags = tags
 	}
+	sub.Notes = notesOf(subscription.Notes)
+	sub.Transitions = []models.StatusTransition{{To: status, Date: billing.MonthStart(startDate)}} //начальный статус действует с месяца начала, как и остальные переходы
 
-	sub := &models.Subscription{ServiceID: service.ID, UserID: subscription.UserID, StartDate: startDate, EndDate: endDate, Price: *subscription.Price}
+	sub.Service = *service //ставка налога сервиса нужна для строк журнала
 	s.logger.Infof("Creating subscription: %+v", sub)
-	err = s.subsrepo.Create(ctx, sub)
+	err = s.subsrepo.Create(ctx, sub, currentLedger())
 	if err != nil {
 		s.logger.Errorf("Create subscription failed: %v", err)
 		return nil, err
 	}
+	s.audit.Record(ctx, models.AuditEntitySubscription, sub.ID, models.AuditActionCreate, nil, sub)
+	if sub.Status == models.StatusPendingApproval {
+		s.publishTransition(ctx, events.TypeSubscriptionSubmitted, sub, &sub.Transitions[0])
+	}
+	sub.Warnings = s.budgets.CheckSubscription(ctx, nil, sub) //превышение бюджета не мешает созданию, только предупреждает
+	withDeadline(sub, billing.DayStart(time.Now()))
 	return sub, nil
 }
 
@@ -85,15 +196,39 @@ func (s *SubscriptionService) GetById(ctx context.Context, id uint) (*models.Sub
 		s.logger.Errorf("GetById subscription failed: %v", err)
 		return nil, err
 	}
+	withDeadline(res, billing.DayStart(time.Now()))
 	return res, nil
 }
 
-func (s *SubscriptionService) GetAll(ctx context.Context) ([]models.Subscription, error) {
-	res, err := s.subsrepo.GetAll(ctx)
+func (s *SubscriptionService) GetAll(ctx context.Context, filter *models.ListFilter) ([]models.Subscription, error) {
+	if filter != nil && filter.Tag != nil {
+		normalized := *filter
+		normalized.Tag = normalizeTag(filter.Tag)
+		filter = &normalized
+	}
+	if filter != nil && filter.AsOf != nil {
+		asOf, err := parseAsOf(*filter.AsOf)
+		if err != nil {
+			s.logger.Errorf("Parsing as_of failed: %v", err)
+			return nil, err
+		}
+		res, err := s.subsrepo.GetAllAsOf(ctx, filter, asOf)
+		if err != nil {
+			s.logger.Errorf("GetAllAsOf subscriptions failed: %v", err)
+			return nil, err
+		}
+		return res, nil
+	}
+
+	res, err := s.subsrepo.GetAll(ctx, filter)
 	if err != nil {
 		s.logger.Errorf("GetAll subscriptions failed: %v", err)
 		return nil, err
 	}
+	today := billing.DayStart(time.Now())
+	for i := range res {
+		withDeadline(&res[i], today)
+	}
 	return res, nil
 }
 
@@ -103,82 +238,298 @@ func (s *SubscriptionService) Update(ctx context.Context, id uint, update *model
 		s.logger.Errorf("GetById subscription failed: %v", err)
 		return nil, err
 	}
+	before := *sub //снимок до изменений для журнала
+
+	if price := models.MinorOrLegacy(update.PriceMinor, update.Price); price != nil {
+		sub.Price = *price
+	}
 
-	if update.Price != nil {
-		sub.Price = *update.Price
+	if update.ProrationMode != nil {
+		sub.ProrationMode = *update.ProrationMode
 	}
 
+	if update.TaxRate != nil {
+		sub.TaxRate = update.TaxRate
+	}
+	if update.TaxInclusive != nil {
+		sub.TaxInclusive = update.TaxInclusive
+	}
+	if update.Notes != nil {
+		sub.Notes = notesOf(update.Notes)
+	}
+	applyTerms(sub, update.AutoRenew, update.MinimumTermMonths, update.RenewalTermMonths, update.NoticeDays)
+
 	if update.EndDate != nil {
-		endDate, err := time.Parse("01-2006", *update.EndDate)
+		endDate, err := parseEndDate(*update.EndDate, sub)
 		if err != nil {
 			s.logger.Errorf("Parsing end date failed: %v", err)
 			return nil, err
 		}
-		if endDate.Before(sub.StartDate) { //конец не должен быть раньше начала
-			ErrInvalidDate = errors.New("end date must be after start date")
-			s.logger.Error(ErrInvalidDate)
-			return nil, ErrInvalidDate
+		if err = checkEndDate(sub, endDate); err != nil {
+			s.logger.Error(err)
+			return nil, err
 		}
 		sub.EndDate = &endDate
 	}
 
-	s.logger.Infof("Updating subscription: %+v", sub)
+	inputs := memberInputs(sub.Members)
+	if update.Members != nil {
+		inputs = *update.Members
+	}
+	members, err := buildMembers(sub.UserID, sub.Price, inputs) //доли проверяем и при смене цены
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	details := &repository.SubscriptionDetails{} //участники, метки и центры затрат заменяются в одной транзакции с подпиской
+	if update.Members != nil {
+		details.Members = &members
+	}
+	if update.Allocations != nil {
+		allocations, err := s.buildAllocations(ctx, *update.Allocations)
+		if err != nil {
+			s.logger.Error(err)
+			return nil, err
+		}
+		details.Allocations = &allocations
+	}
+	if update.Tags != nil { //метки создаются только после всех проверок, в справочнике они общие для всех подписок
+		tags, err := s.findTags(ctx, *update.Tags)
+		if err != nil {
+			return nil, err
+		}
+		details.Tags = &tags
+	}
 
-	err = s.subsrepo.Update(ctx, sub)
+	s.logger.Infof("Updating subscription: %+v", sub)
+	err = s.subsrepo.Update(ctx, sub, details, currentLedger())
 	if err != nil {
 		s.logger.Errorf("Update subscription failed: %v", err)
 		return nil, err
 	}
+	if details.Members != nil {
+		sub.Members = *details.Members
+	}
+	if details.Tags != nil {
+		sub.Tags = *details.Tags
+	}
+	if details.Allocations != nil {
+		sub.Allocations = *details.Allocations
+	}
+	s.audit.Record(ctx, models.AuditEntitySubscription, sub.ID, models.AuditActionUpdate, &before, sub)
+	sub.Warnings = s.budgets.CheckSubscription(ctx, &before, sub)
+	withDeadline(sub, billing.DayStart(time.Now()))
 	return sub, nil
 }
 
 func (s *SubscriptionService) Delete(ctx context.Context, id uint) error {
-	err := s.subsrepo.Delete(ctx, id)
+	before, err := s.subsrepo.GetById(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetById subscription failed: %v", err)
+		return err
+	}
+
+	err = s.subsrepo.Delete(ctx, id)
 	if err != nil {
 		s.logger.Errorf("Delete subscription failed: %v", err)
 		return err
 	}
+	s.audit.Record(ctx, models.AuditEntitySubscription, id, models.AuditActionDelete, before, nil)
 	return nil
 }
 
-func (s *SubscriptionService) SumByFilters(ctx context.Context, filters *models.SumFilter) (int, error) {
+func (s *SubscriptionService) Restore(ctx context.Context, id uint) (*models.Subscription, error) {
+	err := s.subsrepo.Restore(ctx, id, currentLedger())
+	if err != nil {
+		s.logger.Errorf("Restore subscription failed: %v", err)
+		return nil, err
+	}
+	s.logger.Infof("Restored subscription: %d", id)
+
+	sub, err := s.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, models.AuditEntitySubscription, id, models.AuditActionRestore, nil, sub)
+	sub.Warnings = s.budgets.CheckSubscription(ctx, nil, sub) //восстановленная подписка снова тратит бюджет
+	return sub, nil
+}
+
+func (s *SubscriptionService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	before := time.Now().Add(-retention) //удаляем записи, удаленные раньше этого момента
+	count, keys, err := s.subsrepo.PurgeDeleted(ctx, before)
+	if err != nil {
+		s.logger.Errorf("Purge deleted subscriptions failed: %v", err)
+		return 0, err
+	}
+	for _, key := range keys { //записи вложений уже удалены, файл останется лишь в хранилище
+		if err = s.blobs.Delete(ctx, key); err != nil {
+			s.logger.Errorf("Delete attachment %s from storage failed: %v", key, err)
+		}
+	}
+	s.logger.Infof("Purged deleted subscriptions: %d", count)
+	return count, nil
+}
+
+func (s *SubscriptionService) History(ctx context.Context, id uint) ([]models.SubscriptionVersion, error) {
+	res, err := s.subsrepo.GetHistory(ctx, id)
+	if err != nil {
+		s.logger.Errorf("GetHistory subscription failed: %v", err)
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return res, nil
+}
+
+func (s *SubscriptionService) SumByFilters(ctx context.Context, filters *models.SumFilter) (*models.SumResult, error) {
 	var startDate, endDate *time.Time
 
 	if filters == nil {
 		s.logger.Error("SumByFilters failed: filters is nil")
-		return 0, errors.New("filters is nil")
+		return nil, errors.New("filters is nil")
 	}
 
 	if filters.StartDate != nil {
-		start, err := time.Parse("01-2006", *filters.StartDate)
+		start, err := parseMonth(*filters.StartDate)
 		if err != nil {
 			s.logger.Errorf("Parsing start date failed: %v", err)
-			return 0, err
+			return nil, err
 		}
 		startDate = &start
 	}
 
 	if filters.EndDate != nil {
-		end, err := time.Parse("01-2006", *filters.EndDate)
+		end, err := parseMonth(*filters.EndDate)
 		if err != nil {
 			s.logger.Errorf("Parsing end date failed: %v", err)
-			return 0, err
+			return nil, err
 		}
 		endDate = &end
 	}
 
 	if startDate != nil && endDate != nil && startDate.After(*endDate) { //конец не должен быть раньше начала
-		ErrInvalidDate = errors.New("end date must be after start date")
-		s.logger.Error(ErrInvalidDate)
-		return 0, ErrInvalidDate
+		err := fmt.Errorf("%w: end date must be after start date", ErrInvalidDate)
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	byShare := filters.CostBasis != nil && *filters.CostBasis == models.CostBasisShare
+	if byShare && filters.UserID == nil { //доля считается для конкретного участника, иначе сумма молча была бы полной ценой
+		s.logger.Error(ErrShareWithoutUser)
+		return nil, ErrShareWithoutUser
 	}
 
 	s.logger.Infof("SumByFilters: %+v", filters)
 
-	res, err := s.subsrepo.SumByFilters(ctx, filters.UserID, filters.ServiceName, startDate, endDate)
+	var asOf *time.Time
+	periodEnd := billing.MonthStart(time.Now()) //без конца периода считаем по текущий месяц
+	if filters.AsOf != nil {
+		parsed, err := parseAsOf(*filters.AsOf)
+		if err != nil {
+			s.logger.Errorf("Parsing as_of failed: %v", err)
+			return nil, err
+		}
+		asOf = &parsed
+		periodEnd = billing.MonthStart(parsed.AddDate(0, 0, -1))
+	}
+	if endDate != nil {
+		periodEnd = *endDate
+	}
+	var periodStart time.Time
+	if startDate != nil {
+		periodStart = *startDate
+	}
+
+	query := &repository.SubscriptionQuery{
+		UserID:      filters.UserID,
+		ServiceName: filters.ServiceName,
+		Start:       startDate,
+		End:         endDate,
+		AsOf:        asOf,
+		Currency:    filters.Currency,
+		Tag:         normalizeTag(filters.Tag),
+		WithShared:  byShare,
+		WithPending: filters.IncludePending,
+	}
+	if asOf == nil { //текущие суммы берутся из журнала начислений, срезы as_of считаются по версиям
+		query.End = &periodEnd
+		query.WithCharges = true
+	}
+	subs, err := s.subsrepo.FindForSum(ctx, query)
 	if err != nil {
-		s.logger.Errorf("SumByFilters failed: %v", err)
-		return 0, err
+		s.logger.Errorf("FindForSum failed: %v", err)
+		return nil, err
+	}
+
+	currency, err := sumCurrency(subs, filters.Currency)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, err
+	}
+
+	var userID *string
+	if byShare { //только доля пользователя, в том числе в чужих совместных подписках
+		userID = filters.UserID
+	}
+	if asOf != nil { //журнал хранит текущие условия, срез строит такие же строки по версиям на момент as_of
+		for i := range subs {
+			subs[i].Charges = ledgerBetween(&subs[i], periodStart, periodEnd)
+		}
+	}
+	res := &models.SumResult{Money: models.Money{Currency: currency}, Prorations: prorations(subs)}
+	res.Amount, res.TaxTotals = ledgerTotals(subs, userID)
+	return res, nil
+}
+
+// prorations возвращает неполные периоды из начислений подписок по месяцам и подпискам
+func prorations(subs []models.Subscription) []models.Proration {
+	var res []models.Proration
+	for i := range subs {
+		for _, charge := range subs[i].Charges {
+			if charge.Proration != nil {
+				res = append(res, *charge.Proration)
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].Month.Equal(res[j].Month) {
+			return res[i].Month.Before(res[j].Month)
+		}
+		return res[i].SubscriptionID < res[j].SubscriptionID
+	})
+	return res
+}
+
+func parseAsOf(value string) (time.Time, error) { //момент среза - конец указанного дня
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, ErrInvalidAsOf
+	}
+	return day.AddDate(0, 0, 1), nil
+}
+
+func (s *SubscriptionService) OffersEnding(ctx context.Context, filter *models.OffersFilter) ([]models.OfferEnding, error) {
+	from := time.Now()
+	to := from.AddDate(0, 0, filter.Within)
+
+	subs, err := s.subsrepo.FindOffersEnding(ctx, from, to)
+	if err != nil {
+		s.logger.Errorf("FindOffersEnding failed: %v", err)
+		return nil, err
+	}
+
+	res := make([]models.OfferEnding, 0, len(subs))
+	for _, sub := range subs {
+		if sub.TrialEndDate != nil && !sub.TrialEndDate.Before(from) && !sub.TrialEndDate.After(to) {
+			res = append(res, models.OfferEnding{Kind: "trial", EndsAt: *sub.TrialEndDate, NextPrice: billing.PriceAt(sub.Billing(), *sub.TrialEndDate), Subscription: sub})
+		}
+		if sub.PromoEndDate != nil && !sub.PromoEndDate.Before(from) && !sub.PromoEndDate.After(to) {
+			res = append(res, models.OfferEnding{Kind: "promo", EndsAt: *sub.PromoEndDate, NextPrice: sub.Price, Subscription: sub})
+		}
 	}
+	sort.Slice(res, func(i, j int) bool { return res[i].EndsAt.Before(res[j].EndsAt) })
 	return res, nil
 }
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"unicode"
+
+	"go.uber.org/zap"
+)
+
+var ErrSuggestionNotFound = errors.New("suggestion not found")
+
+type SuggestionServiceInterface interface {
+	List(ctx context.Context, filter *models.SuggestionFilter) ([]models.Suggestion, error)
+	Accept(ctx context.Context, accept *models.AcceptSuggestion) (*models.Subscription, error)
+}
+
+type SuggestionService struct {
+	repo          repository.StatementRepoInterface
+	subsrepo      repository.SubscriptionRepoInterface
+	servicerepo   repository.ServiceRepoInterface
+	subscriptions SubscriptionServiceInterface
+	logger        *zap.SugaredLogger
+}
+
+func NewSuggestionService(repo repository.StatementRepoInterface, subsrepo repository.SubscriptionRepoInterface, servicerepo repository.ServiceRepoInterface, subscriptions SubscriptionServiceInterface, logger *zap.SugaredLogger) SuggestionServiceInterface {
+	return &SuggestionService{repo: repo, subsrepo: subsrepo, servicerepo: servicerepo, subscriptions: subscriptions, logger: logger}
+}
+
+// List ищет во всех загруженных выписках пользователя регулярные списания, которые не связаны с подписками
+// и получатель которых не похож ни на одну из его подписок
+func (s *SuggestionService) List(ctx context.Context, filter *models.SuggestionFilter) ([]models.Suggestion, error) {
+	amountTolerance := defaultAmountTolerance
+	if filter.AmountTolerance != nil {
+		amountTolerance = *filter.AmountTolerance
+	}
+
+	transactions, err := s.repo.FindUnmatched(ctx, filter.UserID)
+	if err != nil {
+		s.logger.Errorf("FindUnmatched failed: %v", err)
+		return nil, err
+	}
+	subs, err := s.subsrepo.GetAll(ctx, &models.ListFilter{UserID: &filter.UserID})
+	if err != nil {
+		s.logger.Errorf("GetAll subscriptions failed: %v", err)
+		return nil, err
+	}
+	services, err := s.servicerepo.GetAll(ctx)
+	if err != nil {
+		s.logger.Errorf("GetAll services failed: %v", err)
+		return nil, err
+	}
+
+	names := serviceNames(services)
+	tracked := map[uint][]string{}
+	for _, sub := range subs {
+		tracked[sub.ID] = names[sub.ServiceID]
+	}
+
+	suggestions := []models.Suggestion{}
+	for _, payment := range untracked(transactions, tracked, amountTolerance) {
+		suggestions = append(suggestions, models.Suggestion{RecurringPayment: payment, ServiceName: serviceNameOf(payment.Merchant)})
+	}
+	return suggestions, nil
+}
+
+// Accept создает подписку по предложению тем же путем, что и POST /subs: сервис создается, если его еще нет.
+// Подписка начинается с первого списания, а к ней привязываются все списания получателя из всех выписок:
+// повторы из пересекающихся выписок не сохраняются, поэтому непривязанных копий не остается и предложение не вернется
+func (s *SuggestionService) Accept(ctx context.Context, accept *models.AcceptSuggestion) (*models.Subscription, error) {
+	suggestions, err := s.List(ctx, &models.SuggestionFilter{UserID: accept.UserID, AmountTolerance: accept.AmountTolerance})
+	if err != nil {
+		return nil, err
+	}
+	var suggestion *models.Suggestion
+	for i := range suggestions {
+		if suggestions[i].Merchant == accept.Merchant && suggestions[i].Currency == accept.Currency {
+			suggestion = &suggestions[i]
+		}
+	}
+	if suggestion == nil {
+		s.logger.Warnf("Suggestion %q in %s not found for user %s", accept.Merchant, accept.Currency, accept.UserID)
+		return nil, ErrSuggestionNotFound
+	}
+
+	price := suggestion.Amount
+	if accept.PriceMinor != nil {
+		price = *accept.PriceMinor
+	}
+	serviceName := suggestion.ServiceName
+	if accept.ServiceName != nil && strings.TrimSpace(*accept.ServiceName) != "" {
+		serviceName = strings.TrimSpace(*accept.ServiceName)
+	}
+	billingPeriod := models.BillingMonthly
+	if suggestion.Cadence == models.CadenceAnnual {
+		billingPeriod = models.BillingAnnual
+	}
+
+	sub, err := s.subscriptions.Create(ctx, &models.CreateSubscription{
+		ServiceName:   serviceName,
+		PriceMinor:    &price,
+		Currency:      &suggestion.Currency,
+		UserID:        accept.UserID,
+		StartDate:     suggestion.Transactions[0].Date.Format("2006-01-02"),
+		BillingPeriod: &billingPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(suggestion.Transactions))
+	for _, transaction := range suggestion.Transactions {
+		ids = append(ids, transaction.ID)
+	}
+	if err = s.repo.LinkTransactions(ctx, ids, sub.ID); err != nil { //подписка уже создана, предложение и так исчезнет по названию
+		s.logger.Errorf("LinkTransactions failed: %v", err)
+	}
+	s.logger.Infof("Accepted suggestion %q as subscription %d", suggestion.Merchant, sub.ID)
+	return sub, nil
+}
+
+// serviceNameOf предлагает название сервиса по получателю: "yandex plus" -> "Yandex Plus"
+func serviceNameOf(merchant string) string {
+	words := strings.Fields(merchant)
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"subscriptions/models"
+)
+
+// tagNames приводит метки к виду, в котором они хранятся: без пробелов по краям, в нижнем регистре и без повторов
+func tagNames(names []string) []string {
+	seen := map[string]bool{}
+	res := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		res = append(res, name)
+	}
+	return res
+}
+
+func normalizeTag(tag *string) *string { //фильтр по метке сравнивается с хранимым названием
+	if tag == nil {
+		return nil
+	}
+	normalized := strings.ToLower(strings.TrimSpace(*tag))
+	return &normalized
+}
+
+func (s *SubscriptionService) findTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	names = tagNames(names)
+	if len(names) == 0 {
+		return []models.Tag{}, nil
+	}
+	tags, err := s.subsrepo.FindOrCreateTags(ctx, names)
+	if err != nil {
+		s.logger.Errorf("FindOrCreateTags failed: %v", err)
+		return nil, err
+	}
+	return tags, nil
+}
+
+func notesOf(notes *string) *string { //пустые заметки не храним
+	if notes == nil || strings.TrimSpace(*notes) == "" {
+		return nil
+	}
+	return notes
+}
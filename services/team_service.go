@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"subscriptions/models"
+	"subscriptions/repository"
+
+	"go.uber.org/zap"
+)
+
+type TeamServiceInterface interface {
+	Create(ctx context.Context, team *models.CreateTeam) (*models.Team, error)
+	GetAll(ctx context.Context) ([]models.Team, error)
+}
+
+type TeamService struct {
+	repo   repository.TeamRepoInterface
+	logger *zap.SugaredLogger
+}
+
+func NewTeamService(repo repository.TeamRepoInterface, logger *zap.SugaredLogger) TeamServiceInterface {
+	return &TeamService{repo: repo, logger: logger}
+}
+
+func (s *TeamService) Create(ctx context.Context, team *models.CreateTeam) (*models.Team, error) {
+	newTeam := &models.Team{Name: team.Name}
+	seen := map[string]bool{}
+	for _, userID := range team.UserIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		newTeam.Members = append(newTeam.Members, models.TeamMember{UserID: userID})
+	}
+
+	s.logger.Infof("Create team: %s", newTeam.Name)
+	if err := s.repo.Create(ctx, newTeam); err != nil {
+		s.logger.Errorf("Create team failed: %v", err)
+		return nil, err
+	}
+	return newTeam, nil
+}
+
+func (s *TeamService) GetAll(ctx context.Context) ([]models.Team, error) {
+	res, err := s.repo.GetAll(ctx)
+	if err != nil {
+		s.logger.Errorf("GetAll teams failed: %v", err)
+		return nil, err
+	}
+	return res, nil
+}
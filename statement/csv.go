@@ -0,0 +1,108 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"subscriptions/models"
+)
+
+// названия колонок, под которыми банки выгружают нужные поля
+var csvColumnNames = map[string][]string{
+	"date":        {"date", "дата", "дата операции", "transaction date", "posted date", "booking date"},
+	"description": {"description", "описание", "описание операции", "назначение платежа", "merchant", "payee", "name", "details"},
+	"amount":      {"amount", "сумма", "сумма операции"},
+	"currency":    {"currency", "валюта", "валюта операции"},
+}
+
+// parseCSV разбирает выписку с заголовком. Разделитель - запятая или точка с запятой, списания - отрицательные суммы
+func parseCSV(data []byte, currency string) ([]models.BankTransaction, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = csvDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty file")
+	}
+	columns, err := csvColumns(records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]models.BankTransaction, 0, len(records)-1)
+	for i, record := range records[1:] {
+		line := i + 2
+		if isBlank(record) {
+			continue
+		}
+		field := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		date, err := parseDate(field("date"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		amount, err := parseAmount(field("amount"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		transaction := models.BankTransaction{Date: date, Description: field("description"), Amount: amount, Currency: currency}
+		if value := field("currency"); value != "" {
+			transaction.Currency = strings.ToUpper(value)
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+func csvDelimiter(data []byte) rune { //по первой строке: в русских выгрузках обычно точка с запятой
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+func csvColumns(header []string) (map[string]int, error) { //номера нужных колонок по заголовку
+	columns := map[string]int{}
+	for index, title := range header {
+		title = strings.ToLower(strings.TrimSpace(title))
+		for name, variants := range csvColumnNames {
+			if _, found := columns[name]; found {
+				continue
+			}
+			for _, variant := range variants {
+				if title == variant {
+					columns[name] = index
+				}
+			}
+		}
+	}
+	for _, name := range []string{"date", "description", "amount"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %q not found in header", name)
+		}
+	}
+	return columns, nil
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package statement
+
+import (
+	"fmt"
+	"strings"
+	"subscriptions/models"
+)
+
+// parseOFX разбирает выписку OFX: и SGML версии 1.x с незакрытыми тегами, и XML версии 2.x
+func parseOFX(data []byte, currency string) ([]models.BankTransaction, error) {
+	text := string(data)
+	if value := ofxValue(text, "CURDEF"); value != "" {
+		currency = strings.ToUpper(value)
+	}
+
+	var transactions []models.BankTransaction
+	blocks := strings.Split(text, "<STMTTRN>")
+	for i, block := range blocks[1:] {
+		block, _, _ = strings.Cut(block, "</STMTTRN>")
+
+		posted := ofxValue(block, "DTPOSTED")
+		if len(posted) < 8 {
+			return nil, fmt.Errorf("transaction %d: invalid DTPOSTED %q", i+1, posted)
+		}
+		date, err := parseDate(posted[:4] + "-" + posted[4:6] + "-" + posted[6:8]) //YYYYMMDD[HHMMSS[.XXX]][[TZ]]
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		amount, err := parseAmount(ofxValue(block, "TRNAMT"))
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		description := ofxValue(block, "NAME")
+		if description == "" {
+			description = ofxValue(block, "MEMO")
+		}
+
+		transactions = append(transactions, models.BankTransaction{
+			ExternalID:  ofxValue(block, "FITID"),
+			Date:        date,
+			Description: description,
+			Amount:      amount,
+			Currency:    currency,
+		})
+	}
+	return transactions, nil
+}
+
+// ofxValue возвращает значение тега: текст после него до следующего тега
+func ofxValue(text, tag string) string {
+	_, value, found := strings.Cut(text, "<"+tag+">")
+	if !found {
+		return ""
+	}
+	value, _, _ = strings.Cut(value, "<")
+	return strings.TrimSpace(value)
+}
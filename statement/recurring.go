@@ -0,0 +1,93 @@
+package statement
+
+import (
+	"sort"
+	"subscriptions/models"
+)
+
+// интервалы между списаниями в днях, при которых платежи считаются ежемесячными или ежегодными
+const (
+	monthlyMinDays = 26
+	monthlyMaxDays = 35
+	annualMinDays  = 350
+	annualMaxDays  = 380
+)
+
+// DetectRecurring ищет получателей, которые списывают деньги с регулярным интервалом (раз в месяц или раз в год)
+// и примерно одну и ту же сумму: отклонение от последнего списания не больше amountTolerance процентов.
+// Учитываются только списания, результат отсортирован по получателю
+func DetectRecurring(transactions []models.BankTransaction, amountTolerance float64) []models.RecurringPayment {
+	type key struct{ merchant, currency string }
+	groups := map[key][]models.BankTransaction{}
+	for _, transaction := range transactions {
+		if transaction.Debit() == 0 || transaction.Merchant == "" {
+			continue
+		}
+		k := key{transaction.Merchant, transaction.Currency}
+		groups[k] = append(groups[k], transaction)
+	}
+
+	var payments []models.RecurringPayment
+	for k, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+
+		cadence := cadenceOf(group)
+		last := group[len(group)-1]
+		if cadence == "" || !similarAmounts(group, last.Debit(), amountTolerance) {
+			continue
+		}
+		payments = append(payments, models.RecurringPayment{
+			Merchant:     k.merchant,
+			Description:  last.Description,
+			Cadence:      cadence,
+			Amount:       last.Debit(),
+			Currency:     k.currency,
+			Transactions: group,
+		})
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		if payments[i].Merchant != payments[j].Merchant {
+			return payments[i].Merchant < payments[j].Merchant
+		}
+		return payments[i].Currency < payments[j].Currency
+	})
+	return payments
+}
+
+// AmountWithin проверяет, что сумма отличается от ожидаемой не больше чем на tolerance процентов
+func AmountWithin(amount, expected int64, tolerance float64) bool {
+	diff := amount - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) <= float64(expected)*tolerance/100
+}
+
+// cadenceOf возвращает периодичность, если все интервалы между соседними списаниями ей соответствуют
+func cadenceOf(group []models.BankTransaction) string {
+	monthly, annual := true, true
+	for i := 1; i < len(group); i++ {
+		days := int(group[i].Date.Sub(group[i-1].Date).Hours() / 24)
+		monthly = monthly && days >= monthlyMinDays && days <= monthlyMaxDays
+		annual = annual && days >= annualMinDays && days <= annualMaxDays
+	}
+	switch {
+	case monthly:
+		return models.CadenceMonthly
+	case annual:
+		return models.CadenceAnnual
+	}
+	return ""
+}
+
+func similarAmounts(group []models.BankTransaction, expected int64, tolerance float64) bool {
+	for _, transaction := range group {
+		if !AmountWithin(transaction.Debit(), expected, tolerance) {
+			return false
+		}
+	}
+	return true
+}
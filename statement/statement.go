@@ -0,0 +1,131 @@
+// Package statement разбирает банковские выписки в CSV и OFX и ищет в операциях регулярные платежи.
+// Функции чистые и не обращаются к базе
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"subscriptions/models"
+	"time"
+	"unicode"
+)
+
+// DetectFormat определяет формат выписки по расширению файла, а если оно неизвестно - по содержимому
+func DetectFormat(fileName string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".ofx", ".qfx":
+		return models.StatementOFX
+	case ".csv":
+		return models.StatementCSV
+	}
+	head := bytes.ToUpper(data[:min(len(data), 1024)])
+	if bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>")) {
+		return models.StatementOFX
+	}
+	return models.StatementCSV
+}
+
+// Parse разбирает выписку. currency - валюта операций, если в самой выписке она не указана
+func Parse(format string, data []byte, currency string) ([]models.BankTransaction, error) {
+	var (
+		transactions []models.BankTransaction
+		err          error
+	)
+	switch format {
+	case models.StatementCSV:
+		transactions, err = parseCSV(data, currency)
+	case models.StatementOFX:
+		transactions, err = parseOFX(data, currency)
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, fmt.Errorf("no transactions found")
+	}
+	for i := range transactions {
+		transactions[i].Merchant = Merchant(transactions[i].Description)
+	}
+	return transactions, nil
+}
+
+// Merchant приводит описание операции к виду для сравнения: только буквы в нижнем регистре через пробел,
+// поэтому "NETFLIX.COM 12345" и "Netflix.com 67890" дают одно и то же
+func Merchant(description string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(description) {
+		if unicode.IsLetter(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// MatchesName проверяет, что в описании операции целыми словами встречается название сервиса или его псевдоним
+func MatchesName(merchant, name string) bool {
+	name = Merchant(name)
+	if name == "" {
+		return false
+	}
+	return strings.Contains(" "+merchant+" ", " "+name+" ")
+}
+
+// parseAmount переводит сумму из выписки в минимальные единицы без потери копеек: "-1 234,50" -> -123450
+func parseAmount(value string) (int64, error) {
+	value = strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(strings.TrimSpace(value))
+	if strings.Contains(value, ",") && strings.Contains(value, ".") {
+		value = strings.ReplaceAll(value, ",", "") //запятая - разделитель тысяч
+	} else {
+		value = strings.ReplaceAll(value, ",", ".") //запятая - десятичный разделитель
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	whole, frac, _ := strings.Cut(value, ".")
+	if len(frac) > 2 {
+		frac = strings.TrimRight(frac, "0")
+	}
+	if (whole == "" && frac == "") || len(frac) > 2 {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", value)
+		}
+	}
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+var dateLayouts = []string{"2006-01-02", "02.01.2006", "2006-01-02 15:04:05", "02.01.2006 15:04:05", "02.01.2006 15:04", time.RFC3339}
+
+// parseDate разбирает дату операции, время отбрасывается
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,49 @@
+// Package storage хранит файлы вложений. BlobStore реализован для локальной файловой системы
+// и для S3-совместимых хранилищ, выбор - переменной BLOB_STORE
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore - хранилище файлов по ключу вида "subscriptions/1/abc.pdf"
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error) //ErrBlobNotFound, если файла нет
+	Delete(ctx context.Context, key string) error               //удаление отсутствующего файла не ошибка
+}
+
+const defaultLocalDir = "data/attachments"
+
+// FromEnv создает хранилище по переменным окружения: BLOB_STORE=local (по умолчанию, каталог BLOB_LOCAL_DIR)
+// или BLOB_STORE=s3 (S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY)
+func FromEnv() (BlobStore, error) {
+	switch driver := os.Getenv("BLOB_STORE"); driver {
+	case "", "local":
+		dir := os.Getenv("BLOB_LOCAL_DIR")
+		if dir == "" {
+			dir = defaultLocalDir
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		cfg := S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+		if cfg.Endpoint == "" || cfg.Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required for BLOB_STORE=s3")
+		}
+		return NewS3Store(cfg, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", driver)
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultS3Region = "us-east-1"
+
+type S3Config struct {
+	Endpoint  string //например https://s3.amazonaws.com или http://minio:9000
+	Region    string //по умолчанию us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3Store хранит файлы в бакете S3-совместимого хранилища. Адреса в path-style (endpoint/bucket/key),
+// их поддерживают и AWS, и MinIO, и другие совместимые хранилища
+type S3Store struct {
+	cfg    S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Store создает хранилище, client nil - http.DefaultClient
+func NewS3Store(cfg S3Config, client *http.Client) *S3Store {
+	if cfg.Region == "" {
+		cfg.Region = defaultS3Region
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3Store{cfg: cfg, client: client, now: time.Now}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.do(req, PayloadHash(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkStatus(resp, http.StatusOK)
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, EmptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if err = checkStatus(resp, http.StatusOK); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkStatus(resp, http.StatusNoContent, http.StatusOK)
+}
+
+func (s *S3Store) request(ctx context.Context, method, key string, data []byte) (*http.Request, error) {
+	objectURL, err := url.Parse(s.cfg.Endpoint + "/" + uriEncode(s.cfg.Bucket, true) + "/" + uriEncode(strings.TrimLeft(key, "/"), false))
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	return http.NewRequestWithContext(ctx, method, objectURL.String(), body)
+}
+
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	SignV4(req, payloadHash, Credentials{AccessKey: s.cfg.AccessKey, SecretKey: s.cfg.SecretKey}, s.cfg.Region, s.now())
+	return s.client.Do(req)
+}
+
+func checkStatus(resp *http.Response, expected ...int) error {
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512)) //S3 возвращает описание ошибки в XML
+	return fmt.Errorf("s3 %s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(message)))
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"context"
+	"subscriptions/billing"
+	"subscriptions/events"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/requestctx"
+	"subscriptions/services"
+	"subscriptions/tests/mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestApproval_Flow(t *testing.T) { //новая подписка ждет одобрения, решать может только согласующий, каждый переход - событие
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	auditrepo := new(mocks.AuditRepoMock)
+	publisher := new(mocks.PublisherMock)
+	log := zap.NewNop().Sugar()
+
+	auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	acceptCharges(subrepo)
+	subService := services.NewSubscriptionService(subrepo, srepo, new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log),
+		noBudgets(subrepo, log), publisher, services.ApprovalConfig{Required: true, Approvers: []string{"boss"}, ApproverRole: "approver", TrustIdentityHeaders: true}, log)
+
+	userID := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
+	price := int64(50000)
+	trialDays := uint(14)
+	srepo.On("GetByName", ctx, "Figma").Return(&models.Service{ID: 1, Name: "Figma"}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Subscription).ID = 1
+	}).Return(nil)
+	eventOf := func(eventType string) interface{} {
+		return mock.MatchedBy(func(e events.Event) bool { return e.Type == eventType })
+	}
+	publisher.On("Publish", ctx, eventOf(events.TypeSubscriptionSubmitted)).Once()
+
+	sub, err := subService.Create(ctx, &models.CreateSubscription{ServiceName: "Figma", UserID: userID, PriceMinor: &price, StartDate: "2025-01-10", TrialDays: &trialDays})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusPendingApproval, sub.Status)
+	assert.Equal(t, models.StatusPendingApproval, sub.Transitions[0].To)
+
+	charges := billing.Charges(sub.Billing(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	if assert.Len(t, charges, 1) { //до одобрения начисления считаются как после него: январь - пробный период
+		assert.Equal(t, time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), charges[0].Date)
+	}
+
+	pending := func() *models.Subscription {
+		copied := *sub
+		copied.Transitions = append([]models.StatusTransition(nil), sub.Transitions...)
+		return &copied
+	}
+	subrepo.On("GetById", mock.Anything, uint(1)).Return(pending(), nil).Once()
+	subrepo.On("AddTransition", mock.Anything, mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("*models.StatusTransition")).Return(nil)
+
+	_, err = subService.ChangeStatus(ctx, 1, services.ActionApprove, nil)
+	assert.ErrorIs(t, err, services.ErrApproverRequired)
+	_, err = subService.Approvals(ctx, &models.ApprovalFilter{})
+	assert.ErrorIs(t, err, services.ErrApproverRequired)
+
+	_, err = subService.Approvals(requestctx.WithRole(requestctx.WithActor(ctx, "author"), "manager"), &models.ApprovalFilter{})
+	assert.ErrorIs(t, err, services.ErrApproverRequired) //другая роль прав согласующего не дает
+
+	approver := requestctx.WithActor(ctx, "boss")
+	comment := "в пределах бюджета отдела"
+	publisher.On("Publish", approver, eventOf(events.TypeSubscriptionApproved)).Once()
+	approved, err := subService.ChangeStatus(approver, 1, services.ActionApprove, &models.TransitionRequest{Comment: &comment})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusTrial, approved.Status) //пробный период еще идет
+	last := approved.Transitions[len(approved.Transitions)-1]
+	assert.Equal(t, billing.MonthStart(sub.StartDate), last.Date) //решение действует с месяца начала подписки
+	assert.Equal(t, "boss", last.Actor)
+	assert.Equal(t, &comment, last.Comment)
+
+	subrepo.On("GetById", mock.Anything, uint(1)).Return(pending(), nil).Once()
+	publisher.On("Publish", approver, eventOf(events.TypeSubscriptionRejected)).Once()
+	rejected, err := subService.ChangeStatus(approver, 1, services.ActionReject, &models.TransitionRequest{Comment: &comment})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusRejected, rejected.Status)
+	assert.Empty(t, billing.Charges(rejected.Billing(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)))
+
+	status := models.StatusPendingApproval
+	subrepo.On("GetAll", approver, &models.ListFilter{Status: &status}).Return([]models.Subscription{*pending()}, nil)
+	inbox, err := subService.Approvals(approver, &models.ApprovalFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, inbox, 1)
+
+	publisher.AssertExpectations(t)
+}
+
+func TestApproval_IdentityBehindTrustedProxy(t *testing.T) { //X-User-ID и X-User-Role дают права согласующего только с TRUST_IDENTITY_HEADERS
+	lead := requestctx.WithRole(requestctx.WithActor(context.Background(), "lead"), "approver")
+	boss := requestctx.WithActor(context.Background(), "boss")
+	log := zap.NewNop().Sugar()
+	status := models.StatusPendingApproval
+
+	for _, tt := range []struct {
+		name   string
+		ctx    context.Context
+		config services.ApprovalConfig
+		err    error
+	}{
+		{"role header not trusted", lead, services.ApprovalConfig{Required: true, ApproverRole: "approver"}, services.ErrApproverRequired},
+		{"role behind trusted proxy", lead, services.ApprovalConfig{Required: true, ApproverRole: "approver", TrustIdentityHeaders: true}, nil},
+		{"other role", lead, services.ApprovalConfig{Required: true, ApproverRole: "finance", TrustIdentityHeaders: true}, services.ErrApproverRequired},
+		{"spoofed user id", boss, services.ApprovalConfig{Required: true, Approvers: []string{"boss"}, ApproverRole: "approver"}, services.ErrApproverRequired},
+		{"approver behind trusted proxy", boss, services.ApprovalConfig{Required: true, Approvers: []string{"boss"}, ApproverRole: "approver", TrustIdentityHeaders: true}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			subrepo := new(mocks.SubscriptionRepoMock)
+			subrepo.On("GetAll", tt.ctx, &models.ListFilter{Status: &status}).Return([]models.Subscription{}, nil).Maybe()
+			subService := services.NewSubscriptionService(subrepo, new(mocks.ServiceRepoMock), new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock),
+				services.NewAuditService(new(mocks.AuditRepoMock), log), noBudgets(subrepo, log), anyEvents(), tt.config, log)
+
+			_, err := subService.Approvals(tt.ctx, &models.ApprovalFilter{})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				subrepo.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSumByFilters_IncludePending(t *testing.T) { //подписки на согласовании попадают в сумму только по запросу
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	month := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := "01-2025"
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool { return q.WithPending })).
+		Return(withCharges(month, month, models.Subscription{ID: 1, Price: 700, StartDate: month, Status: models.StatusPendingApproval,
+			Transitions: []models.StatusTransition{{To: models.StatusPendingApproval, Date: month}}}), nil)
+
+	res, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &start, IncludePending: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), res.Amount)
+	subrepo.AssertExpectations(t)
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"subscriptions/models"
+	"subscriptions/requestctx"
+	"subscriptions/services"
+	"testing"
+	"time"
+
+	"subscriptions/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestAudit_UpdateRecordsDiff(t *testing.T) { //в журнал попадают автор, id запроса и только измененные поля
+	ctx := requestctx.WithActor(context.Background(), "admin")
+	ctx = requestctx.WithRequestID(ctx, "req-1")
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	auditrepo := new(mocks.AuditRepoMock)
+	log := zap.NewNop().Sugar()
+
+	acceptCharges(subrepo)
+	subService := services.NewSubscriptionService(subrepo, srepo, new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log), noBudgets(subrepo, log), anyEvents(), services.ApprovalConfig{}, log)
+
+	existedSub := &models.Subscription{
+		ID:        1,
+		ServiceID: 1,
+		UserID:    "6a2995b1-9967-473c-ab26-2710f6e66fd5",
+		Price:     500,
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	newPrice := int64(600)
+
+	var entry *models.AuditEntry
+	subrepo.On("GetById", ctx, uint(1)).Return(existedSub, nil)
+	subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription"), mock.Anything).Return(nil)
+	auditrepo.On("Create", ctx, mock.AnythingOfType("*models.AuditEntry")).Run(func(args mock.Arguments) {
+		entry = args.Get(1).(*models.AuditEntry)
+	}).Return(nil)
+
+	_, err := subService.Update(ctx, 1, &models.UpdateSubscription{PriceMinor: &newPrice})
+	assert.NoError(t, err)
+
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "admin", entry.Actor)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.Equal(t, models.AuditEntitySubscription, entry.Entity)
+		assert.Equal(t, uint(1), entry.EntityID)
+		assert.Equal(t, models.AuditActionUpdate, entry.Action)
+
+		var diff map[string]map[string]interface{}
+		assert.NoError(t, json.Unmarshal(entry.Diff, &diff))
+		assert.Equal(t, map[string]interface{}{"from": float64(500), "to": float64(600)}, diff["price_minor"])
+		assert.NotContains(t, diff, "user_id")
+	}
+}
+
+func TestAudit_ListDefaultsAndInvalidDate(t *testing.T) { //пагинация по умолчанию и невалидная дата
+	ctx := context.Background()
+	auditrepo := new(mocks.AuditRepoMock)
+	log := zap.NewNop().Sugar()
+
+	auditService := services.NewAuditService(auditrepo, log)
+
+	entity := models.AuditEntitySubscription
+	auditrepo.On("Find", ctx, mock.MatchedBy(func(f *models.AuditFilter) bool {
+		return f.Page == 1 && f.PageSize == 50
+	}), (*time.Time)(nil), (*time.Time)(nil)).Return([]models.AuditEntry{{ID: 1}}, int64(1), nil)
+
+	page, err := auditService.List(ctx, &models.AuditFilter{Entity: &entity})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Len(t, page.Items, 1)
+
+	bad := "01-2025"
+	_, err = auditService.List(ctx, &models.AuditFilter{From: &bad})
+	assert.ErrorIs(t, err, services.ErrInvalidAuditFilter)
+}
@@ -0,0 +1,396 @@
+package tests
+
+import (
+	"fmt"
+	"subscriptions/billing"
+	"subscriptions/models"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBilling_Charges(t *testing.T) { //начисления по месяцам для разных видов подписок
+	promoPrice := int64(50)
+	promoEnd := month(2025, time.March)
+	trialEnd := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+	annualTrialEnd := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	end := month(2025, time.February)
+
+	tests := []struct {
+		name     string
+		sub      models.Subscription
+		history  []billing.PriceChange
+		from, to time.Time
+		want     []int //суммы по месяцам периода, 0 - начисления нет
+	}{
+		{
+			name: "monthly",
+			sub:  models.Subscription{Price: 100, StartDate: month(2024, time.June)},
+			from: month(2025, time.January), to: month(2025, time.March),
+			want: []int{100, 100, 100},
+		},
+		{
+			name: "starts inside period",
+			sub:  models.Subscription{Price: 100, StartDate: month(2025, time.February)},
+			from: month(2025, time.January), to: month(2025, time.March),
+			want: []int{0, 100, 100},
+		},
+		{
+			name: "end date is the last paid month",
+			sub:  models.Subscription{Price: 100, StartDate: month(2024, time.June), EndDate: &end},
+			from: month(2025, time.January), to: month(2025, time.March),
+			want: []int{100, 100, 0},
+		},
+		{
+			name: "paused months are free",
+			sub: models.Subscription{Price: 100, StartDate: month(2025, time.January), Transitions: []models.StatusTransition{
+				{To: models.StatusActive, Date: month(2025, time.January)},
+				{From: models.StatusActive, To: models.StatusPaused, Date: month(2025, time.February)},
+				{From: models.StatusPaused, To: models.StatusActive, Date: month(2025, time.April)},
+			}},
+			from: month(2025, time.January), to: month(2025, time.April),
+			want: []int{100, 0, 0, 100},
+		},
+		{
+			name: "trial months are free",
+			sub: models.Subscription{Price: 100, StartDate: month(2025, time.January), TrialEndDate: &trialEnd, Transitions: []models.StatusTransition{
+				{To: models.StatusTrial, Date: month(2025, time.January)},
+			}},
+			from: month(2025, time.January), to: month(2025, time.April),
+			want: []int{0, 0, 100, 100},
+		},
+		{
+			name: "promo price before promo end",
+			sub:  models.Subscription{Price: 100, StartDate: month(2025, time.January), PromoPrice: &promoPrice, PromoEndDate: &promoEnd},
+			from: month(2025, time.January), to: month(2025, time.April),
+			want: []int{50, 50, 100, 100},
+		},
+		{
+			name: "annual is charged once a year",
+			sub:  models.Subscription{Price: 1000, StartDate: month(2024, time.March), BillingPeriod: models.BillingAnnual},
+			from: month(2024, time.January), to: month(2025, time.June),
+			want: []int{0, 0, 1000, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1000, 0, 0, 0},
+		},
+		{
+			name: "annual after trial starts from first paid month",
+			sub: models.Subscription{Price: 1000, StartDate: month(2025, time.February), BillingPeriod: models.BillingAnnual, TrialEndDate: &annualTrialEnd, Transitions: []models.StatusTransition{
+				{To: models.StatusTrial, Date: month(2025, time.February)},
+			}},
+			from: month(2025, time.January), to: month(2025, time.May),
+			want: []int{0, 0, 0, 1000, 0},
+		},
+		{
+			name:    "price history",
+			sub:     models.Subscription{Price: 150, StartDate: month(2024, time.December)},
+			history: []billing.PriceChange{{From: month(2024, time.December), Price: 100}, {From: month(2025, time.March), Price: 150}},
+			from:    month(2025, time.January), to: month(2025, time.April),
+			want: []int{100, 100, 150, 150},
+		},
+		{
+			name: "anchor day is clamped to month end",
+			sub:  models.Subscription{Price: 100, StartDate: time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC)},
+			from: month(2024, time.January), to: month(2024, time.April),
+			want: []int{100, 100, 100, 100},
+		},
+		{
+			name: "day-level end date before charge day",
+			sub: models.Subscription{Price: 100, StartDate: time.Date(2025, time.January, 27, 0, 0, 0, 0, time.UTC),
+				EndDate: func() *time.Time { d := time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC); return &d }()},
+			from: month(2025, time.January), to: month(2025, time.April),
+			want: []int{100, 100, 0, 0},
+		},
+		{
+			name: "anchor day later than start day",
+			sub:  models.Subscription{Price: 100, StartDate: time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC), AnchorDay: 5},
+			from: month(2025, time.January), to: month(2025, time.March),
+			want: []int{0, 100, 100},
+		},
+		{
+			name: "daily proration of cancellation",
+			sub: models.Subscription{Price: 310, StartDate: month(2025, time.January), ProrationMode: models.ProrationDaily,
+				EndDate: func() *time.Time { d := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC); return &d }()},
+			from: month(2025, time.January), to: month(2025, time.April),
+			want: []int{310, 310, 100, 0},
+		},
+		{
+			name: "by-anchor-day proration of first partial period",
+			sub:  models.Subscription{Price: 310, StartDate: time.Date(2025, time.January, 21, 0, 0, 0, 0, time.UTC), AnchorDay: 1, ProrationMode: models.ProrationByAnchorDay},
+			from: month(2025, time.January), to: month(2025, time.February),
+			want: []int{110, 310},
+		},
+		{
+			name: "no proration charges whole periods",
+			sub: models.Subscription{Price: 310, StartDate: time.Date(2025, time.January, 21, 0, 0, 0, 0, time.UTC), AnchorDay: 1, ProrationMode: models.ProrationNone,
+				EndDate: func() *time.Time { d := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC); return &d }()},
+			from: month(2025, time.January), to: month(2025, time.April),
+			want: []int{0, 310, 310, 0},
+		},
+		{
+			name: "by-anchor-day divides by days between charges",
+			sub: models.Subscription{Price: 280, StartDate: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), ProrationMode: models.ProrationByAnchorDay,
+				EndDate: func() *time.Time { d := time.Date(2025, time.February, 13, 0, 0, 0, 0, time.UTC); return &d }()},
+			from: month(2025, time.January), to: month(2025, time.February),
+			want: []int{140, 0},
+		},
+		{
+			name: "daily divides by days in calendar month",
+			sub: models.Subscription{Price: 280, StartDate: time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC), ProrationMode: models.ProrationDaily,
+				EndDate: func() *time.Time { d := time.Date(2025, time.February, 13, 0, 0, 0, 0, time.UTC); return &d }()},
+			from: month(2025, time.January), to: month(2025, time.February),
+			want: []int{126, 0},
+		},
+		{
+			name:    "price change inside period is prorated",
+			sub:     models.Subscription{Price: 400, StartDate: month(2025, time.January), ProrationMode: models.ProrationByAnchorDay},
+			history: []billing.PriceChange{{From: month(2025, time.January), Price: 300}, {From: time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC), Price: 400}},
+			from:    month(2025, time.January), to: month(2025, time.March),
+			want: []int{300, 350, 400},
+		},
+		{
+			name:    "price change without proration applies from next charge",
+			sub:     models.Subscription{Price: 400, StartDate: month(2025, time.January)},
+			history: []billing.PriceChange{{From: month(2025, time.January), Price: 300}, {From: time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC), Price: 400}},
+			from:    month(2025, time.January), to: month(2025, time.March),
+			want: []int{300, 300, 400},
+		},
+		{
+			name: "period before start",
+			sub:  models.Subscription{Price: 100, StartDate: month(2025, time.June)},
+			from: month(2025, time.January), to: month(2025, time.March),
+			want: []int{0, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			byMonth := map[time.Time]int{}
+			terms := tt.sub.Billing()
+			if tt.history != nil {
+				terms.History = tt.history
+			}
+			for _, charge := range billing.Charges(terms, tt.from, tt.to) {
+				byMonth[charge.Month] = int(charge.Amount)
+			}
+			var got []int
+			for m := tt.from; !m.After(tt.to); m = m.AddDate(0, 1, 0) {
+				got = append(got, byMonth[m])
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBilling_ProrationExplanation(t *testing.T) { //неполный период объясняется, полный - нет
+	end := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	sub := models.Subscription{ID: 7, Price: 310, StartDate: month(2025, time.January), EndDate: &end, ProrationMode: models.ProrationDaily}
+
+	charges := billing.Charges(sub.Billing(), month(2025, time.February), month(2025, time.March))
+	if assert.Len(t, charges, 2) {
+		assert.Empty(t, charges[0].Explanation)
+		assert.Equal(t, "daily: 10/31 days at 310, period 2025-03-01 - 2025-03-10", charges[1].Explanation)
+	}
+
+	totals := billing.ByMonth(models.BillingAll([]models.Subscription{sub}), nil, month(2025, time.March), month(2025, time.March))
+	assert.Equal(t, int64(100), totals[month(2025, time.March)].Amount)
+	assert.Equal(t, []string{"subscription 7: daily: 10/31 days at 310, period 2025-03-01 - 2025-03-10"}, totals[month(2025, time.March)].Prorations)
+}
+
+func TestBilling_SplitTax(t *testing.T) { //налог сверху цены или внутри нее, ставка подписки важнее ставки сервиса
+	vat, reduced := 20.0, 10.0
+	inclusive, exclusive := true, false
+
+	tests := []struct {
+		name string
+		sub  models.Subscription
+		want models.TaxTotals
+	}{
+		{name: "no tax", sub: models.Subscription{}, want: models.TaxTotals{Net: 1000, Gross: 1000}},
+		{name: "service exclusive", sub: models.Subscription{Service: models.Service{TaxRate: &vat}}, want: models.TaxTotals{Net: 1000, Tax: 200, Gross: 1200}},
+		{name: "service inclusive", sub: models.Subscription{Service: models.Service{TaxRate: &vat, TaxInclusive: true}}, want: models.TaxTotals{Net: 833, Tax: 167, Gross: 1000}},
+		{name: "subscription overrides rate", sub: models.Subscription{TaxRate: &reduced, Service: models.Service{TaxRate: &vat}}, want: models.TaxTotals{Net: 1000, Tax: 100, Gross: 1100}},
+		{name: "subscription overrides inclusive", sub: models.Subscription{TaxInclusive: &exclusive, Service: models.Service{TaxRate: &vat, TaxInclusive: true}}, want: models.TaxTotals{Net: 1000, Tax: 200, Gross: 1200}},
+		{name: "subscription inclusive without service", sub: models.Subscription{TaxRate: &vat, TaxInclusive: &inclusive}, want: models.TaxTotals{Net: 833, Tax: 167, Gross: 1000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, models.TaxTotals(billing.SplitTax(tt.sub.Billing(), 1000)))
+		})
+	}
+}
+
+func TestBilling_ChargeDate(t *testing.T) { //день списания прижимается к концу короткого месяца
+	tests := []struct {
+		month  time.Time
+		anchor int
+		want   time.Time
+	}{
+		{month(2025, time.January), 31, time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{month(2025, time.February), 31, time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC)},
+		{month(2024, time.February), 30, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{month(2025, time.April), 31, time.Date(2025, time.April, 30, 0, 0, 0, 0, time.UTC)},
+		{month(2025, time.April), 27, time.Date(2025, time.April, 27, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, billing.ChargeDate(tt.month, tt.anchor))
+	}
+}
+
+func TestBilling_PriceHistory(t *testing.T) { //версии превращаются в историю цены, в течение дня действует последнее изменение
+	versions := []models.SubscriptionVersion{
+		{Version: 3, Price: 200, ValidFrom: time.Date(2025, time.March, 2, 15, 0, 0, 0, time.UTC)},
+		{Version: 1, Price: 100, ValidFrom: time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC)},
+		{Version: 2, Price: 150, ValidFrom: time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{Version: 4, Price: 200, ValidFrom: time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	sub := models.Subscription{Price: 200, StartDate: month(2025, time.January), AnchorDay: 10, Versions: versions}
+	history := []billing.PriceChange{
+		{From: time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC), Price: 100},
+		{From: time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC), Price: 200},
+	}
+	assert.Equal(t, history, sub.Billing().History)
+
+	var amounts []int64 //версии из базы доходят до начислений: до 2 марта действует старая цена
+	for _, charge := range billing.Charges(sub.Billing(), month(2025, time.January), month(2025, time.April)) {
+		amounts = append(amounts, charge.Amount)
+	}
+	assert.Equal(t, []int64{100, 100, 200, 200}, amounts)
+}
+
+// randomSub строит подписку из случайных параметров: начало и конец в пределах нескольких лет, пауза, пробный период, годовая оплата
+func randomSub(startOffset, length, pauseAt, pauseLen, trialDays uint8, price uint16, annual, hasEnd bool) billing.Subscription {
+	base := month(2023, time.January)
+	start := base.AddDate(0, int(startOffset%36), int(length%31)) //день начала - день списания
+	sub := billing.Subscription{Price: int64(price), StartDate: start, BillingPeriod: billing.PeriodMonthly}
+	if annual {
+		sub.BillingPeriod = billing.PeriodAnnual
+	}
+	if hasEnd {
+		end := billing.MonthStart(start).AddDate(0, int(length%48), 0)
+		sub.EndDate = &end
+	}
+	status := billing.StatusActive
+	if trialDays%3 == 0 {
+		trialEnd := start.AddDate(0, 0, int(trialDays))
+		sub.TrialEndDate = &trialEnd
+		status = billing.StatusTrial
+	}
+	sub.Transitions = []billing.Transition{{To: status, Date: billing.MonthStart(start)}}
+	if pauseLen > 0 {
+		pause := billing.MonthStart(start).AddDate(0, int(pauseAt%24), 0)
+		sub.Transitions = append(sub.Transitions,
+			billing.Transition{To: billing.StatusPaused, Date: pause},
+			billing.Transition{To: billing.StatusActive, Date: pause.AddDate(0, int(pauseLen%12)+1, 0)},
+		)
+	}
+	return sub
+}
+
+func TestBilling_Properties(t *testing.T) { //свойства движка на случайных подписках
+	config := &quick.Config{MaxCount: 2000}
+	base := month(2023, time.January)
+
+	additive := func(startOffset, length, pauseAt, pauseLen, trialDays uint8, price uint16, annual, hasEnd bool, from, split, to uint8) bool {
+		sub := randomSub(startOffset, length, pauseAt, pauseLen, trialDays, price, annual, hasEnd)
+		a := base.AddDate(0, int(from%60), 0)
+		b := a.AddDate(0, int(split%24), 0)
+		c := b.AddDate(0, int(to%24)+1, 0)
+		return billing.Cost(&sub, a, c) == billing.Cost(&sub, a, b)+billing.Cost(&sub, b.AddDate(0, 1, 0), c)
+	}
+	if err := quick.Check(additive, config); err != nil {
+		t.Errorf("cost is not additive over adjacent periods: %v", err)
+	}
+
+	bounded := func(startOffset, length, pauseAt, pauseLen, trialDays uint8, price uint16, annual, hasEnd bool, from, to uint8) bool {
+		sub := randomSub(startOffset, length, pauseAt, pauseLen, trialDays, price, annual, hasEnd)
+		a := base.AddDate(0, int(from%60), 0)
+		c := a.AddDate(0, int(to%36), 0)
+		var prev time.Time
+		for _, charge := range billing.Charges(&sub, a, c) {
+			if charge.Month.Before(a) || charge.Month.After(c) || charge.Month.Before(billing.MonthStart(sub.StartDate)) {
+				return false
+			}
+			if sub.EndDate != nil && charge.Date.After(*sub.EndDate) {
+				return false
+			}
+			if !prev.IsZero() && !charge.Month.After(prev) {
+				return false
+			}
+			if charge.Amount != sub.Price || billing.StatusAt(&sub, charge.Month) == billing.StatusPaused {
+				return false
+			}
+			if charge.Date.Before(sub.StartDate) || !billing.MonthStart(charge.Date).Equal(charge.Month) || charge.Date != billing.ChargeDate(charge.Month, sub.StartDate.Day()) {
+				return false
+			}
+			prev = charge.Month
+		}
+		return true
+	}
+	if err := quick.Check(bounded, config); err != nil {
+		t.Errorf("charges are outside of the subscription or period: %v", err)
+	}
+
+	modes := []string{billing.ProrationNone, billing.ProrationDaily, billing.ProrationByAnchorDay}
+	proratedBounded := func(startOffset, length, pauseAt, pauseLen, trialDays uint8, price uint16, annual bool, mode, endDay uint8, from, split, to uint8) bool {
+		sub := randomSub(startOffset, length, pauseAt, pauseLen, trialDays, price, annual, false)
+		sub.ProrationMode = modes[int(mode)%len(modes)]
+		end := sub.StartDate.AddDate(0, int(length%36), int(endDay%31))
+		sub.EndDate = &end
+
+		a := base.AddDate(0, int(from%60), 0)
+		b := a.AddDate(0, int(split%24), 0)
+		c := b.AddDate(0, int(to%24)+1, 0)
+		for _, charge := range billing.Charges(&sub, a, c) {
+			if charge.Amount < 0 || charge.Amount > sub.Price || charge.Date.After(end) {
+				return false
+			}
+			if charge.Amount != sub.Price && charge.Explanation == "" {
+				return false //неполный период всегда объясняется
+			}
+		}
+		return billing.Cost(&sub, a, c) == billing.Cost(&sub, a, b)+billing.Cost(&sub, b.AddDate(0, 1, 0), c)
+	}
+	if err := quick.Check(proratedBounded, config); err != nil {
+		t.Errorf("prorated charges are out of bounds: %v", err)
+	}
+
+	annualOnce := func(startOffset, length, pauseAt, trialDays uint8, price uint16, from uint8) bool {
+		sub := randomSub(startOffset, length, pauseAt, 0, trialDays, price, true, false)
+		a := base.AddDate(0, int(from%60), 0)
+		return len(billing.Charges(&sub, a, a.AddDate(0, 11, 0))) <= 1
+	}
+	if err := quick.Check(annualOnce, config); err != nil {
+		t.Errorf("annual subscription is charged more than once in 12 months: %v", err)
+	}
+
+	sharesAddUp := func(amount uint16, percents []uint8, amounts []uint8) bool {
+		sub := billing.Subscription{UserID: "payer"}
+		for i, p := range percents {
+			percent := float64(p % 101)
+			sub.Members = append(sub.Members, billing.Member{UserID: fmt.Sprintf("p%d", i), SharePercent: &percent})
+		}
+		for i, a := range amounts {
+			fixed := int64(a)
+			sub.Members = append(sub.Members, billing.Member{UserID: fmt.Sprintf("a%d", i), ShareAmount: &fixed})
+		}
+
+		total := billing.ShareOf(&sub, sub.UserID, int64(amount))
+		for _, member := range sub.Members {
+			share := billing.ShareOf(&sub, member.UserID, int64(amount))
+			if share < 0 {
+				return false
+			}
+			total += share
+		}
+		return total == int64(amount)
+	}
+	if err := quick.Check(sharesAddUp, config); err != nil {
+		t.Errorf("shares do not add up to the charge: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"subscriptions/billing"
+	"subscriptions/events"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/services"
+	"testing"
+	"time"
+
+	"subscriptions/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestBudget_TeamReport(t *testing.T) { //расходы команды: подписка, найденная у нескольких участников, считается один раз
+	ctx := context.Background()
+	subrepo := new(mocks.SubscriptionRepoMock)
+	budgetrepo := new(mocks.BudgetRepoMock)
+	teamrepo := new(mocks.TeamRepoMock)
+	log := zap.NewNop().Sugar()
+
+	budgetService := services.NewBudgetService(budgetrepo, teamrepo, subrepo, new(mocks.PublisherMock), log)
+
+	alice := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
+	bob := "0b7c1f0e-4b8d-4c55-9d55-3d1f6a7b8c9d"
+	teamID := uint(1)
+	budget := &models.Budget{
+		ID: 1, TeamID: &teamID, Limit: 1000, CostBasis: models.CostBasisPayer,
+		Team: &models.Team{ID: teamID, Members: []models.TeamMember{{UserID: alice}, {UserID: bob}}},
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	shared := models.Subscription{ID: 1, UserID: alice, Price: 700, StartDate: start}
+	own := models.Subscription{ID: 2, UserID: bob, Price: 200, StartDate: start}
+
+	month := billing.MonthStart(time.Now())
+	budgetrepo.On("GetById", ctx, uint(1)).Return(budget, nil)
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool { return *q.UserID == alice })).
+		Return(withCharges(month, month, shared), nil)
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool { return *q.UserID == bob })).
+		Return(withCharges(month, month, shared, own), nil)
+
+	report, err := budgetService.GetReport(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(900), report.Current)
+	assert.Equal(t, int64(900), report.Projected)
+	assert.Equal(t, int64(100), report.Remaining)
+	assert.False(t, report.OverBudget)
+}
+
+func TestCreate_OverBudgetWarning(t *testing.T) { //создание подписки сверх бюджета проходит, но возвращает предупреждение и событие
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	auditrepo := new(mocks.AuditRepoMock)
+	budgetrepo := new(mocks.BudgetRepoMock)
+	teamrepo := new(mocks.TeamRepoMock)
+	publisher := new(mocks.PublisherMock)
+	log := zap.NewNop().Sugar()
+
+	budgets := services.NewBudgetService(budgetrepo, teamrepo, subrepo, publisher, log)
+	acceptCharges(subrepo)
+	subService := services.NewSubscriptionService(subrepo, srepo, new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log), budgets, anyEvents(), services.ApprovalConfig{}, log)
+
+	userID := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
+	video := "video"
+	music := "music"
+	price := uint(800)
+	now := time.Now()
+	thisMonth := billing.MonthStart(now)
+	start := now.Format("01-2006")
+	existing := models.Subscription{ID: 1, UserID: userID, Price: 500, StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	srepo.On("GetByName", ctx, "Netflix").Return(&models.Service{ID: 1, Name: "Netflix", Category: &video}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Subscription).ID = 2
+	}).Return(nil)
+	teamrepo.On("GetByUser", ctx, userID).Return([]models.Team{}, nil)
+	budgetrepo.On("FindForOwners", ctx, []string{userID}, []uint(nil)).Return([]models.Budget{
+		{ID: 1, UserID: &userID, Category: &video, Limit: 1000, CostBasis: models.CostBasisPayer},
+		{ID: 2, UserID: &userID, Category: &music, Limit: 100, CostBasis: models.CostBasisPayer}, //другая категория, не проверяется
+	}, nil)
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool { return *q.Category == video })).
+		Return(withCharges(thisMonth, thisMonth, existing, models.Subscription{ID: 2, UserID: userID, Price: 800, StartDate: thisMonth}), nil)
+	publisher.On("Publish", ctx, mock.MatchedBy(func(e events.Event) bool { return e.Type == events.TypeBudgetExceeded })).Once()
+
+	res, err := subService.Create(ctx, &models.CreateSubscription{ServiceName: "Netflix", UserID: userID, Price: &price, StartDate: start})
+	assert.NoError(t, err)
+	if assert.Len(t, res.Warnings, 1) {
+		assert.Contains(t, res.Warnings[0], "budget 1 exceeded")
+	}
+
+	publisher.AssertExpectations(t)
+	subrepo.AssertExpectations(t)
+}
+
+func TestBudget_AlertsOnlyWhenChangeCrossesLimit(t *testing.T) { //бюджет, превышенный и до изменения, не дает нового предупреждения, возобновление - дает
+	ctx := context.Background()
+	userID := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
+	thisMonth := billing.MonthStart(time.Now())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	other := models.Subscription{ID: 1, UserID: userID, Price: 900, StartDate: start}
+
+	setup := func(target *models.Subscription, inBudget models.Subscription) (services.SubscriptionServiceInterface, *mocks.PublisherMock) {
+		subrepo := new(mocks.SubscriptionRepoMock)
+		budgetrepo := new(mocks.BudgetRepoMock)
+		teamrepo := new(mocks.TeamRepoMock)
+		publisher := new(mocks.PublisherMock)
+		log := zap.NewNop().Sugar()
+		budgets := services.NewBudgetService(budgetrepo, teamrepo, subrepo, publisher, log)
+		auditrepo := new(mocks.AuditRepoMock)
+		auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		acceptCharges(subrepo)
+		subService := services.NewSubscriptionService(subrepo, new(mocks.ServiceRepoMock), new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log), budgets, anyEvents(), services.ApprovalConfig{}, log)
+
+		subrepo.On("GetById", ctx, target.ID).Return(target, nil)
+		subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription"), mock.Anything).Return(nil)
+		subrepo.On("AddTransition", ctx, mock.AnythingOfType("*models.Subscription"), mock.Anything).Return(nil)
+		teamrepo.On("GetByUser", ctx, userID).Return([]models.Team{}, nil)
+		budgetrepo.On("FindForOwners", ctx, []string{userID}, []uint(nil)).Return([]models.Budget{
+			{ID: 1, UserID: &userID, Limit: 1000, CostBasis: models.CostBasisPayer},
+		}, nil)
+		subrepo.On("FindForSum", ctx, mock.Anything).Return(withCharges(thisMonth, thisMonth, other, inBudget), nil) //журнал уже с изменением
+		return subService, publisher
+	}
+
+	t.Run("lowering the price of an over-budget subscription", func(t *testing.T) {
+		target := &models.Subscription{ID: 2, UserID: userID, Price: 300, StartDate: start}
+		subService, publisher := setup(target, models.Subscription{ID: 2, UserID: userID, Price: 200, StartDate: start})
+		price := int64(200)
+		res, err := subService.Update(ctx, 2, &models.UpdateSubscription{PriceMinor: &price})
+		assert.NoError(t, err)
+		assert.Empty(t, res.Warnings) //1200 -> 1100 при лимите 1000: бюджет был превышен и раньше
+		publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("raising the price over the limit", func(t *testing.T) {
+		target := &models.Subscription{ID: 2, UserID: userID, Price: 50, StartDate: start}
+		subService, publisher := setup(target, models.Subscription{ID: 2, UserID: userID, Price: 200, StartDate: start})
+		publisher.On("Publish", ctx, mock.MatchedBy(func(e events.Event) bool { return e.Type == events.TypeBudgetExceeded })).Once()
+		price := int64(200)
+		res, err := subService.Update(ctx, 2, &models.UpdateSubscription{PriceMinor: &price})
+		assert.NoError(t, err)
+		assert.Len(t, res.Warnings, 1) //950 -> 1100
+		publisher.AssertExpectations(t)
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		paused := thisMonth.AddDate(0, -1, 0)
+		transitions := []models.StatusTransition{{To: models.StatusActive, Date: start}, {From: models.StatusActive, To: models.StatusPaused, Date: paused}}
+		target := &models.Subscription{ID: 2, UserID: userID, Price: 200, StartDate: start, Status: models.StatusPaused, Transitions: transitions}
+		resumed := *target
+		resumed.Status = models.StatusActive
+		resumed.Transitions = append(append([]models.StatusTransition(nil), transitions...), models.StatusTransition{To: models.StatusActive, Date: thisMonth})
+		subService, publisher := setup(target, resumed)
+		publisher.On("Publish", ctx, mock.MatchedBy(func(e events.Event) bool { return e.Type == events.TypeBudgetExceeded })).Once()
+		res, err := subService.ChangeStatus(ctx, 2, services.ActionResume, &models.TransitionRequest{})
+		assert.NoError(t, err)
+		assert.Len(t, res.Warnings, 1) //на паузе 900, после возобновления 1100
+		publisher.AssertExpectations(t)
+	})
+}
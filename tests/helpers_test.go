@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"subscriptions/models"
+	"subscriptions/services"
+	"subscriptions/tests/mocks"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+// сервис подписок с журналом изменений, который принимает любые записи, и без бюджетов
+func newSubscriptionService(subrepo *mocks.SubscriptionRepoMock, srepo *mocks.ServiceRepoMock, log *zap.SugaredLogger) services.SubscriptionServiceInterface {
+	auditrepo := new(mocks.AuditRepoMock)
+	auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	acceptCharges(subrepo)
+	return services.NewSubscriptionService(subrepo, srepo, new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log), noBudgets(subrepo, log), anyEvents(), services.ApprovalConfig{}, log)
+}
+
+// журнал начислений принимает любые изменения
+func acceptCharges(subrepo *mocks.SubscriptionRepoMock) {
+	subrepo.On("ReplaceCharges", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+}
+
+// withCharges заполняет начисления подписок за месяцы [from, to] так, как их построил бы сервис и вернул журнал
+func withCharges(from, to time.Time, subs ...models.Subscription) []models.Subscription {
+	res := make([]models.Subscription, len(subs))
+	for i := range subs {
+		res[i] = subs[i]
+		for _, charge := range services.LedgerCharges(&res[i], to) {
+			if !charge.Month.Before(from) {
+				res[i].Charges = append(res[i].Charges, charge)
+			}
+		}
+	}
+	return res
+}
+
+// сервис бюджетов, у которого нет ни команд, ни бюджетов
+func noBudgets(subrepo *mocks.SubscriptionRepoMock, log *zap.SugaredLogger) services.BudgetServiceInterface {
+	teamrepo := new(mocks.TeamRepoMock)
+	teamrepo.On("GetByUser", mock.Anything, mock.Anything).Return([]models.Team{}, nil).Maybe()
+	budgetrepo := new(mocks.BudgetRepoMock)
+	budgetrepo.On("FindForOwners", mock.Anything, mock.Anything, mock.Anything).Return([]models.Budget{}, nil).Maybe()
+	return services.NewBudgetService(budgetrepo, teamrepo, subrepo, new(mocks.PublisherMock), log)
+}
+
+// публикация событий, которая принимает любые события
+func anyEvents() *mocks.PublisherMock {
+	publisher := new(mocks.PublisherMock)
+	publisher.On("Publish", mock.Anything, mock.Anything).Maybe()
+	return publisher
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type AttachmentRepoMock struct { //мок для репозитория вложений
+	mock.Mock
+}
+
+func (a *AttachmentRepoMock) Create(ctx context.Context, attachment *models.Attachment) error {
+	args := a.Called(ctx, attachment)
+	return args.Error(0)
+}
+
+func (a *AttachmentRepoMock) GetById(ctx context.Context, subscriptionID, id uint) (*models.Attachment, error) {
+	args := a.Called(ctx, subscriptionID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (a *AttachmentRepoMock) List(ctx context.Context, subscriptionID uint) ([]models.Attachment, error) {
+	args := a.Called(ctx, subscriptionID)
+	return args.Get(0).([]models.Attachment), args.Error(1)
+}
+
+func (a *AttachmentRepoMock) Delete(ctx context.Context, id uint) error {
+	args := a.Called(ctx, id)
+	return args.Error(0)
+}
@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"context"
+	"subscriptions/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type AuditRepoMock struct { //мок для репозитория журнала изменений
+	mock.Mock
+}
+
+func (a *AuditRepoMock) Create(ctx context.Context, entry *models.AuditEntry) error {
+	args := a.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (a *AuditRepoMock) Find(ctx context.Context, filter *models.AuditFilter, from, to *time.Time) ([]models.AuditEntry, int64, error) {
+	args := a.Called(ctx, filter, from, to)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]models.AuditEntry), args.Get(1).(int64), args.Error(2)
+}
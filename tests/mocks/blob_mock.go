@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type BlobStoreMock struct { //мок для хранилища файлов вложений
+	mock.Mock
+}
+
+func (b *BlobStoreMock) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	args := b.Called(ctx, key, data, contentType)
+	return args.Error(0)
+}
+
+func (b *BlobStoreMock) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	args := b.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+func (b *BlobStoreMock) Delete(ctx context.Context, key string) error {
+	args := b.Called(ctx, key)
+	return args.Error(0)
+}
@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"context"
+	"subscriptions/events"
+	"subscriptions/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type BudgetRepoMock struct { //мок для репозитория бюджетов
+	mock.Mock
+}
+
+func (b *BudgetRepoMock) Create(ctx context.Context, budget *models.Budget) error {
+	args := b.Called(ctx, budget)
+	return args.Error(0)
+}
+
+func (b *BudgetRepoMock) GetById(ctx context.Context, id uint) (*models.Budget, error) {
+	args := b.Called(ctx, id)
+	return args.Get(0).(*models.Budget), args.Error(1)
+}
+
+func (b *BudgetRepoMock) GetAll(ctx context.Context, filter *models.BudgetFilter) ([]models.Budget, error) {
+	args := b.Called(ctx, filter)
+	return args.Get(0).([]models.Budget), args.Error(1)
+}
+
+func (b *BudgetRepoMock) FindForOwners(ctx context.Context, userIDs []string, teamIDs []uint) ([]models.Budget, error) {
+	args := b.Called(ctx, userIDs, teamIDs)
+	return args.Get(0).([]models.Budget), args.Error(1)
+}
+
+func (b *BudgetRepoMock) Delete(ctx context.Context, id uint) error {
+	args := b.Called(ctx, id)
+	return args.Error(0)
+}
+
+type TeamRepoMock struct { //мок для репозитория команд
+	mock.Mock
+}
+
+func (t *TeamRepoMock) Create(ctx context.Context, team *models.Team) error {
+	args := t.Called(ctx, team)
+	return args.Error(0)
+}
+
+func (t *TeamRepoMock) GetAll(ctx context.Context) ([]models.Team, error) {
+	args := t.Called(ctx)
+	return args.Get(0).([]models.Team), args.Error(1)
+}
+
+func (t *TeamRepoMock) GetById(ctx context.Context, id uint) (*models.Team, error) {
+	args := t.Called(ctx, id)
+	return args.Get(0).(*models.Team), args.Error(1)
+}
+
+func (t *TeamRepoMock) GetByUser(ctx context.Context, userID string) ([]models.Team, error) {
+	args := t.Called(ctx, userID)
+	return args.Get(0).([]models.Team), args.Error(1)
+}
+
+type PublisherMock struct { //мок для публикации событий
+	mock.Mock
+}
+
+func (p *PublisherMock) Publish(ctx context.Context, event events.Event) {
+	p.Called(ctx, event)
+}
@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type CostCenterRepoMock struct { //мок для репозитория центров затрат
+	mock.Mock
+}
+
+func (r *CostCenterRepoMock) Create(ctx context.Context, center *models.CostCenter) error {
+	args := r.Called(ctx, center)
+	return args.Error(0)
+}
+
+func (r *CostCenterRepoMock) GetAll(ctx context.Context) ([]models.CostCenter, error) {
+	args := r.Called(ctx)
+	return args.Get(0).([]models.CostCenter), args.Error(1)
+}
+
+func (r *CostCenterRepoMock) GetByIds(ctx context.Context, ids []uint) ([]models.CostCenter, error) {
+	args := r.Called(ctx, ids)
+	return args.Get(0).([]models.CostCenter), args.Error(1)
+}
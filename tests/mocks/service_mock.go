@@ -43,3 +43,8 @@ func (s *ServiceRepoMock) Delete(ctx context.Context, id uint) error {
 	args := s.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (s *ServiceRepoMock) ReplaceAliases(ctx context.Context, id uint, aliases []models.ServiceAlias) error {
+	args := s.Called(ctx, id, aliases)
+	return args.Error(0)
+}
@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"context"
+	"subscriptions/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type StatementRepoMock struct { //мок для репозитория банковских выписок
+	mock.Mock
+}
+
+func (s *StatementRepoMock) CreateImport(ctx context.Context, statement *models.StatementImport) error {
+	args := s.Called(ctx, statement)
+	return args.Error(0)
+}
+
+func (s *StatementRepoMock) FindUnmatched(ctx context.Context, userID string) ([]models.BankTransaction, error) {
+	args := s.Called(ctx, userID)
+	return args.Get(0).([]models.BankTransaction), args.Error(1)
+}
+
+func (s *StatementRepoMock) LinkTransactions(ctx context.Context, ids []uint, subscriptionID uint) error {
+	args := s.Called(ctx, ids, subscriptionID)
+	return args.Error(0)
+}
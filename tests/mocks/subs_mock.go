@@ -3,6 +3,7 @@ package mocks
 import (
 	"context"
 	"subscriptions/models"
+	"subscriptions/repository"
 	"time"
 
 	"github.com/stretchr/testify/mock"
@@ -12,9 +13,12 @@ type SubscriptionRepoMock struct { //мок для репозитория под
 	mock.Mock
 }
 
-func (s *SubscriptionRepoMock) Create(ctx context.Context, subscription *models.Subscription) error {
+func (s *SubscriptionRepoMock) Create(ctx context.Context, subscription *models.Subscription, ledger repository.LedgerFunc) error {
 	args := s.Called(ctx, subscription)
-	return args.Error(0)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return s.replaceCharges(ctx, subscription, ledger)
 }
 
 func (s *SubscriptionRepoMock) GetById(ctx context.Context, id uint) (*models.Subscription, error) {
@@ -22,14 +26,17 @@ func (s *SubscriptionRepoMock) GetById(ctx context.Context, id uint) (*models.Su
 	return args.Get(0).(*models.Subscription), args.Error(1)
 }
 
-func (s *SubscriptionRepoMock) GetAll(ctx context.Context) ([]models.Subscription, error) {
-	args := s.Called(ctx)
+func (s *SubscriptionRepoMock) GetAll(ctx context.Context, filter *models.ListFilter) ([]models.Subscription, error) {
+	args := s.Called(ctx, filter)
 	return args.Get(0).([]models.Subscription), args.Error(1)
 }
 
-func (s *SubscriptionRepoMock) Update(ctx context.Context, subscription *models.Subscription) error {
-	args := s.Called(ctx, subscription)
-	return args.Error(0)
+func (s *SubscriptionRepoMock) Update(ctx context.Context, subscription *models.Subscription, details *repository.SubscriptionDetails, ledger repository.LedgerFunc) error {
+	args := s.Called(ctx, subscription, details)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return s.replaceCharges(ctx, subscription, ledger)
 }
 
 func (s *SubscriptionRepoMock) Delete(ctx context.Context, id uint) error {
@@ -37,7 +44,105 @@ func (s *SubscriptionRepoMock) Delete(ctx context.Context, id uint) error {
 	return args.Error(0)
 }
 
-func (s *SubscriptionRepoMock) SumByFilters(ctx context.Context, userId, serviceName *string, start, end *time.Time) (int, error) {
-	args := s.Called(ctx, userId, serviceName, start, end)
-	return args.Get(0).(int), args.Error(1)
+func (s *SubscriptionRepoMock) Restore(ctx context.Context, id uint, ledger repository.LedgerFunc) error {
+	args := s.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (s *SubscriptionRepoMock) PurgeDeleted(ctx context.Context, before time.Time) (int64, []string, error) {
+	args := s.Called(ctx, before)
+	return args.Get(0).(int64), args.Get(1).([]string), args.Error(2)
+}
+
+func (s *SubscriptionRepoMock) GetHistory(ctx context.Context, id uint) ([]models.SubscriptionVersion, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).([]models.SubscriptionVersion), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) GetAllAsOf(ctx context.Context, filter *models.ListFilter, asOf time.Time) ([]models.Subscription, error) {
+	args := s.Called(ctx, filter, asOf)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) FindForSum(ctx context.Context, query *repository.SubscriptionQuery) ([]models.Subscription, error) {
+	args := s.Called(ctx, query)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) AddTransition(ctx context.Context, subscription *models.Subscription, transition *models.StatusTransition, ledger repository.LedgerFunc) error {
+	args := s.Called(ctx, subscription, transition)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	subscription.Transitions = append(subscription.Transitions, *transition) //как репозиторий, журнал строится с новым переходом
+	return s.replaceCharges(ctx, subscription, ledger)
+}
+
+func (s *SubscriptionRepoMock) FindOffersEnding(ctx context.Context, from, to time.Time) ([]models.Subscription, error) {
+	args := s.Called(ctx, from, to)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) FindRenewing(ctx context.Context, userID *string) ([]models.Subscription, error) {
+	args := s.Called(ctx, userID)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) FindDueRenewals(ctx context.Context, today time.Time) ([]models.Subscription, error) {
+	args := s.Called(ctx, today)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) Renew(ctx context.Context, subscription *models.Subscription, previousEnd time.Time, ledger repository.LedgerFunc) (bool, error) {
+	args := s.Called(ctx, subscription, previousEnd)
+	if !args.Bool(0) || args.Error(1) != nil {
+		return args.Bool(0), args.Error(1)
+	}
+	return true, s.replaceCharges(ctx, subscription, ledger)
+}
+
+func (s *SubscriptionRepoMock) FindCancelled(ctx context.Context, from, to time.Time, userID *string) ([]models.Subscription, error) {
+	args := s.Called(ctx, from, to, userID)
+	return args.Get(0).([]models.Subscription), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) CountSubscribers(ctx context.Context, serviceID uint, at time.Time) (int64, error) {
+	args := s.Called(ctx, serviceID, at)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) SubscriberTrend(ctx context.Context, serviceID uint, from, to time.Time) ([]models.SubscriberPoint, error) {
+	args := s.Called(ctx, serviceID, from, to)
+	return args.Get(0).([]models.SubscriberPoint), args.Error(1)
+}
+
+// replaceCharges - запись журнала внутри транзакции изменения, в тестах ожидается как вызов ReplaceCharges
+func (s *SubscriptionRepoMock) replaceCharges(ctx context.Context, subscription *models.Subscription, ledger repository.LedgerFunc) error {
+	args := s.MethodCalled("ReplaceCharges", ctx, subscription.ID, ledger(subscription))
+	return args.Error(0)
+}
+
+func (s *SubscriptionRepoMock) GetCharges(ctx context.Context, id uint) ([]models.Charge, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).([]models.Charge), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) SubscriptionIDs(ctx context.Context, serviceID *uint, afterID uint, limit int) ([]uint, error) {
+	args := s.Called(ctx, serviceID, afterID, limit)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) RebuildCharges(ctx context.Context, id uint, ledger repository.LedgerFunc) (int, error) {
+	args := s.Called(ctx, id, ledger)
+	return args.Int(0), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) PruneCharges(ctx context.Context) (int64, error) {
+	args := s.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (s *SubscriptionRepoMock) FindOrCreateTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	args := s.Called(ctx, names)
+	return args.Get(0).([]models.Tag), args.Error(1)
 }
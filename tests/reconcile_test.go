@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"context"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/services"
+	"subscriptions/statement"
+	"testing"
+	"time"
+
+	"subscriptions/tests/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestStatement_ParseCSV(t *testing.T) { //точка с запятой, десятичная запятая и пробелы в тысячах
+	data := []byte("\ufeffДата;Описание;Сумма\n" +
+		"11.01.2025;NFLX.COM 866-579;-9,99\n" +
+		"\n" +
+		"20.01.2025;PYATEROCHKA 4512;-1 234,50\n" +
+		"25.01.2025;Зарплата;150000\n")
+
+	transactions, err := statement.Parse(statement.DetectFormat("bank.csv", data), data, "RUB")
+	assert.NoError(t, err)
+	if assert.Len(t, transactions, 3) {
+		assert.Equal(t, time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC), transactions[0].Date)
+		assert.Equal(t, int64(-999), transactions[0].Amount)
+		assert.Equal(t, "nflx com", transactions[0].Merchant)
+		assert.Equal(t, int64(-123450), transactions[1].Amount)
+		assert.Equal(t, int64(0), transactions[2].Debit()) //поступление
+	}
+
+	comma := []byte("Date,Description,Amount,Currency\n2025-02-03,\"Spotify AB\",\"-1,234.56\",usd\n")
+	transactions, err = statement.Parse(models.StatementCSV, comma, "RUB")
+	assert.NoError(t, err)
+	if assert.Len(t, transactions, 1) {
+		assert.Equal(t, int64(-123456), transactions[0].Amount)
+		assert.Equal(t, "USD", transactions[0].Currency)
+	}
+
+	_, err = statement.Parse(models.StatementCSV, []byte("Date;Amount\n2025-01-01;-1\n"), "RUB")
+	assert.Error(t, err) //нет колонки описания
+	_, err = statement.Parse(models.StatementCSV, []byte("Date;Description;Amount\n2025-01-01;Shop;abc\n"), "RUB")
+	assert.Error(t, err)
+}
+
+func TestStatement_ParseOFX(t *testing.T) { //SGML без закрывающих тегов, валюта из CURDEF
+	data := []byte(`OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>USD
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250110120000.000[-5:EST]<TRNAMT>-15.49<FITID>A1<NAME>NETFLIX.COM
+</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250205<TRNAMT>-2.5<FITID>A2<MEMO>Coffee #12
+</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`)
+
+	assert.Equal(t, models.StatementOFX, statement.DetectFormat("export.txt", data))
+	transactions, err := statement.Parse(models.StatementOFX, data, "RUB")
+	assert.NoError(t, err)
+	if assert.Len(t, transactions, 2) {
+		assert.Equal(t, models.BankTransaction{ExternalID: "A1", Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+			Description: "NETFLIX.COM", Merchant: "netflix com", Amount: -1549, Currency: "USD"}, transactions[0])
+		assert.Equal(t, "Coffee #12", transactions[1].Description)
+		assert.Equal(t, int64(-250), transactions[1].Amount)
+	}
+}
+
+func TestReconcile(t *testing.T) { //оплаченные начисления, пропущенные и неучтенные регулярные списания
+	ctx := context.Background()
+	statementrepo := new(mocks.StatementRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	srepo := new(mocks.ServiceRepoMock)
+	log := zap.NewNop().Sugar()
+
+	reconcileService := services.NewReconcileService(statementrepo, subrepo, srepo, log)
+
+	userID := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	netflix := models.Service{ID: 1, Name: "Netflix", Aliases: []models.ServiceAlias{{Alias: "NFLX.COM"}}}
+	spotify := models.Service{ID: 2, Name: "Spotify"}
+	subs := withCharges(jan, jan.AddDate(0, 2, 0),
+		models.Subscription{ID: 10, UserID: userID, ServiceID: 1, Service: netflix, Price: 999, StartDate: jan.AddDate(0, 0, 9)}, //10 числа
+		models.Subscription{ID: 20, UserID: userID, ServiceID: 2, Service: spotify, Price: 299, StartDate: jan.AddDate(0, 0, 4)}, //5 числа
+	)
+
+	srepo.On("GetAll", ctx).Return([]models.Service{netflix, spotify}, nil)
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool {
+		return *q.UserID == userID && q.WithCharges && q.Start.Equal(jan) && q.End.Equal(jan.AddDate(0, 2, 0))
+	})).Return(subs, nil)
+	var saved *models.StatementImport
+	statementrepo.On("CreateImport", ctx, mock.AnythingOfType("*models.StatementImport")).Run(func(args mock.Arguments) {
+		saved = args.Get(1).(*models.StatementImport)
+		saved.ID = 7
+	}).Return(nil)
+
+	data := []byte("Дата;Описание;Сумма\n" +
+		"05.01.2025;SPOTIFY;-2,99\n" +
+		"11.01.2025;NFLX.COM 866-579;-9,99\n" + //на день позже
+		"10.02.2025;NFLX.COM 866-579;-9,99\n" +
+		"10.03.2025;NETFLIX;-9,99\n" +
+		"15.01.2025;YANDEX PLUS 4455;-299,00\n" +
+		"14.02.2025;YANDEX PLUS 4456;-299,00\n" +
+		"16.03.2025;YANDEX PLUS 4457;-299,00\n" +
+		"20.01.2025;PYATEROCHKA;-1 234,50\n" + //нерегулярные покупки
+		"23.01.2025;PYATEROCHKA;-540,00\n" +
+		"25.01.2025;Зарплата;150000,00\n")
+
+	report, err := reconcileService.Reconcile(ctx, &models.ReconcileRequest{UserID: userID}, "bank.csv", data)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), report.ImportID)
+	assert.Equal(t, 10, report.Transactions)
+	assert.Equal(t, jan.AddDate(0, 0, 4), report.From)
+	assert.Equal(t, jan.AddDate(0, 2, 15), report.To)
+
+	assert.Len(t, report.Matched, 4)
+	for _, match := range report.Matched {
+		assert.Equal(t, match.Expected.SubscriptionID, *match.Transaction.SubscriptionID)
+	}
+	if assert.Len(t, report.Missing, 2) { //Spotify в феврале и марте
+		assert.Equal(t, uint(20), report.Missing[0].SubscriptionID)
+		assert.Equal(t, jan.AddDate(0, 1, 4), report.Missing[0].Date)
+		assert.Equal(t, jan.AddDate(0, 2, 4), report.Missing[1].Date)
+	}
+	if assert.Len(t, report.Unknown, 1) {
+		assert.Equal(t, "yandex plus", report.Unknown[0].Merchant)
+		assert.Equal(t, models.CadenceMonthly, report.Unknown[0].Cadence)
+		assert.Equal(t, int64(29900), report.Unknown[0].Amount)
+		assert.Len(t, report.Unknown[0].Transactions, 3)
+	}
+
+	if assert.NotNil(t, saved) && assert.Len(t, saved.Transactions, 10) {
+		assert.Equal(t, userID, saved.Transactions[0].UserID)
+	}
+
+	_, err = reconcileService.Reconcile(ctx, &models.ReconcileRequest{UserID: userID}, "bank.csv", []byte("nothing here"))
+	assert.ErrorIs(t, err, services.ErrInvalidStatement)
+}
+
+func TestSuggestions_ListAndAccept(t *testing.T) { //регулярные списания без подписки и создание подписки по ним
+	ctx := context.Background()
+	statementrepo := new(mocks.StatementRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	srepo := new(mocks.ServiceRepoMock)
+	log := zap.NewNop().Sugar()
+
+	suggestionService := services.NewSuggestionService(statementrepo, subrepo, srepo, newSubscriptionService(subrepo, srepo, log), log)
+
+	userID := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
+	day := func(m, d int) time.Time { return time.Date(2025, time.Month(m), d, 0, 0, 0, 0, time.UTC) }
+	debit := func(id uint, date time.Time, description string, amount int64, currency string) models.BankTransaction {
+		return models.BankTransaction{ID: id, UserID: userID, Date: date, Description: description, Merchant: statement.Merchant(description), Amount: -amount, Currency: currency}
+	}
+	imported := func(importID uint, transaction models.BankTransaction) models.BankTransaction {
+		transaction.ImportID = importID
+		return transaction
+	}
+	statementrepo.On("FindUnmatched", ctx, userID).Return([]models.BankTransaction{
+		imported(1, debit(1, day(1, 15), "YANDEX PLUS 4455", 29900, "RUB")),
+		imported(1, debit(2, day(2, 14), "YANDEX PLUS 4456", 29900, "RUB")),
+		imported(2, debit(3, day(3, 16), "YANDEX PLUS 4457", 29900, "RUB")),
+		debit(5, day(1, 10), "NFLX.COM", 999, "RUB"), //уже есть подписка
+		debit(6, day(2, 10), "NFLX.COM", 999, "RUB"),
+		debit(7, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "JetBrains s.r.o.", 24900, "USD"),
+		debit(8, day(3, 1), "JetBrains s.r.o.", 24900, "USD"),
+	}, nil)
+	subrepo.On("GetAll", ctx, &models.ListFilter{UserID: &userID}).Return([]models.Subscription{{ID: 10, UserID: userID, ServiceID: 1}}, nil)
+	srepo.On("GetAll", ctx).Return([]models.Service{{ID: 1, Name: "Netflix", Aliases: []models.ServiceAlias{{Alias: "NFLX.COM"}}}}, nil)
+
+	suggestions, err := suggestionService.List(ctx, &models.SuggestionFilter{UserID: userID})
+	assert.NoError(t, err)
+	if assert.Len(t, suggestions, 2) {
+		assert.Equal(t, "jetbrains s r o", suggestions[0].Merchant)
+		assert.Equal(t, models.CadenceAnnual, suggestions[0].Cadence)
+		assert.Equal(t, "USD", suggestions[0].Currency)
+		assert.Equal(t, "Yandex Plus", suggestions[1].ServiceName)
+		assert.Equal(t, models.CadenceMonthly, suggestions[1].Cadence)
+		assert.Len(t, suggestions[1].Transactions, 3)
+	}
+
+	srepo.On("GetByName", ctx, "Yandex Plus").Return(nil, gorm.ErrRecordNotFound)
+	srepo.On("Create", ctx, mock.AnythingOfType("*models.Service")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Service).ID = 2
+	}).Return(nil)
+	var created *models.Subscription
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Run(func(args mock.Arguments) {
+		created = args.Get(1).(*models.Subscription)
+		created.ID = 11
+	}).Return(nil)
+	statementrepo.On("LinkTransactions", ctx, []uint{1, 2, 3}, uint(11)).Return(nil).Once() //все списания получателя из всех выписок
+
+	sub, err := suggestionService.Accept(ctx, &models.AcceptSuggestion{UserID: userID, Merchant: "yandex plus", Currency: "RUB"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(11), sub.ID)
+	assert.Equal(t, uint(2), created.ServiceID)
+	assert.Equal(t, int64(29900), created.Price)
+	assert.Equal(t, day(1, 15), created.StartDate)
+	assert.Equal(t, uint8(15), created.AnchorDay)
+	assert.Equal(t, models.BillingMonthly, created.BillingPeriod)
+	statementrepo.AssertExpectations(t)
+
+	_, err = suggestionService.Accept(ctx, &models.AcceptSuggestion{UserID: userID, Merchant: "spotify", Currency: "RUB"})
+	assert.ErrorIs(t, err, services.ErrSuggestionNotFound)
+}
+
+func TestTransactionFingerprint(t *testing.T) { //повторная загрузка узнается по FITID, без него - по дате, сумме и описанию
+	date := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	withID := models.BankTransaction{ExternalID: "2025011501", Date: date, Description: "YANDEX PLUS", Amount: -29900}
+	assert.Equal(t, "id:2025011501", repository.TransactionFingerprint(&withID))
+
+	again := withID
+	again.Description = "YANDEX PLUS 4455" //банк поменял описание, FITID тот же
+	assert.Equal(t, repository.TransactionFingerprint(&withID), repository.TransactionFingerprint(&again))
+
+	csv := models.BankTransaction{Date: date, Description: "YANDEX PLUS 4455", Amount: -29900}
+	assert.Equal(t, "tx:2025-01-15|-29900|YANDEX PLUS 4455", repository.TransactionFingerprint(&csv))
+}
+
+func TestSetFingerprints(t *testing.T) { //одинаковые платежи одной выписки не схлопываются, повторная загрузка дает те же ключи
+	date := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	statement := func() []models.BankTransaction {
+		return []models.BankTransaction{
+			{Date: date, Description: "PARKING", Amount: -10000},
+			{Date: date, Description: "PARKING", Amount: -10000},
+			{Date: date, Description: "YANDEX PLUS", Amount: -29900},
+			{Date: date, Description: "PARKING", Amount: -10000},
+		}
+	}
+	first := statement()
+	repository.SetFingerprints(first)
+	assert.Equal(t, []string{"tx:2025-01-15|-10000|PARKING", "tx:2025-01-15|-10000|PARKING#2", "tx:2025-01-15|-29900|YANDEX PLUS", "tx:2025-01-15|-10000|PARKING#3"},
+		[]string{first[0].Fingerprint, first[1].Fingerprint, first[2].Fingerprint, first[3].Fingerprint})
+
+	again := statement()
+	repository.SetFingerprints(again)
+	for i := range first {
+		assert.Equal(t, first[i].Fingerprint, again[i].Fingerprint)
+	}
+}
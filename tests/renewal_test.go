@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"subscriptions/billing"
+	"subscriptions/events"
+	"subscriptions/models"
+	"subscriptions/services"
+	"subscriptions/tests/mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func day(year int, m time.Month, d int) time.Time {
+	return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func dayPtr(year int, m time.Month, d int) *time.Time {
+	date := day(year, m, d)
+	return &date
+}
+
+func TestBilling_CancellationDeadline(t *testing.T) { //последний день отмены перед продлением по сроку договора и сроку уведомления
+	end := day(2025, time.December, 31)
+	today := day(2025, time.March, 10)
+
+	tests := []struct {
+		name               string
+		sub                models.Subscription
+		deadline, renewsAt *time.Time
+	}{
+		{
+			name: "no auto-renew",
+			sub:  models.Subscription{StartDate: day(2025, time.January, 15), NoticeDays: 30},
+		},
+		{
+			name: "cancelled",
+			sub:  models.Subscription{StartDate: day(2025, time.January, 15), AutoRenew: true, Status: models.StatusCancelled},
+		},
+		{
+			name:     "monthly without notice renews every billing period",
+			sub:      models.Subscription{StartDate: day(2025, time.January, 15), AutoRenew: true, Status: models.StatusActive},
+			deadline: dayPtr(2025, time.March, 14), renewsAt: dayPtr(2025, time.March, 15),
+		},
+		{
+			name: "annual contract with 30 day notice",
+			sub: models.Subscription{StartDate: day(2024, time.May, 1), AutoRenew: true, Status: models.StatusActive,
+				BillingPeriod: models.BillingAnnual, NoticeDays: 30},
+			deadline: dayPtr(2025, time.April, 1), renewsAt: dayPtr(2025, time.May, 1),
+		},
+		{
+			name: "missed deadline moves to the next renewal",
+			sub: models.Subscription{StartDate: day(2024, time.March, 20), AutoRenew: true, Status: models.StatusActive,
+				RenewalTermMonths: 12, NoticeDays: 30},
+			deadline: dayPtr(2026, time.February, 18), renewsAt: dayPtr(2026, time.March, 20),
+		},
+		{
+			name: "minimum term comes first",
+			sub: models.Subscription{StartDate: day(2025, time.January, 31), AutoRenew: true, Status: models.StatusActive,
+				MinimumTermMonths: 3, RenewalTermMonths: 1, NoticeDays: 7},
+			deadline: dayPtr(2025, time.April, 23), renewsAt: dayPtr(2025, time.April, 30),
+		},
+		{
+			name: "explicit end date is the end of the current term",
+			sub: models.Subscription{StartDate: day(2025, time.January, 1), EndDate: &end, AutoRenew: true, Status: models.StatusActive,
+				RenewalTermMonths: 12, NoticeDays: 60},
+			deadline: dayPtr(2025, time.November, 2), renewsAt: dayPtr(2026, time.January, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadline, renewsAt := billing.CancellationDeadline(tt.sub.Billing(), today)
+			assert.Equal(t, tt.deadline, deadline)
+			assert.Equal(t, tt.renewsAt, renewsAt)
+		})
+	}
+}
+
+func TestBilling_RenewedEnd(t *testing.T) { //продления подряд не сползают после короткого месяца
+	tests := []struct {
+		name string
+		sub  models.Subscription
+		ends []time.Time
+	}{
+		{
+			name: "monthly from the 31st",
+			sub:  models.Subscription{StartDate: day(2024, time.December, 31), EndDate: dayPtr(2025, time.January, 30), AutoRenew: true},
+			ends: []time.Time{day(2025, time.February, 27), day(2025, time.March, 30), day(2025, time.April, 29), day(2025, time.May, 30)},
+		},
+		{
+			name: "proration with anchor day 31 ends where the period ends",
+			sub: models.Subscription{StartDate: day(2025, time.January, 31), AnchorDay: 31, AutoRenew: true,
+				ProrationMode: models.ProrationDaily},
+			ends: []time.Time{day(2025, time.March, 30), day(2025, time.April, 29), day(2025, time.May, 30)},
+		},
+		{
+			name: "annual from February 29",
+			sub: models.Subscription{StartDate: day(2024, time.February, 29), EndDate: dayPtr(2025, time.February, 27), AutoRenew: true,
+				BillingPeriod: models.BillingAnnual},
+			ends: []time.Time{day(2026, time.February, 27), day(2027, time.February, 27), day(2028, time.February, 28)},
+		},
+		{
+			name: "end date off the boundary is aligned to it",
+			sub: models.Subscription{StartDate: day(2025, time.January, 10), EndDate: dayPtr(2025, time.March, 20), AutoRenew: true,
+				MinimumTermMonths: 3, RenewalTermMonths: 1},
+			ends: []time.Time{day(2025, time.April, 9), day(2025, time.May, 9)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.sub.Billing()
+			if sub.EndDate == nil { //срок до конца периода, оплаченного за январь
+				end := billing.PaidThrough(sub, billing.MonthStart(sub.StartDate))
+				assert.Equal(t, day(2025, time.February, 27), end)
+				sub.EndDate = &end
+			}
+			var ends []time.Time
+			for range tt.ends {
+				end := billing.RenewedEnd(sub)
+				ends = append(ends, end)
+				assert.Equal(t, end.AddDate(0, 0, 1), *billing.NextRenewal(sub, end), "next renewal follows the new end")
+				sub.EndDate = &end
+			}
+			assert.Equal(t, tt.ends, ends)
+		})
+	}
+}
+
+func TestRenewalDeadlines(t *testing.T) { //только сроки в ближайшие within дней, по порядку
+	ctx := context.Background()
+	subrepo := new(mocks.SubscriptionRepoMock)
+	srepo := new(mocks.ServiceRepoMock)
+	subService := newSubscriptionService(subrepo, srepo, zap.NewNop().Sugar())
+
+	today := billing.DayStart(time.Now())
+	soon := models.Subscription{ID: 1, StartDate: today.AddDate(-1, 0, 20), AutoRenew: true, Status: models.StatusActive,
+		BillingPeriod: models.BillingAnnual, NoticeDays: 10} //продление через 20 дней, срок отмены через 10
+	sooner := models.Subscription{ID: 2, StartDate: today.AddDate(0, -1, 5), AutoRenew: true, Status: models.StatusActive} //продление через 5 дней
+	later := models.Subscription{ID: 3, StartDate: today.AddDate(-1, 0, 90), AutoRenew: true, Status: models.StatusActive,
+		BillingPeriod: models.BillingAnnual, NoticeDays: 30}
+	subrepo.On("FindRenewing", ctx, (*string)(nil)).Return([]models.Subscription{soon, sooner, later}, nil)
+
+	res, err := subService.RenewalDeadlines(ctx, &models.DeadlinesFilter{Within: 30})
+	assert.NoError(t, err)
+	if assert.Len(t, res, 2) {
+		assert.Equal(t, uint(2), res[0].Subscription.ID)
+		assert.Equal(t, uint(1), res[1].Subscription.ID)
+		assert.Equal(t, 10, res[1].DaysLeft)
+		assert.Equal(t, today.AddDate(0, 0, 10), res[1].Deadline)
+	}
+}
+
+func TestRenewDue(t *testing.T) { //каждый пропущенный срок - отдельное продление, уже продленная другим запуском подписка не трогается
+	ctx := context.Background()
+	subrepo := new(mocks.SubscriptionRepoMock)
+	auditrepo := new(mocks.AuditRepoMock)
+	publisher := new(mocks.PublisherMock)
+	log := zap.NewNop().Sugar()
+
+	auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	subService := services.NewSubscriptionService(subrepo, new(mocks.ServiceRepoMock), new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log),
+		noBudgets(subrepo, log), publisher, services.ApprovalConfig{}, log)
+
+	today := billing.DayStart(time.Now())
+	start := billing.MonthStart(today).AddDate(-2, 0, 0)
+	stale := start.AddDate(1, 0, -1) //первый год закончился больше года назад
+	taken := today.AddDate(0, 0, -1)
+	subrepo.On("FindDueRenewals", ctx, today).Return([]models.Subscription{
+		{ID: 1, UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", StartDate: start, EndDate: &stale, AutoRenew: true,
+			Status: models.StatusActive, BillingPeriod: models.BillingMonthly, RenewalTermMonths: 12},
+		{ID: 2, StartDate: taken.AddDate(0, -1, 1), EndDate: &taken, AutoRenew: true, Status: models.StatusActive},
+	}, nil)
+
+	var ends []time.Time
+	subrepo.On("Renew", ctx, mock.MatchedBy(func(sub *models.Subscription) bool { return sub.ID == 1 }), mock.Anything).Run(func(args mock.Arguments) {
+		ends = append(ends, *args.Get(1).(*models.Subscription).EndDate)
+	}).Return(true, nil)
+	subrepo.On("Renew", ctx, mock.MatchedBy(func(sub *models.Subscription) bool { return sub.ID == 2 }), taken).Return(false, nil).Once()
+	subrepo.On("ReplaceCharges", ctx, uint(1), mock.Anything).Return(nil).Twice() //журнал пишется в транзакции каждого продления
+	publisher.On("Publish", ctx, mock.MatchedBy(func(e events.Event) bool { return e.Type == events.TypeSubscriptionRenewed })).Twice()
+
+	count, err := subService.RenewDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count) //срок продления 12 месяцев: конец сдвигается по годам от начала, пока не окажется впереди
+	want := []time.Time{start.AddDate(2, 0, -1), start.AddDate(3, 0, -1)}
+	assert.Equal(t, want, ends)
+	subrepo.AssertCalled(t, "Renew", ctx, mock.Anything, stale)
+	subrepo.AssertNotCalled(t, "ReplaceCharges", ctx, uint(2), mock.Anything) //вторую подписку продлил параллельный запуск
+	publisher.AssertExpectations(t)
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/services"
+	"subscriptions/tests/mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestReport_SpendByTag(t *testing.T) { //подписка с двумя метками входит в обе группы, без меток - в группу с пустым ключом
+	ctx := context.Background()
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	reportService := services.NewReportService(subrepo, new(mocks.CostCenterRepoMock), log)
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	work, family := models.Tag{ID: 1, Name: "work"}, models.Tag{ID: 2, Name: "family"}
+	subs := withCharges(jan, feb,
+		models.Subscription{ID: 1, Price: 1000, StartDate: jan, Tags: []models.Tag{work, family}},
+		models.Subscription{ID: 2, Price: 300, StartDate: jan, Tags: []models.Tag{work}},
+		models.Subscription{ID: 3, Price: 200, StartDate: feb},
+	)
+	subrepo.On("FindForSum", ctx, &repository.SubscriptionQuery{Start: &jan, End: &feb, WithCharges: true}).Return(subs, nil)
+
+	start, end := "01-2025", "02-2025"
+	report, err := reportService.Spend(ctx, &models.SpendFilter{StartDate: &start, EndDate: &end, GroupBy: models.GroupByTag})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2800), report.Total) //каждая подписка в итоге один раз
+	assert.Equal(t, models.DefaultCurrency, report.Currency)
+	assert.Equal(t, []models.SpendGroup{
+		{Key: "work", Amount: 2600, Taxes: models.TaxTotals{Net: 2600, Gross: 2600}, Subscriptions: 2},
+		{Key: "family", Amount: 2000, Taxes: models.TaxTotals{Net: 2000, Gross: 2000}, Subscriptions: 1},
+		{Key: "", Amount: 200, Taxes: models.TaxTotals{Net: 200, Gross: 200}, Subscriptions: 1},
+	}, report.Groups)
+
+	start, end = "03-2025", "02-2025"
+	_, err = reportService.Spend(ctx, &models.SpendFilter{StartDate: &start, EndDate: &end, GroupBy: models.GroupByTag})
+	assert.ErrorIs(t, err, services.ErrInvalidDate)
+	assert.EqualError(t, err, "invalid date: end date must be after start date")
+}
+
+func TestReport_Chargeback(t *testing.T) { //доли делятся без потери копеек, нераспределенные подписки и другие валюты - отдельные строки
+	ctx := context.Background()
+	subrepo := new(mocks.SubscriptionRepoMock)
+	centers := new(mocks.CostCenterRepoMock)
+	log := zap.NewNop().Sugar()
+
+	reportService := services.NewReportService(subrepo, centers, log)
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	thirds := []models.CostAllocation{{CostCenterID: 1, Percent: 33.33}, {CostCenterID: 2, Percent: 33.33}, {CostCenterID: 3, Percent: 33.34}}
+	subs := withCharges(jan, jan,
+		models.Subscription{ID: 1, Price: 1000, StartDate: jan, Allocations: thirds},
+		models.Subscription{ID: 2, Price: 500, StartDate: jan, Allocations: []models.CostAllocation{{CostCenterID: 2, Percent: 100}}},
+		models.Subscription{ID: 3, Price: 700, StartDate: jan},
+		models.Subscription{ID: 4, Price: 900, Currency: "USD", StartDate: jan, Allocations: []models.CostAllocation{{CostCenterID: 1, Percent: 100}}},
+	)
+	subrepo.On("FindForSum", ctx, &repository.SubscriptionQuery{Start: &jan, End: &jan, WithCharges: true}).Return(subs, nil)
+	centers.On("GetAll", ctx).Return([]models.CostCenter{{ID: 1, Code: "IT", Name: "Разработка"}, {ID: 2, Code: "HR", Name: "Кадры"}, {ID: 3, Code: "OPS", Name: "Эксплуатация"}}, nil)
+
+	report, err := reportService.Chargeback(ctx, &models.ChargebackFilter{Month: "01-2025"})
+	assert.NoError(t, err)
+	assert.Equal(t, jan, report.Month)
+
+	type line struct {
+		code     string
+		currency string
+		amount   int64
+		subs     int
+	}
+	var lines []line
+	for _, l := range report.Lines {
+		lines = append(lines, line{l.Code, l.Currency, l.Amount, l.Subscriptions})
+	}
+	assert.Equal(t, []line{
+		{"HR", models.DefaultCurrency, 333 + 500, 2},
+		{"IT", models.DefaultCurrency, 333, 1},
+		{"IT", "USD", 900, 1},
+		{"OPS", models.DefaultCurrency, 334, 1},
+		{"", models.DefaultCurrency, 700, 1}, //без распределения
+	}, lines)
+
+	_, err = reportService.Chargeback(ctx, &models.ChargebackFilter{Month: "2025/01"})
+	assert.ErrorIs(t, err, services.ErrInvalidDateFormat)
+}
+
+func TestReport_Churn(t *testing.T) { //отмены по месяцам и сервисам, причины и средний срок жизни; отмена вне периода не считается
+	ctx := context.Background()
+	subrepo := new(mocks.SubscriptionRepoMock)
+	reportService := services.NewReportService(subrepo, new(mocks.CostCenterRepoMock), zap.NewNop().Sugar())
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	expensive, unused := models.CancelReasonTooExpensive, models.CancelReasonNotUsed
+	cancelled := func(id, serviceID uint, name string, start, end, month time.Time, reason *string) models.Subscription {
+		return models.Subscription{ID: id, ServiceID: serviceID, Service: models.Service{ID: serviceID, Name: name}, StartDate: start, EndDate: &end,
+			Status: models.StatusCancelled, Transitions: []models.StatusTransition{
+				{To: models.StatusActive, Date: start},
+				{From: models.StatusActive, To: models.StatusCancelled, Date: month, Reason: reason},
+			}}
+	}
+	subrepo.On("FindCancelled", ctx, jan, feb, (*string)(nil)).Return([]models.Subscription{
+		cancelled(1, 1, "Netflix", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), jan, &expensive),
+		cancelled(2, 1, "Netflix", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), feb, &unused),
+		cancelled(3, 2, "Spotify", time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), feb, nil),
+		cancelled(4, 2, "Spotify", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), nil),
+	}, nil)
+
+	start, end := "01-2025", "02-2025"
+	report, err := reportService.Churn(ctx, &models.ChurnFilter{StartDate: &start, EndDate: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Cancellations)
+	assert.Equal(t, []models.ChurnLine{
+		{Month: jan, ServiceID: 1, ServiceName: "Netflix", Cancellations: 1},
+		{Month: feb, ServiceID: 1, ServiceName: "Netflix", Cancellations: 1},
+		{Month: feb, ServiceID: 2, ServiceName: "Spotify", Cancellations: 1},
+	}, report.Lines)
+	assert.Equal(t, []models.ChurnReason{{Reason: "not_used", Cancellations: 1}, {Reason: "too_expensive", Cancellations: 1}, {Reason: "unspecified", Cancellations: 1}}, report.Reasons)
+	assert.Equal(t, 8.0, report.AverageLifetimeMonths) //около 12, 8 и 4 месяцев
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"subscriptions/billing"
+	"subscriptions/models"
+	"subscriptions/repository"
+	"subscriptions/services"
+	"subscriptions/tests/mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestServiceStats(t *testing.T) { //цены действующих подписок, выручка из журнала за текущий месяц и тренд за последние months месяцев
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+	serviceService := services.NewServiceService(srepo, subrepo, services.NewAuditService(new(mocks.AuditRepoMock), log), log)
+
+	current := billing.MonthStart(time.Now())
+	started := current.AddDate(0, -3, 0)
+	vat := 20.0
+	promo := int64(500)
+	promoEnd := current.AddDate(0, 2, 0)
+	ended := current.AddDate(0, 0, -1)
+	subs := withCharges(current, current,
+		models.Subscription{ID: 1, Price: 1000, Currency: "RUB", StartDate: started, Status: models.StatusActive, TaxRate: &vat}, //налог сверху: 1200 в выручке
+		models.Subscription{ID: 2, Price: 12000, Currency: "RUB", StartDate: current, Status: models.StatusActive, BillingPeriod: models.BillingAnnual},
+		models.Subscription{ID: 3, Price: 3000, Currency: "RUB", StartDate: started, Status: models.StatusTrial, //в ценах есть, выручки нет
+			Transitions: []models.StatusTransition{{To: models.StatusTrial, Date: started}}},
+		models.Subscription{ID: 4, Price: 2000, Currency: "RUB", StartDate: started, Status: models.StatusActive, PromoPrice: &promo, PromoEndDate: &promoEnd},
+		models.Subscription{ID: 5, Price: 9000, Currency: "RUB", StartDate: started, Status: models.StatusPaused, //на паузе: нет ни в ценах, ни в выручке
+			Transitions: []models.StatusTransition{{To: models.StatusPaused, Date: current.AddDate(0, -1, 0)}}},
+		models.Subscription{ID: 6, Price: 7000, Currency: "RUB", StartDate: started, EndDate: &ended, Status: models.StatusCancelled},
+		models.Subscription{ID: 7, Price: 15, Currency: "USD", StartDate: started, Status: models.StatusActive},
+	)
+
+	serviceID := uint(1)
+	srepo.On("GetById", ctx, uint(1)).Return(&models.Service{ID: 1, Name: "Notion"}, nil)
+	srepo.On("GetById", ctx, uint(2)).Return((*models.Service)(nil), gorm.ErrRecordNotFound)
+	subrepo.On("CountSubscribers", ctx, uint(1), mock.AnythingOfType("time.Time")).Return(int64(5), nil)
+	subrepo.On("FindForSum", ctx, &repository.SubscriptionQuery{ServiceID: &serviceID, Start: &current, End: &current, WithCharges: true}).Return(subs, nil)
+
+	trend := []models.SubscriberPoint{{Month: current.AddDate(0, -2, 0), Subscribers: 1}, {Month: current.AddDate(0, -1, 0), Subscribers: 2}, {Month: current, Subscribers: 2}}
+	subrepo.On("SubscriberTrend", ctx, uint(1), current.AddDate(0, -2, 0), current).Return(trend, nil)
+
+	months := 3
+	stats, err := serviceService.Stats(ctx, 1, &models.ServiceStatsFilter{Months: &months})
+	assert.NoError(t, err)
+	prices := []models.PriceStats{
+		{Currency: "RUB", Subscriptions: 4, Average: 1750, Min: 1000, Max: 3000, MonthlyRevenue: 1200 + 12000 + 500}, //годовая цена в ценах 1000 в месяц, в выручке целиком
+		{Currency: "USD", Subscriptions: 1, Average: 15, Min: 15, Max: 15, MonthlyRevenue: 15},
+	}
+	assert.Equal(t, &models.ServiceStats{ServiceID: 1, ServiceName: "Notion", ActiveSubscribers: 5, Prices: prices, Trend: trend}, stats)
+
+	_, err = serviceService.Stats(ctx, 2, &models.ServiceStatsFilter{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestServiceUpdate_TaxRebuildsCharges(t *testing.T) { //смена налога пересобирает журнал подписок сервиса, смена категории - нет
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	auditrepo := new(mocks.AuditRepoMock)
+	log := zap.NewNop().Sugar()
+	auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	serviceService := services.NewServiceService(srepo, subrepo, services.NewAuditService(auditrepo, log), log)
+
+	vat := 20.0
+	serviceID := uint(1)
+	srepo.On("GetById", ctx, serviceID).Return(&models.Service{ID: 1, Name: "Notion", TaxRate: &vat}, nil)
+	srepo.On("Update", ctx, mock.AnythingOfType("*models.Service")).Return(nil)
+
+	category := "work"
+	_, err := serviceService.Update(ctx, 1, &models.UpdateService{Category: &category, TaxRate: &vat})
+	assert.NoError(t, err)
+	subrepo.AssertNotCalled(t, "SubscriptionIDs", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	subrepo.On("SubscriptionIDs", ctx, &serviceID, uint(0), mock.Anything).Return([]uint{3, 5}, nil)
+	subrepo.On("RebuildCharges", ctx, mock.AnythingOfType("uint"), mock.AnythingOfType("repository.LedgerFunc")).Return(12, nil)
+	_, err = serviceService.Update(ctx, 1, &models.UpdateService{Category: &category, TaxRate: &vat, TaxInclusive: true})
+	assert.NoError(t, err)
+	subrepo.AssertCalled(t, "RebuildCharges", ctx, uint(3), mock.Anything)
+	subrepo.AssertCalled(t, "RebuildCharges", ctx, uint(5), mock.Anything)
+}
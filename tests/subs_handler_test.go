@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"subscriptions/handlers"
+	"subscriptions/models"
+	"subscriptions/tests/mocks"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+func TestSumHandler_Prorations(t *testing.T) { //ответ суммы объясняет, как посчитан каждый неполный месяц
+	gin.SetMode(gin.TestMode)
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	handler := handlers.NewSubscriptionHandler(newSubscriptionService(subrepo, srepo, log))
+	router := gin.New()
+	router.GET("/api/subs/sum", handler.SumByFilters)
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	cancelled := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	sub := models.Subscription{ID: 1, Price: 310, StartDate: jan, AnchorDay: 1, EndDate: &cancelled, ProrationMode: models.ProrationDaily}
+	subrepo.On("FindForSum", mock.Anything, mock.AnythingOfType("*repository.SubscriptionQuery")).Return(withCharges(mar, mar, sub), nil)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/subs/sum?start_date=03-2025&end_date=03-2025", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Amount     int64              `json:"sum_minor"`
+		Prorations []models.Proration `json:"prorations"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int64(100), body.Amount)
+	assert.Equal(t, []models.Proration{
+		{SubscriptionID: 1, Month: mar, Mode: models.ProrationDaily, PeriodStart: mar, PeriodEnd: cancelled, Days: 10, CycleDays: 31,
+			Parts: []models.ProrationPart{{Days: 10, Price: 310, DailyRate: 10}}, Amount: 100,
+			Explanation: "daily: 10/31 days at 310, period 2025-03-01 - 2025-03-10"},
+	}, body.Prorations)
+}
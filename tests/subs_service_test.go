@@ -2,11 +2,13 @@ package tests
 
 import (
 	"context"
+	"errors"
 	"subscriptions/services"
 	"testing"
 	"time"
 
 	"subscriptions/models"
+	"subscriptions/repository"
 	"subscriptions/tests/mocks"
 
 	"github.com/stretchr/testify/assert"
@@ -21,7 +23,7 @@ func TestCreate_NoService(t *testing.T) { //нет сервиса с таким
 	subrepo := new(mocks.SubscriptionRepoMock)
 	log := zap.NewNop().Sugar()
 
-	subService := services.NewSubscriptionService(subrepo, srepo, log)
+	subService := newSubscriptionService(subrepo, srepo, log)
 
 	price := uint(500)
 	createSub := &models.CreateSubscription{
@@ -43,7 +45,7 @@ func TestCreate_NoService(t *testing.T) { //нет сервиса с таким
 	assert.NotNil(t, res)
 	assert.NotZero(t, res.ServiceID)
 	assert.Equal(t, "6a2995b1-9967-473c-ab26-2710f6e66fd5", res.UserID)
-	assert.Equal(t, uint(500), res.Price)
+	assert.Equal(t, int64(500*models.MinorUnits), res.Price) //устаревшая цена в целых единицах переводится в копейки
 	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), res.StartDate)
 
 	srepo.AssertExpectations(t)
@@ -56,7 +58,7 @@ func TestCreate_InvalidDate(t *testing.T) { //невалидная дата
 	subrepo := new(mocks.SubscriptionRepoMock)
 	log := zap.NewNop().Sugar()
 
-	subService := services.NewSubscriptionService(subrepo, srepo, log)
+	subService := newSubscriptionService(subrepo, srepo, log)
 
 	end := "01-2024"
 	price := uint(500)
@@ -75,7 +77,8 @@ func TestCreate_InvalidDate(t *testing.T) { //невалидная дата
 
 	assert.Nil(t, res)
 	assert.Error(t, err)
-	assert.EqualError(t, err, "end date must be after start date")
+	assert.ErrorIs(t, err, services.ErrInvalidDate)
+	assert.EqualError(t, err, "invalid date: end date must be after start date")
 }
 
 func TestUpdate_Success(t *testing.T) { //успешное обновление
@@ -84,7 +87,7 @@ func TestUpdate_Success(t *testing.T) { //успешное обновление
 	subrepo := new(mocks.SubscriptionRepoMock)
 	log := zap.NewNop().Sugar()
 
-	subService := services.NewSubscriptionService(subrepo, srepo, log)
+	subService := newSubscriptionService(subrepo, srepo, log)
 
 	existedSub := &models.Subscription{
 		ID:        1,
@@ -98,13 +101,13 @@ func TestUpdate_Success(t *testing.T) { //успешное обновление
 	newPrice := uint(600)
 
 	subrepo.On("GetById", ctx, uint(1)).Return(existedSub, nil)
-	subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription")).Return(nil)
+	subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription"), mock.Anything).Return(nil)
 
 	res, err := subService.Update(ctx, 1, &models.UpdateSubscription{Price: &newPrice})
 
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
-	assert.Equal(t, uint(600), res.Price)
+	assert.Equal(t, int64(600*models.MinorUnits), res.Price)
 }
 
 func TestUpdate_InvalidDate(t *testing.T) { //невалидная дата
@@ -113,7 +116,7 @@ func TestUpdate_InvalidDate(t *testing.T) { //невалидная дата
 	subrepo := new(mocks.SubscriptionRepoMock)
 	log := zap.NewNop().Sugar()
 
-	subService := services.NewSubscriptionService(subrepo, srepo, log)
+	subService := newSubscriptionService(subrepo, srepo, log)
 
 	existedSub := &models.Subscription{
 		ID:        1,
@@ -127,13 +130,14 @@ func TestUpdate_InvalidDate(t *testing.T) { //невалидная дата
 	newEnd := "01-2024"
 
 	subrepo.On("GetById", ctx, uint(1)).Return(existedSub, nil)
-	subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription")).Return(nil)
+	subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription"), mock.Anything).Return(nil)
 
 	res, err := subService.Update(ctx, 1, &models.UpdateSubscription{EndDate: &newEnd})
 
 	assert.Nil(t, res)
 	assert.Error(t, err)
-	assert.EqualError(t, err, "end date must be after start date")
+	assert.ErrorIs(t, err, services.ErrInvalidDate)
+	assert.EqualError(t, err, "invalid date: end date must be after start date")
 }
 
 func TestSumByFilters_Success(t *testing.T) { //успешное получение суммы
@@ -142,7 +146,7 @@ func TestSumByFilters_Success(t *testing.T) { //успешное получен
 	subrepo := new(mocks.SubscriptionRepoMock)
 	log := zap.NewNop().Sugar()
 
-	subService := services.NewSubscriptionService(subrepo, srepo, log)
+	subService := newSubscriptionService(subrepo, srepo, log)
 
 	userID := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
 	serviceName := "Spotify"
@@ -157,13 +161,16 @@ func TestSumByFilters_Success(t *testing.T) { //успешное получен
 
 	endDate, _ := time.Parse("01-2006", end)
 	startDate, _ := time.Parse("01-2006", start)
+	subEnd := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
 
-	subrepo.On("SumByFilters", ctx, &userID, &serviceName, &startDate, &endDate).Return(1000, nil)
+	subrepo.On("FindForSum", ctx, &repository.SubscriptionQuery{UserID: &userID, ServiceName: &serviceName, Start: &startDate, End: &endDate, WithCharges: true}).Return(withCharges(startDate, endDate,
+		models.Subscription{ID: 1, Price: 500, StartDate: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), EndDate: &subEnd}, //в периоде январь и февраль
+	), nil)
 
 	res, err := subService.SumByFilters(ctx, filters)
 	assert.NoError(t, err)
 	assert.NotZero(t, res)
-	assert.Equal(t, 1000, res)
+	assert.Equal(t, models.Money{Amount: 1000, Currency: models.DefaultCurrency}, res.Money)
 
 }
 
@@ -173,7 +180,7 @@ func TestSumByFilters_InvalidDate(t *testing.T) { //невалидная дат
 	subrepo := new(mocks.SubscriptionRepoMock)
 	log := zap.NewNop().Sugar()
 
-	subService := services.NewSubscriptionService(subrepo, srepo, log)
+	subService := newSubscriptionService(subrepo, srepo, log)
 
 	userID := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
 	serviceName := "Spotify"
@@ -189,10 +196,872 @@ func TestSumByFilters_InvalidDate(t *testing.T) { //невалидная дат
 	endDate, _ := time.Parse("01-2006", end)
 	startDate, _ := time.Parse("01-2006", start)
 
-	subrepo.On("SumByFilters", ctx, &userID, &serviceName, &startDate, &endDate).Return(1000, nil)
+	subrepo.On("FindForSum", ctx, &repository.SubscriptionQuery{UserID: &userID, ServiceName: &serviceName, Start: &startDate, End: &endDate}).Return([]models.Subscription{}, nil)
 
 	res, err := subService.SumByFilters(ctx, filters)
 	assert.Zero(t, res)
 	assert.Error(t, err)
-	assert.EqualError(t, err, "end date must be after start date")
+	assert.ErrorIs(t, err, services.ErrInvalidDate)
+	assert.EqualError(t, err, "invalid date: end date must be after start date")
+}
+
+func TestRestore_NotFound(t *testing.T) { //восстановление несуществующей подписки
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	subrepo.On("Restore", ctx, uint(1)).Return(gorm.ErrRecordNotFound)
+
+	res, err := subService.Restore(ctx, 1)
+
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	subrepo.AssertNotCalled(t, "GetById", ctx, uint(1))
+}
+
+func TestPurgeDeleted_Retention(t *testing.T) { //очистка учитывает срок хранения и удаляет файлы вложений из хранилища
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	blobs := new(mocks.BlobStoreMock)
+	log := zap.NewNop().Sugar()
+
+	subService := services.NewSubscriptionService(subrepo, srepo, new(mocks.CostCenterRepoMock), blobs, services.NewAuditService(new(mocks.AuditRepoMock), log), noBudgets(subrepo, log), anyEvents(), services.ApprovalConfig{}, log)
+
+	retention := 30 * 24 * time.Hour
+	expected := time.Now().Add(-retention)
+
+	keys := []string{"subscriptions/1/a.pdf", "subscriptions/2/b.png"}
+	subrepo.On("PurgeDeleted", ctx, mock.MatchedBy(func(before time.Time) bool {
+		return before.Sub(expected).Abs() < time.Minute
+	})).Return(int64(3), keys, nil)
+	blobs.On("Delete", ctx, "subscriptions/1/a.pdf").Return(errors.New("storage unavailable")) //ошибка хранилища не отменяет очистку
+	blobs.On("Delete", ctx, "subscriptions/2/b.png").Return(nil)
+
+	count, err := subService.PurgeDeleted(ctx, retention)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+	subrepo.AssertExpectations(t)
+	blobs.AssertExpectations(t)
+}
+
+func TestSumByFilters_AsOf(t *testing.T) { //сумма по состоянию на прошлую дату
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	asOf := "2025-03-31"
+	filters := &models.SumFilter{AsOf: &asOf}
+
+	asOfTime := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	subrepo.On("FindForSum", ctx, &repository.SubscriptionQuery{AsOf: &asOfTime}).Return([]models.Subscription{
+		{ID: 1, Price: 100, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, //без конца считается по март включительно
+	}, nil)
+
+	res, err := subService.SumByFilters(ctx, filters)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), res.Amount)
+
+	bad := "03-2025"
+	_, err = subService.SumByFilters(ctx, &models.SumFilter{AsOf: &bad})
+	assert.ErrorIs(t, err, services.ErrInvalidAsOf)
+}
+
+func TestHistory_NotFound(t *testing.T) { //у несуществующей подписки нет истории
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	subrepo.On("GetHistory", ctx, uint(42)).Return([]models.SubscriptionVersion{}, nil)
+
+	res, err := subService.History(ctx, 42)
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSumByFilters_PausedAndTrial(t *testing.T) { //месяцы паузы и пробного периода не оплачиваются
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	month := func(m int) time.Time { return time.Date(2025, time.Month(m), 1, 0, 0, 0, 0, time.UTC) }
+	start := "01-2025"
+	end := "12-2025"
+	cancelled := month(10)
+
+	subrepo.On("FindForSum", ctx, mock.AnythingOfType("*repository.SubscriptionQuery")).Return(withCharges(month(1), month(12), models.Subscription{
+		ID: 1, Price: 100, StartDate: month(1), EndDate: &cancelled, Status: models.StatusCancelled,
+		Transitions: []models.StatusTransition{
+			{To: models.StatusTrial, Date: month(1)},                                 //январь и февраль бесплатно
+			{From: models.StatusTrial, To: models.StatusActive, Date: month(3)},      //март, апрель
+			{From: models.StatusActive, To: models.StatusPaused, Date: month(5)},     //май - июль на паузе
+			{From: models.StatusPaused, To: models.StatusActive, Date: month(8)},     //август, сентябрь
+			{From: models.StatusActive, To: models.StatusCancelled, Date: month(10)}, //октябрь - последний месяц
+		},
+	}), nil)
+
+	res, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), res.Amount)
+}
+
+func TestChangeStatus(t *testing.T) { //переходы конечного автомата
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSub := func(status string) *models.Subscription {
+		return &models.Subscription{ID: 1, Price: 100, StartDate: start, Status: status,
+			Transitions: []models.StatusTransition{{To: status, Date: start}}}
+	}
+	date := "03-2025"
+	early := "12-2024"
+
+	tests := []struct {
+		name    string
+		status  string
+		action  string
+		date    *string
+		want    string
+		wantErr error
+	}{
+		{"trial activate", models.StatusTrial, services.ActionActivate, &date, models.StatusActive, nil},
+		{"active pause", models.StatusActive, services.ActionPause, &date, models.StatusPaused, nil},
+		{"paused resume", models.StatusPaused, services.ActionResume, &date, models.StatusActive, nil},
+		{"paused cancel", models.StatusPaused, services.ActionCancel, &date, models.StatusCancelled, nil},
+		{"trial pause", models.StatusTrial, services.ActionPause, &date, "", services.ErrInvalidTransition},
+		{"cancelled resume", models.StatusCancelled, services.ActionResume, &date, "", services.ErrInvalidTransition},
+		{"active activate", models.StatusActive, services.ActionActivate, &date, "", services.ErrInvalidTransition},
+		{"before start", models.StatusActive, services.ActionPause, &early, "", services.ErrInvalidTransitionDate},
+		{"unknown action", models.StatusActive, "archive", &date, "", services.ErrUnknownAction},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subrepo.ExpectedCalls = nil
+			acceptCharges(subrepo)
+			subrepo.On("GetById", ctx, uint(1)).Return(newSub(tt.status), nil)
+			subrepo.On("AddTransition", ctx, mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("*models.StatusTransition")).Return(nil)
+
+			res, err := subService.ChangeStatus(ctx, 1, tt.action, &models.TransitionRequest{Date: tt.date})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, res)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, res.Status)
+			assert.Len(t, res.Transitions, 2)
+			if tt.want == models.StatusCancelled {
+				assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), *res.EndDate)
+			}
+		})
+	}
+}
+
+func TestChangeStatus_StartMidMonth(t *testing.T) { //подписку, начатую не с 1-го числа, можно приостановить или отменить в месяце начала
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	price := uint(300)
+	srepo.On("GetByName", ctx, "Spotify").Return(&models.Service{ID: 1, Name: "Spotify"}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Return(nil)
+	created, err := subService.Create(ctx, &models.CreateSubscription{ServiceName: "Spotify", UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", Price: &price, StartDate: "2025-07-27"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), created.Transitions[0].Date)
+
+	for _, action := range []string{services.ActionPause, services.ActionCancel} {
+		t.Run(action, func(t *testing.T) {
+			sub := *created
+			sub.ID = 1
+			sub.Transitions = append([]models.StatusTransition(nil), created.Transitions...)
+			subrepo.ExpectedCalls = nil
+			acceptCharges(subrepo)
+			subrepo.On("GetById", ctx, uint(1)).Return(&sub, nil)
+			subrepo.On("AddTransition", ctx, mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("*models.StatusTransition")).Return(nil)
+
+			date := "07-2025"
+			res, err := subService.ChangeStatus(ctx, 1, action, &models.TransitionRequest{Date: &date})
+			assert.NoError(t, err)
+			assert.Len(t, res.Transitions, 2)
+			assert.Equal(t, time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), res.Transitions[1].Date)
+		})
+	}
+}
+
+func TestCreate_TrialAndPromo(t *testing.T) { //пробный период и вводная цена
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	price := uint(500)
+	promoPrice := uint(100)
+	trialDays := uint(14)
+	promoMonths := uint(3)
+	createSub := &models.CreateSubscription{
+		ServiceName: "Spotify",
+		UserID:      "6a2995b1-9967-473c-ab26-2710f6e66fd5",
+		Price:       &price,
+		StartDate:   "01-2025",
+		TrialDays:   &trialDays,
+		PromoPrice:  &promoPrice,
+		PromoMonths: &promoMonths,
+	}
+
+	srepo.On("GetByName", ctx, "Spotify").Return(&models.Service{ID: 1, Name: "Spotify"}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Return(nil)
+
+	res, err := subService.Create(ctx, createSub)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusTrial, res.Status)
+	assert.Equal(t, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), *res.TrialEndDate)
+	assert.Equal(t, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), *res.PromoEndDate) //февраль - апрель по вводной цене
+
+	start := "01-2025"
+	end := "06-2025"
+	subrepo.On("FindForSum", ctx, mock.AnythingOfType("*repository.SubscriptionQuery")).
+		Return(withCharges(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), *res), nil)
+
+	sum, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, int64((3*100+2*500)*models.MinorUnits), sum.Amount) //январь бесплатно, затем три месяца по 100 и два по 500
+}
+
+func TestSumByFilters_CostBasisShare(t *testing.T) { //совместная подписка: плательщик и участники платят свои доли
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	payer := "6a2995b1-9967-473c-ab26-2710f6e66fd5"
+	member := "0b7c1f0e-4b8d-4c55-9d55-3d1f6a7b8c9d"
+	percent := 25.0
+	amount := int64(100)
+	start := "01-2025"
+	end := "03-2025"
+	family := models.Subscription{
+		ID: 1, UserID: payer, Price: 1000, StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Members: []models.SubscriptionMember{
+			{UserID: member, SharePercent: &percent},
+			{UserID: "9f0e8d7c-6b5a-4c3d-8e2f-1a0b9c8d7e6f", ShareAmount: &amount},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		userID    string
+		costBasis string
+		withShare bool
+		want      int
+	}{
+		{"payer full price", payer, models.CostBasisPayer, false, 3 * 1000},
+		{"payer share", payer, models.CostBasisShare, true, 3 * (1000 - 250 - 100)},
+		{"member share", member, models.CostBasisShare, true, 3 * 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subrepo.ExpectedCalls = nil
+			userID := tt.userID
+			costBasis := tt.costBasis
+			subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool {
+				return *q.UserID == userID && q.WithShared == tt.withShare
+			})).Return(withCharges(family.StartDate, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), family), nil)
+
+			res, err := subService.SumByFilters(ctx, &models.SumFilter{UserID: &userID, StartDate: &start, EndDate: &end, CostBasis: &costBasis})
+			assert.NoError(t, err)
+			assert.Equal(t, int64(tt.want), res.Amount)
+		})
+	}
+
+	t.Run("share without user", func(t *testing.T) { //без участника долю считать не для кого
+		subrepo.ExpectedCalls, subrepo.Calls = nil, nil
+		costBasis := models.CostBasisShare
+		_, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &end, CostBasis: &costBasis})
+		assert.ErrorIs(t, err, services.ErrShareWithoutUser)
+		subrepo.AssertNotCalled(t, "FindForSum", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCreate_InvalidMembers(t *testing.T) { //доли участников не должны превышать цену
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	price := uint(500)
+	percent := 60.0
+	amount := uint(300)
+	createSub := &models.CreateSubscription{
+		ServiceName: "Spotify",
+		UserID:      "6a2995b1-9967-473c-ab26-2710f6e66fd5",
+		Price:       &price,
+		StartDate:   "01-2025",
+		Members: []models.MemberInput{
+			{UserID: "0b7c1f0e-4b8d-4c55-9d55-3d1f6a7b8c9d", SharePercent: &percent},
+			{UserID: "9f0e8d7c-6b5a-4c3d-8e2f-1a0b9c8d7e6f", ShareAmount: &amount},
+		},
+	}
+
+	srepo.On("GetByName", ctx, "Spotify").Return(&models.Service{ID: 1, Name: "Spotify"}, nil)
+
+	res, err := subService.Create(ctx, createSub)
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, services.ErrInvalidMembers)
+	subrepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestForecast(t *testing.T) { //прогноз учитывает окончание вводной цены и дату окончания подписки
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	promoPrice := int64(100)
+	promoEnd := thisMonth.AddDate(0, 2, 0)
+	end := thisMonth.AddDate(0, 2, 0)
+	subs := []models.Subscription{
+		{ID: 1, Price: 300, StartDate: thisMonth.AddDate(-1, 0, 0), PromoPrice: &promoPrice, PromoEndDate: &promoEnd},
+		{ID: 2, Price: 50, StartDate: thisMonth, EndDate: &end},
+	}
+
+	ledger := withCharges(thisMonth, thisMonth.AddDate(0, 3, 0), subs...)
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool {
+		return q.WithCharges && q.Start.Equal(thisMonth) && q.End.Equal(thisMonth.AddDate(0, 3, 0))
+	})).Return(ledger, nil)
+
+	res, err := subService.Forecast(ctx, &models.ForecastFilter{Months: 4})
+	assert.NoError(t, err)
+	if assert.Len(t, res.Months, 4) {
+		assert.Equal(t, []int64{150, 150, 350, 300}, []int64{res.Months[0].Amount, res.Months[1].Amount, res.Months[2].Amount, res.Months[3].Amount})
+		assert.Equal(t, int64(650), res.Months[2].Cumulative)
+	}
+	assert.Equal(t, int64(950), res.Total)
+
+	ledger[1].Charges[0].Amount = 70 //прогноз берет суммы из журнала, а не пересчитывает условия
+	res, err = subService.Forecast(ctx, &models.ForecastFilter{Months: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(170), res.Months[0].Amount)
+}
+
+func TestSimulate(t *testing.T) { //отмена, переход на годовую оплату и новая подписка без записи в базу
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	now := time.Now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	subs := []models.Subscription{
+		{ID: 1, Price: 100, StartDate: thisMonth.AddDate(-1, 0, 0), BillingPeriod: models.BillingMonthly},
+		{ID: 2, Price: 200, StartDate: thisMonth.AddDate(-1, 0, 0), BillingPeriod: models.BillingMonthly},
+	}
+	subrepo.On("FindForSum", ctx, mock.AnythingOfType("*repository.SubscriptionQuery")).Return(withCharges(thisMonth, thisMonth.AddDate(0, 11, 0), subs...), nil)
+
+	cancelID, switchID := uint(1), uint(2)
+	annual := models.BillingAnnual
+	month := thisMonth.AddDate(0, 2, 0).Format("01-2006")
+	annualPrice, newPrice := int64(2000), int64(50)
+
+	res, err := subService.Simulate(ctx, &models.SimulationRequest{Months: 12, Changes: []models.SimulationChange{
+		{Action: models.SimulationCancel, SubscriptionID: &cancelID, Month: &month},
+		{Action: models.SimulationSwitch, SubscriptionID: &switchID, Month: &month, PriceMinor: &annualPrice, BillingPeriod: &annual},
+		{Action: models.SimulationAdd, PriceMinor: &newPrice, ServiceName: "Music"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12*300), res.Before.Total)
+	assert.Equal(t, int64(2*100+2*200+2000+12*50), res.After.Total)
+	assert.Equal(t, res.Before.Total-res.After.Total, res.Savings)
+	assert.Equal(t, int64(100+200+50), res.After.Months[0].Amount)
+	assert.Equal(t, int64(100+200+50), res.After.Months[1].Amount)
+	assert.Equal(t, int64(2000+50), res.After.Months[2].Amount) //отмена с этого месяца: он уже не оплачивается
+	assert.Equal(t, int64(50), res.After.Months[3].Amount)
+	assert.Equal(t, int64(100), subs[0].Price) //исходные подписки не меняются
+	assert.Nil(t, subs[0].EndDate)
+
+	subrepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	subrepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	this := thisMonth.Format("01-2006")
+	res, err = subService.Simulate(ctx, &models.SimulationRequest{Months: 12, Changes: []models.SimulationChange{
+		{Action: models.SimulationCancel, SubscriptionID: &cancelID, Month: &this},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12*200), res.After.Total) //отмена с первого месяца прогноза убирает все списания
+
+	unknown := uint(99)
+	_, err = subService.Simulate(ctx, &models.SimulationRequest{Changes: []models.SimulationChange{{Action: models.SimulationCancel, SubscriptionID: &unknown}}})
+	assert.ErrorIs(t, err, services.ErrInvalidSimulation)
+}
+
+func TestCreate_DayLevelDates(t *testing.T) { //дата с днем задает день списания, MM-YYYY по-прежнему работает
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	price := uint(300)
+	end := "04-2025"
+	srepo.On("GetByName", ctx, "Spotify").Return(&models.Service{ID: 1, Name: "Spotify"}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Return(nil)
+
+	res, err := subService.Create(ctx, &models.CreateSubscription{ServiceName: "Spotify", UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", Price: &price, StartDate: "2025-01-31", EndDate: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(31), res.AnchorDay)
+	assert.Equal(t, time.Date(2025, 4, 30, 0, 0, 0, 0, time.UTC), *res.EndDate) //апрель оплачивается, списание 30-го
+
+	legacy, err := subService.Create(ctx, &models.CreateSubscription{ServiceName: "Spotify", UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", Price: &price, StartDate: "01-2025", EndDate: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(1), legacy.AnchorDay)
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), *legacy.EndDate)
+
+	bad := "31.01.2025"
+	_, err = subService.Create(ctx, &models.CreateSubscription{ServiceName: "Spotify", UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", Price: &price, StartDate: bad})
+	assert.ErrorIs(t, err, services.ErrInvalidDateFormat)
+
+	start := "01-2025"
+	sumEnd := "2025-06-15"
+	subrepo.On("FindForSum", ctx, mock.AnythingOfType("*repository.SubscriptionQuery")).
+		Return(withCharges(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), *res), nil)
+	sum, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &sumEnd})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4*300*models.MinorUnits), sum.Amount) //31 января, 28 февраля, 31 марта и 30 апреля
+}
+
+func TestChangeStatus_CancelWithProration(t *testing.T) { //с пересчетом отмена действует с точностью до дня, без дня - до конца периода
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	start := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	newSub := func() *models.Subscription {
+		return &models.Subscription{ID: 1, Price: 100, StartDate: start, AnchorDay: 15, Status: models.StatusActive, ProrationMode: models.ProrationDaily,
+			Transitions: []models.StatusTransition{{To: models.StatusActive, Date: start}}}
+	}
+
+	tests := []struct {
+		date string
+		want time.Time
+	}{
+		{"2025-03-20", time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"03-2025", time.Date(2025, 4, 14, 0, 0, 0, 0, time.UTC)}, //мартовский период оплачен целиком
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			subrepo.ExpectedCalls = nil
+			acceptCharges(subrepo)
+			subrepo.On("GetById", ctx, uint(1)).Return(newSub(), nil)
+			subrepo.On("AddTransition", ctx, mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("*models.StatusTransition")).Return(nil)
+
+			date := tt.date
+			res, err := subService.ChangeStatus(ctx, 1, services.ActionCancel, &models.TransitionRequest{Date: &date})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, *res.EndDate)
+			assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), res.Transitions[1].Date)
+		})
+	}
+}
+
+func TestCancel_ReasonAndMinimumTerm(t *testing.T) { //причина сохраняется в переходе, отмена внутри минимального срока запрещена
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSub := func() *models.Subscription {
+		return &models.Subscription{ID: 1, Price: 100, StartDate: start, AnchorDay: 1, Status: models.StatusActive, MinimumTermMonths: 12,
+			Transitions: []models.StatusTransition{{To: models.StatusActive, Date: start}}}
+	}
+	subrepo.On("GetById", ctx, uint(1)).Return(newSub(), nil).Once()
+
+	early, month := "06-2025", "12-2025"
+	reason, comment := models.CancelReasonSwitched, "переехали на другой сервис"
+	_, err := subService.Cancel(ctx, 1, &models.CancelRequest{EffectiveMonth: &early, Reason: &reason})
+	assert.ErrorIs(t, err, services.ErrMinimumTerm)
+
+	subrepo.On("GetById", ctx, uint(1)).Return(newSub(), nil).Once()
+	subrepo.On("AddTransition", ctx, mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("*models.StatusTransition")).Return(nil)
+	res, err := subService.Cancel(ctx, 1, &models.CancelRequest{EffectiveMonth: &month, Reason: &reason, Comment: &comment})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, res.Status)
+	assert.Equal(t, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), *res.EndDate) //декабрь - последний месяц минимального срока
+	last := res.Transitions[len(res.Transitions)-1]
+	assert.Equal(t, &reason, last.Reason)
+	assert.Equal(t, &comment, last.Comment)
+}
+
+func TestSumByFilters_Currencies(t *testing.T) { //копейки и валюта: разные валюты без фильтра не складываются
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	start := "01-2025"
+	end := "02-2025"
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rub := models.Subscription{ID: 1, Price: 999, Currency: "RUB", StartDate: jan}
+	usd := models.Subscription{ID: 2, Price: 1050, Currency: "USD", StartDate: jan}
+
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool { return q.Currency == nil })).
+		Return(withCharges(jan, jan.AddDate(0, 1, 0), rub, usd), nil)
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool { return q.Currency != nil && *q.Currency == "USD" })).
+		Return(withCharges(jan, jan.AddDate(0, 1, 0), usd), nil)
+
+	_, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &end})
+	assert.ErrorIs(t, err, services.ErrMixedCurrencies)
+
+	currency := "USD"
+	res, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &end, Currency: &currency})
+	assert.NoError(t, err)
+	assert.Equal(t, models.Money{Amount: 2100, Currency: "USD"}, res.Money) //10.50 за два месяца
+}
+
+func TestMoney_LegacyFields(t *testing.T) { //старые поля в целых единицах заполняются при сохранении
+	promo := int64(1999)
+	sub := models.Subscription{Price: 99999, PromoPrice: &promo}
+	assert.NoError(t, sub.BeforeSave(nil))
+	assert.Equal(t, uint(999), sub.LegacyPrice)
+	assert.Equal(t, uint(19), *sub.LegacyPromoPrice)
+	assert.Equal(t, models.DefaultCurrency, sub.Currency)
+
+	legacy := uint(5)
+	minor := int64(450)
+	assert.Equal(t, int64(500), *models.MinorOrLegacy(nil, &legacy))
+	assert.Equal(t, minor, *models.MinorOrLegacy(&minor, &legacy)) //новое поле важнее устаревшего
+	assert.Nil(t, models.MinorOrLegacy(nil, nil))
+}
+
+func TestSumByFilters_Taxes(t *testing.T) { //сумма без налога, налог и сумма с налогом по ставкам подписок
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	vat := 20.0
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	subs := []models.Subscription{
+		{ID: 1, Price: 1200, StartDate: jan, Service: models.Service{TaxRate: &vat, TaxInclusive: true}}, //1000 + 200 налога внутри цены
+		{ID: 2, Price: 500, StartDate: jan, Service: models.Service{TaxRate: &vat}},                      //500 + 100 налога сверху
+	}
+	subrepo.On("FindForSum", ctx, mock.AnythingOfType("*repository.SubscriptionQuery")).Return(withCharges(jan, jan.AddDate(0, 1, 0), subs...), nil)
+
+	start := "01-2025"
+	end := "02-2025"
+	res, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2*1700), res.Amount)
+	assert.Equal(t, models.TaxTotals{Net: 2 * 1500, Tax: 2 * 300, Gross: 2 * 1800}, res.TaxTotals)
+}
+
+func TestSumByFilters_Prorations(t *testing.T) { //в ответе по каждому неполному периоду видны дни, период и дневная ставка
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	cancelled := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	raised := time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC)
+	subs := []models.Subscription{
+		{ID: 1, Price: 310, StartDate: jan, AnchorDay: 1, EndDate: &cancelled, ProrationMode: models.ProrationDaily}, //отмена 10 марта
+		{ID: 2, Price: 620, StartDate: jan, AnchorDay: 1, ProrationMode: models.ProrationDaily, Versions: []models.SubscriptionVersion{ //цена выросла 11 марта
+			{Version: 1, ValidFrom: jan, Price: 310}, {Version: 2, ValidFrom: raised, Price: 620}}},
+		{ID: 3, Price: 500, StartDate: jan, AnchorDay: 1}, //без пересчета
+	}
+	subrepo.On("FindForSum", ctx, mock.AnythingOfType("*repository.SubscriptionQuery")).Return(withCharges(mar, mar, subs...), nil)
+
+	month := "03-2025"
+	res, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &month, EndDate: &month})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100+520+500), res.Amount)
+	assert.Equal(t, []models.Proration{
+		{SubscriptionID: 1, Month: mar, Mode: models.ProrationDaily, PeriodStart: mar, PeriodEnd: cancelled, Days: 10, CycleDays: 31,
+			Parts: []models.ProrationPart{{Days: 10, Price: 310, DailyRate: 10}}, Amount: 100,
+			Explanation: "daily: 10/31 days at 310, period 2025-03-01 - 2025-03-10"},
+		{SubscriptionID: 2, Month: mar, Mode: models.ProrationDaily, PeriodStart: mar, PeriodEnd: time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC), Days: 31, CycleDays: 31,
+			Parts: []models.ProrationPart{{Days: 10, Price: 310, DailyRate: 10}, {Days: 21, Price: 620, DailyRate: 20}}, Amount: 520,
+			Explanation: "daily: 10/31 days at 310 + 21/31 days at 620, period 2025-03-01 - 2025-03-31"},
+	}, res.Prorations)
+}
+
+func TestRebuildCharges(t *testing.T) { //журнал строится заново: по строке на каждый оплачиваемый месяц с валютой и налогом
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	vat := 20.0
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	subs := map[uint]*models.Subscription{
+		1: {ID: 1, Price: 500, Currency: "USD", StartDate: jan, EndDate: &end, TaxRate: &vat}, //январь - март
+		2: {ID: 2, Price: 300, StartDate: jan, EndDate: &jan, Status: models.StatusTrial,
+			Transitions: []models.StatusTransition{{To: models.StatusTrial, Date: jan}}}, //пробный период не оплачивается
+	}
+	subrepo.On("SubscriptionIDs", ctx, (*uint)(nil), uint(0), mock.Anything).Return([]uint{1, 2}, nil)
+
+	var saved []models.Charge
+	for id, sub := range subs { //каждая подписка пересобирается отдельно по своему состоянию в базе
+		sub := sub
+		charges := map[uint]int{1: 3, 2: 0}[id]
+		subrepo.On("RebuildCharges", ctx, id, mock.AnythingOfType("repository.LedgerFunc")).Run(func(args mock.Arguments) {
+			saved = append(saved, args.Get(2).(repository.LedgerFunc)(sub)...)
+		}).Return(charges, nil).Once()
+	}
+	subrepo.On("PruneCharges", ctx).Return(int64(0), nil)
+
+	count, err := subService.RebuildCharges(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	if assert.Len(t, saved, 3) {
+		for i, charge := range saved {
+			assert.Equal(t, uint(1), charge.SubscriptionID)
+			assert.Equal(t, jan.AddDate(0, i, 0), charge.Month)
+			assert.Equal(t, "USD", charge.Currency)
+			assert.Equal(t, models.TaxTotals{Net: 500, Tax: 100, Gross: 600}, charge.TaxTotals)
+		}
+	}
+}
+
+func TestCreate_ChargesUseServiceTax(t *testing.T) { //строки журнала новой подписки считаются по ставке налога сервиса
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	auditrepo := new(mocks.AuditRepoMock)
+	log := zap.NewNop().Sugar()
+
+	auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	subService := services.NewSubscriptionService(subrepo, srepo, new(mocks.CostCenterRepoMock), new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log),
+		noBudgets(subrepo, log), anyEvents(), services.ApprovalConfig{}, log)
+
+	vat := 20.0
+	price := int64(1000)
+	end := "02-2025"
+	srepo.On("GetByName", ctx, "Spotify").Return(&models.Service{ID: 1, Name: "Spotify", TaxRate: &vat}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.Subscription).ID = 7
+	}).Return(nil)
+	var saved []models.Charge
+	subrepo.On("ReplaceCharges", ctx, uint(7), mock.AnythingOfType("[]models.Charge")).Run(func(args mock.Arguments) {
+		saved = args.Get(2).([]models.Charge)
+	}).Return(nil).Once()
+
+	_, err := subService.Create(ctx, &models.CreateSubscription{
+		ServiceName: "Spotify",
+		UserID:      "6a2995b1-9967-473c-ab26-2710f6e66fd5",
+		PriceMinor:  &price,
+		StartDate:   "01-2025",
+		EndDate:     &end,
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, saved, 2) {
+		for _, charge := range saved {
+			assert.Equal(t, models.TaxTotals{Net: 1000, Tax: 200, Gross: 1200}, charge.TaxTotals)
+		}
+	}
+	subrepo.AssertExpectations(t)
+}
+
+func TestCreate_TagsAndNotes(t *testing.T) { //метки приводятся к нижнему регистру без повторов, заметки сохраняются
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	price := int64(29900)
+	notes := "семейный тариф"
+	srepo.On("GetByName", ctx, "Spotify").Return(&models.Service{ID: 1, Name: "Spotify"}, nil)
+	subrepo.On("FindOrCreateTags", ctx, []string{"music", "family"}).Return([]models.Tag{{ID: 2, Name: "family"}, {ID: 1, Name: "music"}}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Return(nil)
+
+	res, err := subService.Create(ctx, &models.CreateSubscription{
+		ServiceName: "Spotify",
+		UserID:      "6a2995b1-9967-473c-ab26-2710f6e66fd5",
+		PriceMinor:  &price,
+		StartDate:   "01-2025",
+		Notes:       &notes,
+		Tags:        []string{" Music", "family", "MUSIC", ""},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Tag{{ID: 2, Name: "family"}, {ID: 1, Name: "music"}}, res.Tags)
+	assert.Equal(t, &notes, res.Notes)
+	subrepo.AssertExpectations(t)
+}
+
+func TestUpdate_ReplacesTags(t *testing.T) { //пустой список убирает все метки, пустые заметки удаляются
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	notes := "старая заметка"
+	existedSub := &models.Subscription{ID: 1, ServiceID: 1, UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", Price: 500,
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Notes: &notes, Tags: []models.Tag{{ID: 1, Name: "work"}}}
+	subrepo.On("GetById", ctx, uint(1)).Return(existedSub, nil)
+	subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription"), &repository.SubscriptionDetails{Tags: &[]models.Tag{}}).Return(nil)
+
+	empty := ""
+	res, err := subService.Update(ctx, 1, &models.UpdateSubscription{Tags: &[]string{}, Notes: &empty})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Tags)
+	assert.Nil(t, res.Notes)
+	subrepo.AssertNotCalled(t, "FindOrCreateTags", mock.Anything, mock.Anything)
+	subrepo.AssertExpectations(t)
+}
+
+func TestUpdate_MembersSavedWithSubscription(t *testing.T) { //участники уходят в репозиторий вместе с подпиской, ошибка не меняет состав
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	member := "0f8fad5b-d9cb-469f-a165-70867728950e"
+	share := 50.0
+	existedSub := &models.Subscription{ID: 1, ServiceID: 1, UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", Price: 1000,
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	subrepo.On("GetById", ctx, uint(1)).Return(existedSub, nil)
+	var details *repository.SubscriptionDetails
+	subrepo.On("Update", ctx, mock.AnythingOfType("*models.Subscription"), mock.AnythingOfType("*repository.SubscriptionDetails")).Run(func(args mock.Arguments) {
+		details = args.Get(2).(*repository.SubscriptionDetails)
+	}).Return(gorm.ErrInvalidTransaction).Once()
+
+	_, err := subService.Update(ctx, 1, &models.UpdateSubscription{Members: &[]models.MemberInput{{UserID: member, SharePercent: &share}}})
+	assert.ErrorIs(t, err, gorm.ErrInvalidTransaction)
+	if assert.NotNil(t, details) && assert.NotNil(t, details.Members) {
+		assert.Len(t, *details.Members, 1)
+		assert.Equal(t, member, (*details.Members)[0].UserID)
+	}
+	assert.Empty(t, existedSub.Members)
+}
+
+func TestSumByFilters_Tag(t *testing.T) { //фильтр по метке без учета регистра передается в выборку
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := newSubscriptionService(subrepo, srepo, log)
+
+	start := "01-2025"
+	tag := " Work "
+	month := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	subrepo.On("FindForSum", ctx, mock.MatchedBy(func(q *repository.SubscriptionQuery) bool { return q.Tag != nil && *q.Tag == "work" })).
+		Return(withCharges(month, month, models.Subscription{ID: 1, Price: 1500, StartDate: month, Tags: []models.Tag{{ID: 1, Name: "work"}}}), nil)
+
+	res, err := subService.SumByFilters(ctx, &models.SumFilter{StartDate: &start, EndDate: &start, Tag: &tag})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), res.Amount)
+	subrepo.AssertExpectations(t)
+}
+
+func TestCreate_Allocations(t *testing.T) { //доли центров затрат в сумме 100%, центры должны существовать
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	centers := new(mocks.CostCenterRepoMock)
+	auditrepo := new(mocks.AuditRepoMock)
+	log := zap.NewNop().Sugar()
+
+	auditrepo.On("Create", mock.Anything, mock.Anything).Return(nil).Maybe()
+	acceptCharges(subrepo)
+	subService := services.NewSubscriptionService(subrepo, srepo, centers, new(mocks.BlobStoreMock), services.NewAuditService(auditrepo, log), noBudgets(subrepo, log), anyEvents(), services.ApprovalConfig{}, log)
+
+	price := int64(100000)
+	create := func(allocations ...models.AllocationInput) (*models.Subscription, error) {
+		return subService.Create(ctx, &models.CreateSubscription{ServiceName: "Slack", UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5",
+			PriceMinor: &price, StartDate: "01-2025", Allocations: allocations})
+	}
+	srepo.On("GetByName", ctx, "Slack").Return(&models.Service{ID: 1, Name: "Slack"}, nil)
+	subrepo.On("Create", ctx, mock.AnythingOfType("*models.Subscription")).Return(nil)
+	centers.On("GetByIds", ctx, []uint{1, 2}).Return([]models.CostCenter{{ID: 1, Code: "IT"}, {ID: 2, Code: "HR"}}, nil)
+	centers.On("GetByIds", ctx, []uint{1, 3}).Return([]models.CostCenter{{ID: 1, Code: "IT"}}, nil)
+
+	_, err := create(models.AllocationInput{CostCenterID: 1, Percent: 60}, models.AllocationInput{CostCenterID: 2, Percent: 30})
+	assert.ErrorIs(t, err, services.ErrInvalidAllocations) //в сумме 90%
+	_, err = create(models.AllocationInput{CostCenterID: 1, Percent: 50}, models.AllocationInput{CostCenterID: 1, Percent: 50})
+	assert.ErrorIs(t, err, services.ErrInvalidAllocations) //один центр дважды
+	_, err = create(models.AllocationInput{CostCenterID: 1, Percent: 50}, models.AllocationInput{CostCenterID: 3, Percent: 50})
+	assert.ErrorIs(t, err, services.ErrInvalidAllocations) //центра 3 нет
+
+	res, err := create(models.AllocationInput{CostCenterID: 1, Percent: 66.67}, models.AllocationInput{CostCenterID: 2, Percent: 33.33})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.CostAllocation{{CostCenterID: 1, Percent: 66.67}, {CostCenterID: 2, Percent: 33.33}}, res.Allocations)
+}
+
+func TestUpdate_ValidatesBeforeSaving(t *testing.T) { //неверное распределение не создает метки и не меняет подписку
+	ctx := context.Background()
+	srepo := new(mocks.ServiceRepoMock)
+	subrepo := new(mocks.SubscriptionRepoMock)
+	centers := new(mocks.CostCenterRepoMock)
+	log := zap.NewNop().Sugar()
+
+	subService := services.NewSubscriptionService(subrepo, srepo, centers, new(mocks.BlobStoreMock), services.NewAuditService(new(mocks.AuditRepoMock), log), noBudgets(subrepo, log), anyEvents(), services.ApprovalConfig{}, log)
+
+	existedSub := &models.Subscription{ID: 1, ServiceID: 1, UserID: "6a2995b1-9967-473c-ab26-2710f6e66fd5", Price: 1000,
+		StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	subrepo.On("GetById", ctx, uint(1)).Return(existedSub, nil)
+	centers.On("GetByIds", ctx, []uint{1, 2}).Return([]models.CostCenter{{ID: 1, Code: "IT"}, {ID: 2, Code: "HR"}}, nil)
+
+	_, err := subService.Update(ctx, 1, &models.UpdateSubscription{Tags: &[]string{"work"},
+		Allocations: &[]models.AllocationInput{{CostCenterID: 1, Percent: 60}, {CostCenterID: 2, Percent: 30}}})
+	assert.ErrorIs(t, err, services.ErrInvalidAllocations)
+	subrepo.AssertNotCalled(t, "FindOrCreateTags", mock.Anything, mock.Anything)
+	subrepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
 }